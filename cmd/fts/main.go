@@ -1,12 +1,17 @@
 package main
 
 import (
+	"compress/gzip"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/dariasmyr/fts-engine/internal/adapters/grpcapi"
 	ftspersist "github.com/dariasmyr/fts-engine/internal/services/fts/persist"
+	"github.com/dariasmyr/fts-engine/internal/services/search"
 	"github.com/dariasmyr/fts-engine/internal/utils"
 	"io"
+	"iter"
 	"log/slog"
 	"net/http"
 	_ "net/http/pprof"
@@ -14,14 +19,19 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/dariasmyr/fts-engine/config"
 	"github.com/dariasmyr/fts-engine/internal/adapters/cui"
+	"github.com/dariasmyr/fts-engine/internal/adapters/httpapi"
 	"github.com/dariasmyr/fts-engine/internal/adapters/loader/wiki"
 	"github.com/dariasmyr/fts-engine/internal/domain/models"
 	"github.com/dariasmyr/fts-engine/internal/lib/logger/sl"
+	"github.com/dariasmyr/fts-engine/internal/storage/memory"
 	pkgfts "github.com/dariasmyr/fts-engine/pkg/fts"
 	"github.com/dariasmyr/fts-engine/pkg/ftsbuiltin"
 	"github.com/dariasmyr/fts-engine/pkg/keygen"
@@ -54,9 +64,9 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	log := setupLogger(cfg.Env)
+	log, terminal := setupLogger(cfg.Env)
 	if cfgSource == "defaults" {
-		log.Warn("No config file found; using built-in defaults", "dump_path", cfg.DumpPath, "snapshot_path", cfg.FTS.Snapshot.Path)
+		log.Warn("No config file found; using built-in defaults", "dump_paths", cfg.DumpPaths, "snapshot_path", cfg.FTS.Snapshot.Path)
 	} else {
 		log.Info("Loaded configuration", "source", cfgSource)
 	}
@@ -82,60 +92,58 @@ func main() {
 		cancel()
 	}()
 
-	documentsByID := make(map[string]models.Document)
+	store := memory.New()
 
-	var ftsEngine cui.SearchEngine
-
-	switch cfg.FTS.Engine {
-	case "trie":
-		keyGen, err := selectKeyGenerator(cfg.FTS.KeyGen)
-		if err != nil {
-			log.Error("Failed to select keygen", "error", sl.Err(err))
-			return
-		}
-
-		pipeline := buildPipeline(cfg)
-		svc, loadedFromSnapshot, err := buildService(log, cfg, keyGen, pipeline)
-		if err != nil {
-			log.Error("Failed to initialize trie service", "error", sl.Err(err))
-			return
-		}
-		ftsEngine = &serviceAdapter{service: svc, snapshotLoaded: loadedFromSnapshot}
-	default:
-		log.Error("unknown fts engine", "engine", cfg.FTS.Engine)
+	ftsEngine, err := buildEngine(log, cfg, store)
+	if err != nil {
+		log.Error("Failed to initialize fts engine", "engine", cfg.FTS.Engine, "error", sl.Err(err))
 		return
 	}
+	var snapshotLoaded bool
+	if adapter, ok := ftsEngine.(*serviceAdapter); ok {
+		snapshotLoaded = adapter.snapshotLoaded
+	}
 
 	log.Info("FTS engine initialised")
 
-	dumpLoader := wiki.New(log, cfg.DumpPath)
+	dumpLoader := wiki.New(log, cfg.DumpPaths...)
+	dumpLoader.SetIDStrategy(wiki.IDStrategy(cfg.IDStrategy))
+	dumpLoader.SetFetchOptions(wiki.FetchOptions{
+		Timeout: cfg.Enrich.Timeout,
+		Retries: cfg.Enrich.Retries,
+		Backoff: cfg.Enrich.Backoff,
+		Rate:    cfg.Enrich.Rate,
+		Burst:   cfg.Enrich.Burst,
+	})
 	log.Info("Loader initialised")
 
-	startTime := time.Now()
-	documents, err := dumpLoader.LoadDocuments(ctx)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			log.Warn("Dump file not found; starting with an empty corpus", "path", cfg.DumpPath)
-			documents = nil
-		} else {
-			log.Error("Failed to load documents", "error", sl.Err(err))
-			return
-		}
-	}
-
-	duration := time.Since(startTime)
-	log.Info(fmt.Sprintf("Unpacked & parsed %d documents in %v", len(documents), duration))
-
 	go func() {
 		http.ListenAndServe("localhost:6060", nil)
 	}()
 
 	if cfg.Mode.Type == "experiment" {
+		startTime := time.Now()
+		documents, err := dumpLoader.LoadDocuments(ctx)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				log.Warn("Dump file not found; starting with an empty corpus", "paths", cfg.DumpPaths)
+				documents = nil
+			} else {
+				log.Error("Failed to load documents", "error", sl.Err(err))
+				return
+			}
+		}
+
+		duration := time.Since(startTime)
+		log.Info(fmt.Sprintf("Unpacked & parsed %d documents in %v", len(documents), duration))
+
 		startTime = time.Now()
 		memStats := utils.MeasureMemory(func() {
+			jobs, finish := startIndexing(ctx, log, cfg, ftsEngine, len(documents))
 			for _, doc := range documents {
-				_ = ftsEngine.IndexDocument(ctx, doc.ID, doc.Abstract)
+				jobs <- doc
 			}
+			finish()
 		})
 		duration = time.Since(startTime)
 		log.Info(fmt.Sprintf("Indexed %d documents in %v", len(documents), duration))
@@ -144,21 +152,56 @@ func main() {
 		return
 	}
 
-	startTime = time.Now()
-	for i := range documents {
-		doc := documents[i]
-		documentsByID[doc.ID] = doc
-
-		select {
-		case <-rootCtx.Done():
-			log.Info("Received shutdown signal, shutting down...")
+	// Documents are indexed while the dump is still being parsed, so the
+	// decoded dump is never held in memory as a whole.
+	startTime := time.Now()
+	loaded := 0
+	// The dump is streamed, so the total is unknown and progress reports
+	// carry no time estimate.
+	var (
+		jobs   chan<- models.Document
+		finish = func() {}
+	)
+	if !snapshotLoaded {
+		jobs, finish = startIndexing(ctx, log, cfg, ftsEngine, 0)
+	}
+	var (
+		interrupted bool
+		loadErr     error
+	)
+	load := func() {
+		docs, loadErrs := dumpLoader.StreamDocuments(ctx)
+		if cfg.EnrichExtracts {
+			docs = dumpLoader.EnrichDocuments(ctx, docs, cfg.Enrich.Workers)
+		}
+		loaded, interrupted = feedDocuments(log, docs, store, jobs, rootCtx.Done())
+		// Interrupted or not, the documents already handed to the indexers
+		// are indexed before main goes on to return.
+		finish()
+		if !interrupted {
+			loadErr = <-loadErrs
+		}
+	}
+	// MeasureMemory forces collections before and after the load, which is
+	// over before any search is served.
+	if cfg.Mode.MemProfile && !snapshotLoaded {
+		logMemory(log, cfg, utils.MeasureMemory(load))
+	} else {
+		load()
+	}
+	if interrupted {
+		log.Info("Received shutdown signal, shutting down...")
+		return
+	}
+	if err := loadErr; err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			log.Warn("Dump file not found; starting with an empty corpus", "paths", cfg.DumpPaths)
+		} else {
+			log.Error("Failed to load documents", "error", sl.Err(err))
 			return
-		default:
-			if indexErr := ftsEngine.IndexDocument(ctx, doc.ID, doc.Abstract); indexErr != nil {
-				log.Error("could not index document:", "error", indexErr)
-			}
 		}
 	}
+	log.Info(fmt.Sprintf("Loaded %d documents in %v", loaded, time.Since(startTime)))
 
 	adapter, ok := ftsEngine.(*serviceAdapter)
 	if !ok {
@@ -167,12 +210,12 @@ func main() {
 	}
 
 	if !adapter.snapshotLoaded {
-		if err := buildFilterIfNeeded(log, adapter.service); err != nil {
+		if err := buildFilterIfNeeded(log, adapter.service.Load()); err != nil {
 			log.Error("Failed to finalize search filter", "error", sl.Err(err))
 			return
 		}
 
-		if err := saveSnapshotIfEnabled(log, cfg, adapter.service); err != nil {
+		if err := saveSnapshotIfEnabled(log, cfg, adapter.service.Load()); err != nil {
 			log.Error("Failed to persist snapshot", "error", sl.Err(err))
 			return
 		}
@@ -180,18 +223,126 @@ func main() {
 		log.Info("Skipping re-indexing: snapshot loaded", "path", cfg.FTS.Snapshot.Path)
 	}
 
-	appCUI := cui.New(ctx, log, ftsEngine, documentsByID, 10)
+	// The snapshot above holds only the dump, so the log of documents added
+	// since is replayed on every start rather than truncated.
+	if path := cfg.FTS.Snapshot.LogPath; path != "" {
+		replayed, err := adapter.openLog(ctx, log, path)
+		if err != nil {
+			log.Error("Failed to replay document log", "path", path, "error", sl.Err(err))
+			return
+		}
+		defer adapter.closeLog()
+		log.Info("Document log replayed", "path", path, "documents", replayed)
+	}
+
+	if cfg.Mode.Type == "server" {
+		server := httpapi.New(log, cfg.HTTP.Address, adapter, adapter, cfg.HTTP.MaxResults)
+		if cfg.GRPC.Address != "" {
+			grpcServer := grpcapi.New(log, adapter, adapter, cfg.HTTP.MaxResults)
+			go func() {
+				if err := grpcServer.Run(ctx, cfg.GRPC.Address); err != nil {
+					log.Error("gRPC API failed", "error", sl.Err(err))
+				}
+			}()
+		}
+		go func() {
+			<-rootCtx.Done()
+			server.Drain()
+		}()
+
+		if err := server.Run(ctx); err != nil {
+			log.Error("HTTP API failed", "error", sl.Err(err))
+		}
+		return
+	}
+
+	appCUI := cui.New(ctx, log, ftsEngine, adapter, 10)
+
+	// From here on the CUI draws on the terminal, so log lines such as the
+	// engine's per-search debug records only go to the log file.
+	terminal.muted.Store(true)
 
 	cuiErr := appCUI.Start()
+	terminal.muted.Store(false)
 	if cuiErr != nil {
 		log.Error("Failed to start appCUI", "error", sl.Err(cuiErr))
 		return
 	}
 }
 
+// feedDocuments stores every document received on docs and sends it to jobs,
+// unless jobs is nil as when the index came from a snapshot. It returns how
+// many documents it stored, and whether it stopped because stop was closed
+// before docs ran out. Documents already sent stay with the indexers, so the
+// caller still finishes them.
+func feedDocuments(log *slog.Logger, docs <-chan models.Document, store search.DocumentWriter, jobs chan<- models.Document, stop <-chan struct{}) (int, bool) {
+	loaded := 0
+	for doc := range docs {
+		if err := store.SaveDocument(doc); err != nil {
+			log.Error("Failed to store document", "id", doc.ID, "error", sl.Err(err))
+			continue
+		}
+		loaded++
+
+		if jobs == nil {
+			continue
+		}
+
+		select {
+		case <-stop:
+			return loaded, true
+		case jobs <- doc:
+		}
+	}
+	return loaded, false
+}
+
+// startIndexing indexes the documents sent on the returned channel on
+// cfg.FTS.Indexers goroutines, logging progress every cfg.FTS.Progress.
+// total is the number of documents to expect, or 0 when it is not known.
+// finish waits for the sent documents to be indexed and logs a summary. It
+// closes the channel, so call it once, from the goroutine that sends, after
+// the last send: a send after it panics, and skipping it leaves documents
+// unindexed.
+func startIndexing(ctx context.Context, log *slog.Logger, cfg *config.Config, engine search.Searcher, total int) (chan<- models.Document, func()) {
+	progress := utils.NewProgress(total, time.Now())
+	jobs := make(chan models.Document, cfg.FTS.Indexers)
+
+	var workers sync.WaitGroup
+	for range cfg.FTS.Indexers {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for doc := range jobs {
+				started := time.Now()
+				err := search.IndexDocument(ctx, engine, doc)
+				progress.Record(time.Since(started), err)
+				if err != nil {
+					log.Error("could not index document:", "id", doc.ID, "error", sl.Err(err))
+				}
+			}
+		}()
+	}
+
+	reportCtx, stopReports := context.WithCancel(ctx)
+	reported := make(chan struct{})
+	go func() {
+		defer close(reported)
+		progress.Log(reportCtx, log, cfg.FTS.Progress)
+	}()
+
+	return jobs, func() {
+		close(jobs)
+		workers.Wait()
+		stopReports()
+		<-reported
+		progress.Report(time.Now()).Log(log, "Indexing finished")
+	}
+}
+
 func analyzeTrie(
 	cfg *config.Config,
-	engine cui.SearchEngine,
+	engine search.Searcher,
 	memStats runtime.MemStats,
 	log *slog.Logger,
 ) {
@@ -218,50 +369,543 @@ func analyzeTrie(
 		"avgDepth", stats.AvgDepth,
 		"totalDocs", stats.TotalDocs,
 		"totalChildren", stats.TotalChildren,
-		"heapMB", memStats.HeapAlloc/1024/1024,
-		"heapObjects", memStats.HeapObjects,
-		"totalAllocMB", memStats.TotalAlloc/1024/1024,
 	)
+	logMemory(log, cfg, memStats)
+
+	log.Info("FTS average children per level", "levels", formatLevels(stats.AvgChildrenPerLevel))
+}
+
+// logMemory logs the deltas utils.MeasureMemory took around indexing. In
+// prod and server mode they include the stored documents, which are kept
+// while the dump streams in.
+func logMemory(log *slog.Logger, cfg *config.Config, mem runtime.MemStats) {
+	log.Info("FTS indexing memory",
+		"index", cfg.FTS.Index,
+		"keygen", cfg.FTS.KeyGen,
+		"heapAlloc", formatMiB(mem.HeapAlloc),
+		"totalAlloc", formatMiB(mem.TotalAlloc),
+		"heapObjects", int64(mem.HeapObjects),
+	)
+}
+
+// formatMiB renders a byte delta from utils.MeasureMemory in MiB. A heap that
+// shrank leaves the unsigned delta wrapped around, which int64 turns back
+// into a negative number.
+func formatMiB(delta uint64) string {
+	return fmt.Sprintf("%.1f MiB", float64(int64(delta))/(1<<20))
+}
 
-	for level, avg := range stats.AvgChildrenPerLevel {
-		log.Info(fmt.Sprintf("Level %d: avg children = %.2f", level, avg))
+// formatLevels renders per-level averages as "L0=12.00 L1=3.25 ...".
+func formatLevels(avgs []float64) string {
+	levels := make([]string, len(avgs))
+	for level, avg := range avgs {
+		levels[level] = fmt.Sprintf("L%d=%.2f", level, avg)
 	}
+	return strings.Join(levels, " ")
+}
 
+// buildEngine maps fts.engine to a search backend. Backends differ only in
+// the index data structure, which buildService picks from fts.index.
+func buildEngine(log *slog.Logger, cfg *config.Config, documents documentStore) (search.Searcher, error) {
+	switch cfg.FTS.Engine {
+	case "trie":
+		keyGen, err := selectKeyGenerator(cfg.FTS.KeyGen, cfg.FTS.NGram)
+		if err != nil {
+			return nil, err
+		}
+
+		pipeline := buildPipeline(cfg)
+		svc, loadedFromSnapshot, err := buildService(log, cfg, keyGen, pipeline)
+		if err != nil {
+			return nil, err
+		}
+
+		adapter := &serviceAdapter{
+			snapshotLoaded: loadedFromSnapshot,
+			documents:      documents,
+			cache:          search.NewDocumentCache(documents),
+			snippetWindow:  cfg.FTS.Snippet,
+			allKeys:        cfg.FTS.AllKeys,
+			suggestBelow:   cfg.FTS.Suggest,
+			timeout:        cfg.FTS.SearchTimeout,
+			statsAge:       cfg.FTS.StatsInterval,
+			hydrators:      hydrateWorkers(cfg),
+			lazy:           !cfg.FTS.Hydrate,
+			ids:            wiki.IDStrategy(cfg.IDStrategy),
+			fresh: func() (*pkgfts.Service, error) {
+				return newService(log, cfg, keyGen, pipeline)
+			},
+		}
+		adapter.service.Store(svc)
+		return adapter, nil
+	default:
+		return nil, fmt.Errorf("unknown fts engine %q", cfg.FTS.Engine)
+	}
+}
+
+// documentStore is what the adapter keeps the documents it serves in.
+type documentStore interface {
+	search.DocumentStore
+	search.DocumentWriter
+	search.DocumentIterator
+	Len() int
 }
 
 type serviceAdapter struct {
-	service        *pkgfts.Service
+	// service is swapped whole by Rebuild, so every search reads it once.
+	service        atomic.Pointer[pkgfts.Service]
 	snapshotLoaded bool
+	snippetWindow  int
+	allKeys        bool
+	suggestBelow   int
+	// timeout bounds every search; 0 means no limit. See
+	// fts.search_timeout.
+	timeout time.Duration
+	// hydrators bounds the goroutines that attach documents to one page of
+	// results; see fts.search_workers.
+	hydrators int
+	// lazy leaves results without their document and snippet, for callers
+	// that only need IDs and scores; see fts.hydrate.
+	lazy bool
+	// ids derives the ID of an added document that has none.
+	ids wiki.IDStrategy
+	// statsAge is how long IndexStats reuses an analysis of an index that
+	// has changed since; see fts.stats_interval.
+	statsAge time.Duration
+	// version counts the changes to the index, so IndexStats can tell
+	// whether analysis still describes it.
+	version  atomic.Uint64
+	analysis atomic.Pointer[indexAnalysis]
+
+	// mu keeps the index and documents in step once the adapter serves
+	// searches; AddDocument holds it for the whole add so replacing a
+	// document is atomic.
+	mu        sync.RWMutex
+	documents documentStore
+	// cache serves the documents of results shown again, such as the pages
+	// of a popular search, while their last readers still hold them; see
+	// search.DocumentCache.
+	cache *search.DocumentCache
+	// changes logs added documents once openLog has replayed it; nil
+	// without fts.snapshot.log_path.
+	changes *ftspersist.Log
+
+	// Rebuild indexes into a service from fresh. While it runs, added
+	// documents are also kept in pending so they reach the new index.
+	fresh      func() (*pkgfts.Service, error)
+	rebuilding bool
+	pending    []models.Document
 }
 
+var (
+	_ search.Searcher         = (*serviceAdapter)(nil)
+	_ search.FuzzySearcher    = (*serviceAdapter)(nil)
+	_ search.FieldIndexer     = (*serviceAdapter)(nil)
+	_ search.DocumentStore    = (*serviceAdapter)(nil)
+	_ search.DocumentAdder    = (*serviceAdapter)(nil)
+	_ search.StatsReporter    = (*serviceAdapter)(nil)
+	_ search.StatsRefresher   = (*serviceAdapter)(nil)
+	_ search.TextAnalyzer     = (*serviceAdapter)(nil)
+	_ search.DocumentIterator = (*serviceAdapter)(nil)
+	_ search.Rebuilder        = (*serviceAdapter)(nil)
+)
+
 func (s *serviceAdapter) IndexDocument(ctx context.Context, docID string, content string) error {
-	return s.service.IndexDocument(ctx, pkgfts.DocID(docID), content)
+	defer s.version.Add(1)
+	return s.service.Load().IndexDocument(ctx, pkgfts.DocID(docID), content)
+}
+
+func (s *serviceAdapter) GetDocument(id string) (models.Document, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.cache.GetDocument(id)
+}
+
+// AddDocument indexes doc and stores it, replacing the document with the same
+// ID. An empty ID is derived with the dump loader's id_strategy, so with url
+// re-adding an edited article replaces it.
+func (s *serviceAdapter) AddDocument(ctx context.Context, doc models.Document) error {
+	if doc.ID == "" {
+		doc.ID = s.ids.ID(doc)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.changes != nil {
+		record, err := json.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("add document %s: %w", doc.ID, err)
+		}
+		if err := s.changes.Append(record); err != nil {
+			return fmt.Errorf("add document %s: %w", doc.ID, err)
+		}
+	}
+
+	if err := s.IndexFields(ctx, doc); err != nil {
+		return fmt.Errorf("add document %s: %w", doc.ID, err)
+	}
+	if s.rebuilding {
+		s.pending = append(s.pending, doc)
+	}
+
+	if err := s.documents.SaveDocument(doc); err != nil {
+		return fmt.Errorf("add document %s: %w", doc.ID, err)
+	}
+	s.cache.Forget(doc.ID)
+	return nil
 }
 
-func (s *serviceAdapter) SearchDocuments(ctx context.Context, query string, maxResults int) (*models.SearchResult, error) {
-	result, err := s.service.SearchDocuments(ctx, query, maxResults)
+// openLog adds the documents logged at path again, restoring those added
+// after the index was built or its snapshot written, and logs every later
+// AddDocument there. It returns how many documents it replayed. AddDocument
+// logs a document before indexing it, so the log may hold one that failed
+// to index; such a record is logged and skipped rather than failing the
+// start, unless ctx is done.
+func (s *serviceAdapter) openLog(ctx context.Context, log *slog.Logger, path string) (int, error) {
+	changes, replayed, err := ftspersist.OpenLog(path, func(record []byte) error {
+		var doc models.Document
+		if err := json.Unmarshal(record, &doc); err != nil {
+			log.Warn("Skipping unreadable logged document", "path", path, "error", sl.Err(err))
+			return nil
+		}
+		if err := s.AddDocument(ctx, doc); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			log.Warn("Skipping logged document", "path", path, "id", doc.ID, "error", sl.Err(err))
+		}
+		return nil
+	})
+	if err != nil {
+		return replayed, err
+	}
+
+	s.mu.Lock()
+	s.changes = changes
+	s.mu.Unlock()
+	return replayed, nil
+}
+
+// closeLog stops logging added documents.
+func (s *serviceAdapter) closeLog() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.changes == nil {
+		return nil
+	}
+	err := s.changes.Close()
+	s.changes = nil
+	return err
+}
+
+// IndexFields indexes the configured fields of doc.
+func (s *serviceAdapter) IndexFields(ctx context.Context, doc models.Document) error {
+	content := map[string]string{
+		"title":    doc.Title,
+		"abstract": doc.Abstract,
+		"extract":  doc.Extract,
+		"url":      doc.URL,
+	}
+
+	svc := s.service.Load()
+	fields := make(map[string]string, len(content))
+	for _, name := range svc.Fields() {
+		fields[name] = content[name]
+	}
+
+	defer s.version.Add(1)
+	return svc.IndexFields(ctx, pkgfts.DocID(doc.ID), fields)
+}
+
+func (s *serviceAdapter) SearchDocuments(ctx context.Context, query string, offset, maxResults int) (*models.SearchResult, error) {
+	ctx, cancel := s.searchContext(ctx)
+	defer cancel()
+
+	svc := s.service.Load()
+	result, err := svc.Search(ctx, query, pkgfts.SearchOptions{
+		Offset:         offset,
+		Limit:          maxResults,
+		RequireAllKeys: s.allKeys,
+		SuggestBelow:   s.suggestBelow,
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	out := toModelResult(result)
+	s.hydrate(svc, query, out)
+	return out, nil
+}
+
+func (s *serviceAdapter) SearchFuzzy(ctx context.Context, query string, maxDist, maxResults int) (*models.SearchResult, error) {
+	ctx, cancel := s.searchContext(ctx)
+	defer cancel()
+
+	svc := s.service.Load()
+	result, err := svc.SearchFuzzy(ctx, query, maxDist, maxResults)
+	if err != nil {
+		return nil, err
+	}
+
+	out := toModelResult(result)
+	s.hydrate(svc, query, out)
+	return out, nil
+}
+
+// searchContext bounds ctx by the search timeout, if one is set.
+func (s *serviceAdapter) searchContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.timeout)
+}
+
+// hydrate attaches the stored document and an abstract snippet around the
+// query matches to every result. Fuzzy results also highlight the matched terms.
+func (s *serviceAdapter) hydrate(svc *pkgfts.Service, query string, result *models.SearchResult) {
+	if s.lazy {
+		return
+	}
+
+	workers := min(s.hydrators, len(result.ResultData))
+	if workers <= 1 {
+		for i := range result.ResultData {
+			s.hydrateResult(svc, query, &result.ResultData[i])
+		}
+		return
+	}
+
+	// Every result is written by the worker that took its index, so the
+	// ranking order stays as it is.
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				s.hydrateResult(svc, query, &result.ResultData[i])
+			}
+		}()
+	}
+	for i := range result.ResultData {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// hydrateResult attaches the document of data and its snippet and title
+// highlights. A document missing from the store leaves data without them,
+// which the frontends show as unavailable.
+func (s *serviceAdapter) hydrateResult(svc *pkgfts.Service, query string, data *models.ResultData) {
+	doc, ok := s.GetDocument(data.ID)
+	if !ok {
+		return
+	}
+	data.Document = doc
+
+	terms := query
+	for _, term := range data.FuzzyTerms {
+		terms += " " + term.Term
+	}
+	snippet := svc.Snippet(terms, doc.Abstract, s.snippetWindow)
+	data.Snippet = snippet.Text
+	for _, span := range snippet.Spans {
+		data.MatchSpans = append(data.MatchSpans, models.MatchSpan{Start: span.Start, End: span.End})
+	}
+	for _, span := range svc.Highlight(terms, doc.Title) {
+		data.TitleSpans = append(data.TitleSpans, models.MatchSpan{Start: span.Start, End: span.End})
+	}
+}
+
+// hydrateWorkers is fts.search_workers, or GOMAXPROCS when that is 0 as for
+// the index lookups.
+func hydrateWorkers(cfg *config.Config) int {
+	if cfg.FTS.SearchWorkers > 0 {
+		return cfg.FTS.SearchWorkers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// Rebuild indexes the stored documents into a fresh index, for instance after
+// the pipeline changed, and swaps it in once it is complete. Searches keep
+// using the old index until then, and documents added meanwhile reach both.
+// On error, including ctx being done, the old index stays.
+func (s *serviceAdapter) Rebuild(ctx context.Context) (int, error) {
+	if s.fresh == nil {
+		return 0, fmt.Errorf("rebuild: no index factory")
+	}
+	svc, err := s.fresh()
+	if err != nil {
+		return 0, fmt.Errorf("rebuild: %w", err)
+	}
+
+	s.mu.Lock()
+	if s.rebuilding {
+		s.mu.Unlock()
+		return 0, search.ErrRebuildRunning
+	}
+	s.rebuilding, s.pending = true, nil
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.rebuilding, s.pending = false, nil
+		s.mu.Unlock()
+	}()
+
+	target := &serviceAdapter{}
+	target.service.Store(svc)
+	if _, err := search.Reindex(ctx, s.documents, target); err != nil {
+		return 0, fmt.Errorf("rebuild: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, doc := range s.pending {
+		if err := target.IndexFields(ctx, doc); err != nil {
+			return 0, fmt.Errorf("rebuild: add document %s: %w", doc.ID, err)
+		}
+	}
+	if err := svc.BuildFilter(); err != nil {
+		return 0, fmt.Errorf("rebuild: %w", err)
+	}
+	s.service.Store(svc)
+	// A document added during the rebuild may also have been read from the
+	// store, or replace one that was, so count the stored IDs rather than
+	// the documents indexed.
+	return s.documents.Len(), nil
+}
+
+func toModelResult(result *pkgfts.SearchResult) *models.SearchResult {
 	out := make([]models.ResultData, 0, len(result.Results))
 	for _, item := range result.Results {
-		out = append(out, models.ResultData{
+		data := models.ResultData{
 			ID:            string(item.ID),
 			UniqueMatches: item.UniqueMatches,
 			TotalMatches:  item.TotalMatches,
-		})
+			Score:         item.Score,
+			MatchedTerms:  item.MatchedTerms,
+		}
+		for _, term := range item.FuzzyTerms {
+			data.FuzzyTerms = append(data.FuzzyTerms, models.FuzzyTerm{
+				Token:    term.Token,
+				Term:     term.Term,
+				Distance: term.Distance,
+			})
+		}
+		out = append(out, data)
 	}
 
 	return &models.SearchResult{
 		ResultData:        out,
 		TotalResultsCount: result.TotalResultsCount,
 		Timings:           result.Timings,
+		Suggestion:        result.Suggestion,
+		Truncated:         result.Truncated,
+	}
+}
+
+func (s *serviceAdapter) AnalyzeText(text string) (models.TextAnalysis, error) {
+	analysis, err := s.service.Load().AnalyzeText(text)
+	if err != nil {
+		return models.TextAnalysis{}, err
+	}
+	return models.TextAnalysis{
+		Tokens:       toModelTokens(analysis.Tokens),
+		PhraseTokens: toModelTokens(analysis.PhraseTokens),
 	}, nil
 }
 
+func toModelTokens(tokens []pkgfts.AnalyzedToken) []models.AnalyzedToken {
+	if tokens == nil {
+		return nil
+	}
+	out := make([]models.AnalyzedToken, 0, len(tokens))
+	for _, token := range tokens {
+		out = append(out, models.AnalyzedToken{Token: token.Token, Keys: token.Keys})
+	}
+	return out
+}
+
 func (s *serviceAdapter) AnalyzeStats() (pkgfts.Stats, bool) {
-	return s.service.Analyze()
+	return s.service.Load().Analyze()
+}
+
+// Documents yields the stored documents in ID order. The store iterates a
+// copy, so the consumer may add documents to this adapter, as a Reindex into
+// it does.
+func (s *serviceAdapter) Documents(ctx context.Context) iter.Seq2[models.Document, error] {
+	return s.documents.Documents(ctx)
+}
+
+// indexAnalysis is what Analyze reported for service once version changes
+// had been made to it.
+type indexAnalysis struct {
+	service  *pkgfts.Service
+	version  uint64
+	at       time.Time
+	stats    pkgfts.Stats
+	analyzed bool
+}
+
+// reusable reports whether a may stand for svc after version changes: either
+// none were made since, or a is younger than maxAge.
+func (a *indexAnalysis) reusable(svc *pkgfts.Service, version uint64, maxAge time.Duration) bool {
+	if a == nil || a.service != svc {
+		return false
+	}
+	return a.version == version || time.Since(a.at) < maxAge
+}
+
+// IndexStats counts the stored documents rather than the indexed ones, so it
+// stays right for an index restored from a snapshot. Analyze walks the whole
+// index, so its figures are reused until the index changes, or for statsAge
+// while it keeps changing, as during a bulk load. The lock keeps AddDocument
+// from changing the index while Analyze walks it.
+func (s *serviceAdapter) IndexStats() models.IndexStats {
+	return s.indexStats(false)
+}
+
+// RefreshIndexStats is IndexStats with a new analysis of the index.
+func (s *serviceAdapter) RefreshIndexStats() models.IndexStats {
+	return s.indexStats(true)
+}
+
+func (s *serviceAdapter) indexStats(refresh bool) models.IndexStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	svc := s.service.Load()
+	stats := models.IndexStats{
+		Documents:    s.documents.Len(),
+		AvgDocLength: svc.CorpusStats().AvgDocLength,
+	}
+
+	analysis := s.analysis.Load()
+	if version := s.version.Load(); refresh || !analysis.reusable(svc, version, s.statsAge) {
+		analysis = &indexAnalysis{service: svc, version: version, at: time.Now()}
+		analysis.stats, analysis.analyzed = svc.Analyze()
+		s.analysis.Store(analysis)
+	}
+	if analysis.analyzed {
+		stats.Analyzed = true
+		stats.AnalyzedAt = analysis.at
+		stats.Keys = analysis.stats.Leaves
+		stats.Nodes = analysis.stats.Nodes
+		stats.MaxDepth = analysis.stats.MaxDepth
+		stats.Postings = analysis.stats.TotalDocs
+	}
+	if cache, ok := svc.ResultCacheStats(); ok {
+		stats.CacheEntries = cache.Entries
+		stats.CacheHits = cache.Hits
+		stats.CacheMisses = cache.Misses
+	}
+	return stats
 }
 
 func buildService(log *slog.Logger, cfg *config.Config, keyGen pkgfts.KeyGenerator, pipeline textproc.Pipeline) (*pkgfts.Service, bool, error) {
@@ -269,28 +913,34 @@ func buildService(log *slog.Logger, cfg *config.Config, keyGen pkgfts.KeyGenerat
 		return nil, false, fmt.Errorf("nil config")
 	}
 
-	if cfg.Mode.Type == "prod" && cfg.FTS.Snapshot.Enabled && cfg.FTS.Snapshot.LoadOnStart {
+	if cfg.Mode.Type != "experiment" && cfg.FTS.Snapshot.Enabled && cfg.FTS.Snapshot.LoadOnStart {
 		svc, ok, err := tryLoadSnapshot(log, cfg, keyGen, pipeline)
 		if err != nil {
-			return nil, false, err
-		}
-		if ok {
+			log.Warn("Snapshot is unusable, rebuilding index from dump", "error", sl.Err(err))
+		} else if ok {
 			return svc, true, nil
 		}
 	}
 
+	svc, err := newService(log, cfg, keyGen, pipeline)
+	return svc, false, err
+}
+
+// newService returns an empty service as configured by fts.index and
+// fts.filter.
+func newService(log *slog.Logger, cfg *config.Config, keyGen pkgfts.KeyGenerator, pipeline textproc.Pipeline) (*pkgfts.Service, error) {
 	index, err := selectIndex(cfg.FTS.Index)
 	if err != nil {
-		return nil, false, err
+		return nil, err
 	}
 
 	searchFilter, err := selectFilter(cfg)
 	if err != nil {
-		return nil, false, err
+		return nil, err
 	}
 
-	svc := pkgfts.New(index, keyGen, pkgfts.WithPipeline(pipeline), pkgfts.WithFilter(searchFilter))
-	return svc, false, nil
+	opts := append(serviceOptions(log, cfg, pipeline), pkgfts.WithFilter(searchFilter))
+	return pkgfts.New(index, keyGen, opts...), nil
 }
 
 func tryLoadSnapshot(log *slog.Logger, cfg *config.Config, keyGen pkgfts.KeyGenerator, pipeline textproc.Pipeline) (*pkgfts.Service, bool, error) {
@@ -308,13 +958,19 @@ func tryLoadSplitSnapshot(log *slog.Logger, cfg *config.Config, keyGen pkgfts.Ke
 		return nil, false, nil
 	}
 
-	if _, err := os.Stat(indexPath); err != nil {
+	indexInfo, err := os.Stat(indexPath)
+	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return nil, false, nil
 		}
 		return nil, false, fmt.Errorf("check index snapshot path: %w", err)
 	}
 
+	if dumpPath, newer := newerDump(cfg.DumpPaths, indexInfo.ModTime()); newer {
+		log.Info("Dump is newer than index snapshot, rebuilding", "dump_path", dumpPath, "index_path", indexPath)
+		return nil, false, nil
+	}
+
 	indexFile, err := os.Open(indexPath)
 	if err != nil {
 		return nil, false, fmt.Errorf("open index snapshot: %w", err)
@@ -334,7 +990,7 @@ func tryLoadSplitSnapshot(log *slog.Logger, cfg *config.Config, keyGen pkgfts.Ke
 		)
 	}
 
-	builtOpts := []pkgfts.Option{pkgfts.WithPipeline(pipeline)}
+	builtOpts := serviceOptions(log, cfg, pipeline)
 
 	if expectedFilter != "" {
 		if filterPath == "" {
@@ -400,7 +1056,7 @@ func tryLoadSplitSnapshot(log *slog.Logger, cfg *config.Config, keyGen pkgfts.Ke
 		}
 	}
 
-	svc := pkgfts.New(loadedIndex.Index, keyGen, builtOpts...)
+	svc := pkgfts.New(loadedIndex.Index, keyGen, append(builtOpts, pkgfts.WithRestoredIndex())...)
 	log.Info("Loaded split FTS snapshots", "index_path", indexPath, "filter_path", filterPath)
 	return svc, true, nil
 }
@@ -442,16 +1098,16 @@ func saveSplitSnapshots(log *slog.Logger, cfg *config.Config, svc *pkgfts.Servic
 		SyncFile:       cfg.FTS.Snapshot.SyncFile,
 	}
 
-	if err := ftspersist.SaveAtomicWithOptions(indexPath, opts, func(w io.Writer) error {
+	if err := ftspersist.SaveAtomicWithOptions(indexPath, opts, snapshotWriter(cfg, func(w io.Writer) error {
 		return pkgfts.SaveIndexSnapshot(w, indexName, index)
-	}); err != nil {
+	})); err != nil {
 		return err
 	}
 
 	if searchFilter != nil && filterName != "" {
-		if err := ftspersist.SaveAtomicWithOptions(filterPath, opts, func(w io.Writer) error {
+		if err := ftspersist.SaveAtomicWithOptions(filterPath, opts, snapshotWriter(cfg, func(w io.Writer) error {
 			return pkgfts.SaveFilterSnapshot(w, filterName, searchFilter)
-		}); err != nil {
+		})); err != nil {
 			return err
 		}
 	} else if filterPath != "" {
@@ -464,6 +1120,32 @@ func saveSplitSnapshots(log *slog.Logger, cfg *config.Config, svc *pkgfts.Servic
 	return nil
 }
 
+// snapshotWriter gzips what write produces when fts.snapshot.compress is set.
+// Loading detects compressed snapshots itself, so the setting can change
+// between runs.
+func snapshotWriter(cfg *config.Config, write func(w io.Writer) error) func(w io.Writer) error {
+	if !cfg.FTS.Snapshot.Compress {
+		return write
+	}
+	return func(w io.Writer) error {
+		zw := gzip.NewWriter(w)
+		if err := write(zw); err != nil {
+			return err
+		}
+		return zw.Close()
+	}
+}
+
+// newerDump returns the first dump shard modified after t.
+func newerDump(dumpPaths []string, t time.Time) (string, bool) {
+	for _, path := range wiki.ExpandPaths(dumpPaths) {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(t) {
+			return path, true
+		}
+	}
+	return "", false
+}
+
 func snapshotIndexPath(cfg *config.Config) string {
 	if cfg == nil {
 		return ""
@@ -508,15 +1190,73 @@ func snapshotFilterPath(cfg *config.Config) string {
 	return base[:len(base)-len(ext)] + ".filter" + ext
 }
 
-func selectKeyGenerator(kind string) (pkgfts.KeyGenerator, error) {
+func selectKeyGenerator(kind string, ngramSize int) (pkgfts.KeyGenerator, error) {
 	switch kind {
 	case "word":
 		return keygen.Word, nil
+	case "trigram":
+		return keygen.Trigram, nil
+	case "ngram":
+		return keygen.NGram(ngramSize), nil
 	default:
 		return nil, fmt.Errorf("unknown keygen %q", kind)
 	}
 }
 
+// serviceOptions returns the options shared by freshly built and snapshot-loaded services.
+func serviceOptions(log *slog.Logger, cfg *config.Config, pipeline textproc.Pipeline) []pkgfts.Option {
+	opts := []pkgfts.Option{
+		pkgfts.WithPipeline(pipeline),
+		pkgfts.WithScorer(selectScorer(cfg)),
+		pkgfts.WithLogger(log),
+	}
+	if cfg.FTS.Positions {
+		opts = append(opts, pkgfts.WithPositions())
+	}
+	if cfg.FTS.Reverse {
+		opts = append(opts, pkgfts.WithReverseIndex())
+	}
+	if len(cfg.FTS.Fields) > 0 {
+		opts = append(opts, pkgfts.WithFields(cfg.FTS.Fields...))
+	}
+	if len(cfg.FTS.Weights) > 0 {
+		opts = append(opts, pkgfts.WithFieldWeights(cfg.FTS.Weights))
+	}
+	if cfg.FTS.SearchWorkers > 0 {
+		opts = append(opts, pkgfts.WithSearchWorkers(cfg.FTS.SearchWorkers))
+	}
+	if cfg.FTS.ResultCache > 0 {
+		opts = append(opts, pkgfts.WithResultCache(cfg.FTS.ResultCache))
+	}
+	if stages := phraseSkips(cfg); stages != 0 {
+		opts = append(opts, pkgfts.WithPhrasePipeline(pipeline.Without(stages)))
+	}
+	return opts
+}
+
+// phraseSkips returns the pipeline stages fts.pipeline leaves out of phrases.
+func phraseSkips(cfg *config.Config) textproc.Stage {
+	var stages textproc.Stage
+	if !cfg.FTS.Pipeline.PhraseStopwords {
+		stages |= textproc.StageStopWords
+	}
+	if !cfg.FTS.Pipeline.PhraseStem {
+		stages |= textproc.StageStem
+	}
+	return stages
+}
+
+func selectScorer(cfg *config.Config) pkgfts.Scorer {
+	switch cfg.FTS.Ranking {
+	case "bm25":
+		return pkgfts.BM25{K1: cfg.FTS.BM25.K1, B: cfg.FTS.BM25.B}
+	case "weighted":
+		return pkgfts.MatchWeights{Unique: cfg.FTS.Matches.Unique, Total: cfg.FTS.Matches.Total}
+	default:
+		return nil
+	}
+}
+
 func selectIndex(name string) (pkgfts.Index, error) {
 	return ftsbuiltin.BuildIndex(name)
 }
@@ -570,7 +1310,9 @@ func buildPipeline(cfg *config.Config) textproc.Pipeline {
 		filters = append(filters, textproc.MinLengthOrNumericFilter{MinLength: cfg.FTS.Pipeline.MinLength})
 	}
 
-	if cfg.FTS.Pipeline.StopwordsEN {
+	if len(cfg.FTS.Pipeline.Stopwords) > 0 {
+		filters = append(filters, textproc.NewStopwordFilter(cfg.FTS.Pipeline.Stopwords))
+	} else if cfg.FTS.Pipeline.StopwordsEN {
 		filters = append(filters, textproc.EnglishStopwordFilter{})
 	}
 
@@ -586,17 +1328,20 @@ func buildPipeline(cfg *config.Config) textproc.Pipeline {
 		filters = append(filters, textproc.RussianStemFilter{})
 	}
 
-	return textproc.NewPipeline(textproc.AlnumTokenizer{}, filters...)
+	return textproc.NewPipeline(textproc.AlnumTokenizer{Inner: cfg.FTS.Pipeline.InnerChars}, filters...)
 }
 
-func setupLogger(env string) *slog.Logger {
+// setupLogger logs to the terminal and data/app.log. The returned writer lets
+// the terminal copy be muted while the CUI owns the screen.
+func setupLogger(env string) (*slog.Logger, *terminalWriter) {
 	logFile, err := os.OpenFile("data/app.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err != nil {
-		fmt.Println("Failed to open log file:", err)
+		fmt.Fprintln(os.Stderr, "Failed to open log file:", err)
 		os.Exit(1)
 	}
 
-	multiWriter := io.MultiWriter(os.Stdout, logFile)
+	terminal := &terminalWriter{w: os.Stdout}
+	multiWriter := io.MultiWriter(terminal, logFile)
 
 	var log *slog.Logger
 	switch env {
@@ -614,5 +1359,18 @@ func setupLogger(env string) *slog.Logger {
 		)
 	}
 
-	return log
+	return log, terminal
+}
+
+// terminalWriter passes log lines on to w until it is muted.
+type terminalWriter struct {
+	w     io.Writer
+	muted atomic.Bool
+}
+
+func (t *terminalWriter) Write(p []byte) (int, error) {
+	if t.muted.Load() {
+		return len(p), nil
+	}
+	return t.w.Write(p)
 }
@@ -0,0 +1,716 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dariasmyr/fts-engine/config"
+	"github.com/dariasmyr/fts-engine/internal/adapters/loader/wiki"
+	"github.com/dariasmyr/fts-engine/internal/domain/models"
+	"github.com/dariasmyr/fts-engine/internal/services/search"
+	"github.com/dariasmyr/fts-engine/internal/storage/memory"
+	pkgfts "github.com/dariasmyr/fts-engine/pkg/fts"
+	"github.com/dariasmyr/fts-engine/pkg/ftsbuiltin"
+	"github.com/dariasmyr/fts-engine/pkg/keygen"
+	"github.com/dariasmyr/fts-engine/pkg/textproc"
+)
+
+func newTestAdapter(t *testing.T) *serviceAdapter {
+	t.Helper()
+
+	index, err := ftsbuiltin.BuildIndex("slicedradix")
+	if err != nil {
+		t.Fatalf("BuildIndex() error = %v", err)
+	}
+	store := memory.New()
+	adapter := &serviceAdapter{
+		documents:     store,
+		cache:         search.NewDocumentCache(store),
+		snippetWindow: pkgfts.DefaultSnippetWindow,
+	}
+	adapter.service.Store(pkgfts.New(index, keygen.Word, pkgfts.WithFields("title", "abstract")))
+	return adapter
+}
+
+func TestAddDocumentReplaces(t *testing.T) {
+	ctx := context.Background()
+	adapter := newTestAdapter(t)
+
+	doc := models.Document{ID: "doc-1", DocumentBase: models.DocumentBase{Title: "grand hotel"}}
+	if err := adapter.AddDocument(ctx, doc); err != nil {
+		t.Fatalf("AddDocument() error = %v", err)
+	}
+	doc.Title = "river barge"
+	if err := adapter.AddDocument(ctx, doc); err != nil {
+		t.Fatalf("AddDocument() error = %v", err)
+	}
+
+	if res, _ := adapter.SearchDocuments(ctx, "hotel", 0, 10); res.TotalResultsCount != 0 {
+		t.Fatalf("old content still matches: %+v", res.ResultData)
+	}
+	res, err := adapter.SearchDocuments(ctx, "barge", 0, 10)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	if res.TotalResultsCount != 1 || res.ResultData[0].Document.Title != "river barge" {
+		t.Fatalf("result = %+v", res)
+	}
+}
+
+func TestSearchTimeout(t *testing.T) {
+	ctx := context.Background()
+	adapter := newTestAdapter(t)
+	if err := adapter.AddDocument(ctx, models.Document{ID: "doc-1", DocumentBase: models.DocumentBase{Title: "grand hotel"}}); err != nil {
+		t.Fatalf("AddDocument() error = %v", err)
+	}
+
+	adapter.timeout = time.Nanosecond
+	if _, err := adapter.SearchDocuments(ctx, "hotel", 0, 10); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("SearchDocuments() error = %v, want context.DeadlineExceeded", err)
+	}
+
+	adapter.timeout = time.Minute
+	if res, err := adapter.SearchDocuments(ctx, "hotel", 0, 10); err != nil || res.TotalResultsCount != 1 {
+		t.Fatalf("SearchDocuments() = %+v, %v; want the document within the timeout", res, err)
+	}
+}
+
+func TestSearchHighlightsTitle(t *testing.T) {
+	ctx := context.Background()
+	adapter := newTestAdapter(t)
+	doc := models.Document{ID: "doc-1", DocumentBase: models.DocumentBase{Title: "Grand HOTEL", Abstract: "by the river"}}
+	if err := adapter.AddDocument(ctx, doc); err != nil {
+		t.Fatalf("AddDocument() error = %v", err)
+	}
+
+	res, err := adapter.SearchDocuments(ctx, "hotel", 0, 10)
+	if err != nil || res.TotalResultsCount != 1 {
+		t.Fatalf("result = %+v, err = %v", res, err)
+	}
+	if spans := res.ResultData[0].TitleSpans; len(spans) != 1 || doc.Title[spans[0].Start:spans[0].End] != "HOTEL" {
+		t.Fatalf("TitleSpans = %+v, want HOTEL", spans)
+	}
+}
+
+func TestAddDocumentGeneratesID(t *testing.T) {
+	adapter := newTestAdapter(t)
+
+	if err := adapter.AddDocument(context.Background(), models.Document{DocumentBase: models.DocumentBase{Title: "grand hotel"}}); err != nil {
+		t.Fatalf("AddDocument() error = %v", err)
+	}
+	res, err := adapter.SearchDocuments(context.Background(), "hotel", 0, 10)
+	if err != nil || res.TotalResultsCount != 1 || res.ResultData[0].ID == "" {
+		t.Fatalf("result = %+v, err = %v", res, err)
+	}
+}
+
+func TestAddDocumentURLIDReplacesEditedArticle(t *testing.T) {
+	ctx := context.Background()
+	adapter := newTestAdapter(t)
+	adapter.ids = wiki.IDURL
+
+	doc := models.Document{DocumentBase: models.DocumentBase{Title: "grand hotel", URL: "https://example.org/grand-hotel", Abstract: "old hotel"}}
+	if err := adapter.AddDocument(ctx, doc); err != nil {
+		t.Fatalf("AddDocument() error = %v", err)
+	}
+	doc.Abstract = "restored hotel"
+	if err := adapter.AddDocument(ctx, doc); err != nil {
+		t.Fatalf("AddDocument() error = %v", err)
+	}
+
+	res, err := adapter.SearchDocuments(ctx, "hotel", 0, 10)
+	if err != nil || res.TotalResultsCount != 1 {
+		t.Fatalf("result = %+v, err = %v; want the edit to replace the article", res, err)
+	}
+	if res, _ := adapter.SearchDocuments(ctx, "old", 0, 10); res.TotalResultsCount != 0 {
+		t.Fatalf("old abstract still found: %+v", res)
+	}
+}
+
+func TestSearchFindsHexID(t *testing.T) {
+	adapter := newTestAdapter(t)
+
+	doc := models.Document{DocumentBase: models.DocumentBase{Title: "grand hotel", URL: "https://example.org/grand-hotel"}}
+	id := wiki.DocumentID(doc)
+	doc.ID = id
+	if err := adapter.AddDocument(context.Background(), doc); err != nil {
+		t.Fatalf("AddDocument() error = %v", err)
+	}
+
+	res, err := adapter.SearchDocuments(context.Background(), "hotel", 0, 10)
+	if err != nil || res.TotalResultsCount != 1 {
+		t.Fatalf("result = %+v, err = %v", res, err)
+	}
+	if got := res.ResultData[0]; got.ID != id || got.Document.ID != id {
+		t.Fatalf("result ID %q, document ID %q; want %q", got.ID, got.Document.ID, id)
+	}
+}
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestOpenLogSkipsFailedRecords(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "documents.log")
+
+	adapter := newTestAdapter(t)
+	if _, err := adapter.openLog(ctx, discardLogger, path); err != nil {
+		t.Fatalf("openLog() error = %v", err)
+	}
+	// Shutdown cancels an add after it was logged, before it was indexed.
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	late := models.Document{ID: "late", DocumentBase: models.DocumentBase{Title: "late hotel"}}
+	if err := adapter.AddDocument(canceled, late); err == nil {
+		t.Fatal("AddDocument() with a canceled context error = nil")
+	}
+	if err := adapter.changes.Append([]byte("not a document")); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if err := adapter.AddDocument(ctx, models.Document{ID: "grand", DocumentBase: models.DocumentBase{Title: "grand hotel"}}); err != nil {
+		t.Fatalf("AddDocument() error = %v", err)
+	}
+	adapter.closeLog()
+
+	recovered := newTestAdapter(t)
+	if _, err := recovered.openLog(ctx, discardLogger, path); err != nil {
+		t.Fatalf("openLog() error = %v, want bad records skipped", err)
+	}
+	defer recovered.closeLog()
+
+	res, err := recovered.SearchDocuments(ctx, "hotel", 0, 10)
+	if err != nil || res.TotalResultsCount != 2 {
+		t.Fatalf("result = %+v, err = %v; want both logged documents", res, err)
+	}
+}
+
+func TestOpenLogRecoversAddedDocuments(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "documents.log")
+
+	adapter := newTestAdapter(t)
+	if _, err := adapter.openLog(ctx, discardLogger, path); err != nil {
+		t.Fatalf("openLog() error = %v", err)
+	}
+	for _, title := range []string{"grand hotel", "river barge"} {
+		doc := models.Document{ID: title, DocumentBase: models.DocumentBase{Title: title}}
+		if err := adapter.AddDocument(ctx, doc); err != nil {
+			t.Fatalf("AddDocument() error = %v", err)
+		}
+	}
+	// Crash: no snapshot is written, the log is all that is left.
+	adapter.closeLog()
+
+	recovered := newTestAdapter(t)
+	replayed, err := recovered.openLog(ctx, discardLogger, path)
+	if err != nil || replayed != 2 {
+		t.Fatalf("openLog() = %d, %v; want 2 replayed", replayed, err)
+	}
+	defer recovered.closeLog()
+
+	res, err := recovered.SearchDocuments(ctx, "hotel", 0, 10)
+	if err != nil || res.TotalResultsCount != 1 || res.ResultData[0].Document.Title != "grand hotel" {
+		t.Fatalf("result = %+v, err = %v; want the logged document", res, err)
+	}
+}
+
+// countingStore counts document reads.
+type countingStore struct {
+	*memory.Store
+	gets int
+}
+
+func (s *countingStore) GetDocument(id string) (models.Document, bool) {
+	s.gets++
+	return s.Store.GetDocument(id)
+}
+
+func (s *countingStore) DocumentRef(id string) (*models.Document, bool) {
+	s.gets++
+	return s.Store.DocumentRef(id)
+}
+
+func TestSearchHydration(t *testing.T) {
+	ctx := context.Background()
+
+	for _, lazy := range []bool{false, true} {
+		store := &countingStore{Store: memory.New()}
+		adapter := newTestAdapter(t)
+		adapter.documents, adapter.cache, adapter.lazy = store, search.NewDocumentCache(store), lazy
+
+		doc := models.Document{ID: "doc-1", DocumentBase: models.DocumentBase{Title: "grand hotel", Abstract: "a grand hotel"}}
+		if err := adapter.AddDocument(ctx, doc); err != nil {
+			t.Fatalf("AddDocument() error = %v", err)
+		}
+
+		res, err := adapter.SearchDocuments(ctx, "hotel", 0, 10)
+		if err != nil || res.TotalResultsCount != 1 {
+			t.Fatalf("lazy %v: result = %+v, err = %v", lazy, res, err)
+		}
+		got := res.ResultData[0]
+		if lazy && (store.gets != 0 || got.Document.ID != "" || got.Snippet != "" || got.ID != "doc-1") {
+			t.Fatalf("lazy: %d reads, result %+v; want the ID only and no reads", store.gets, got)
+		}
+		if !lazy && (store.gets != 1 || got.Document.ID != "doc-1" || got.Snippet == "") {
+			t.Fatalf("eager: %d reads, result %+v; want one read and the document", store.gets, got)
+		}
+		if lazy {
+			continue
+		}
+
+		// The store holds the document, so the cache serves it again.
+		runtime.GC()
+		if res, err = adapter.SearchDocuments(ctx, "hotel", 0, 10); err != nil || res.ResultData[0].Document.ID != "doc-1" {
+			t.Fatalf("second search: result = %+v, err = %v", res, err)
+		}
+		if store.gets != 1 {
+			t.Fatalf("second search: %d reads, want the document from the cache", store.gets)
+		}
+	}
+}
+
+func TestIndexStats(t *testing.T) {
+	ctx := context.Background()
+	adapter := newTestAdapter(t)
+	for i, title := range []string{"grand hotel", "river hotel"} {
+		doc := models.Document{ID: fmt.Sprintf("doc-%d", i), DocumentBase: models.DocumentBase{Title: title}}
+		if err := adapter.AddDocument(ctx, doc); err != nil {
+			t.Fatalf("AddDocument() error = %v", err)
+		}
+	}
+
+	stats := adapter.IndexStats()
+	if stats.Documents != 2 || stats.AvgDocLength != 2 || !stats.Analyzed {
+		t.Fatalf("stats = %+v, want 2 analyzed documents of 2 tokens", stats)
+	}
+	// grand, river and hotel, keyed under the title field.
+	if stats.Keys != 3 || stats.Postings != 4 {
+		t.Fatalf("stats = %+v, want 3 keys and 4 postings", stats)
+	}
+}
+
+func TestIndexStatsReusesAnalysis(t *testing.T) {
+	ctx := context.Background()
+	adapter := newTestAdapter(t)
+	add := func(id, title string) {
+		t.Helper()
+		doc := models.Document{ID: id, DocumentBase: models.DocumentBase{Title: title}}
+		if err := adapter.AddDocument(ctx, doc); err != nil {
+			t.Fatalf("AddDocument() error = %v", err)
+		}
+	}
+
+	add("doc-1", "grand hotel")
+	first := adapter.IndexStats()
+	if again := adapter.IndexStats(); again.AnalyzedAt != first.AnalyzedAt {
+		t.Fatalf("unchanged index analyzed again at %v, want the analysis of %v", again.AnalyzedAt, first.AnalyzedAt)
+	}
+
+	// A change makes the analysis stale at once without stats_interval.
+	add("doc-2", "river barge")
+	if stats := adapter.IndexStats(); stats.Keys != 4 || stats.Documents != 2 {
+		t.Fatalf("stats = %+v, want 4 keys of 2 documents", stats)
+	}
+
+	adapter.statsAge = time.Hour
+	add("doc-3", "small inn")
+	stats := adapter.IndexStats()
+	if stats.Keys != 4 || stats.Documents != 3 {
+		t.Fatalf("stats = %+v, want the recent analysis of 4 keys and a count of 3 documents", stats)
+	}
+	if stats = adapter.RefreshIndexStats(); stats.Keys != 6 {
+		t.Fatalf("refreshed stats = %+v, want 6 keys", stats)
+	}
+}
+
+func TestAnalyzeText(t *testing.T) {
+	adapter := newTestAdapter(t)
+	index, err := ftsbuiltin.BuildIndex("slicedradix")
+	if err != nil {
+		t.Fatalf("BuildIndex() error = %v", err)
+	}
+	adapter.service.Store(pkgfts.New(index, keygen.Trigram, pkgfts.WithPipeline(textproc.DefaultEnglishPipeline())))
+
+	analysis, err := adapter.AnalyzeText("The grand Hotels")
+	if err != nil {
+		t.Fatalf("AnalyzeText() error = %v", err)
+	}
+	want := models.TextAnalysis{Tokens: []models.AnalyzedToken{
+		{Token: "grand", Keys: []string{"gra", "ran", "and"}},
+		{Token: "hotel", Keys: []string{"hot", "ote", "tel"}},
+	}}
+	if !reflect.DeepEqual(analysis, want) {
+		t.Fatalf("AnalyzeText() = %+v, want %+v", analysis, want)
+	}
+}
+
+func TestReindexFromAdapter(t *testing.T) {
+	ctx := context.Background()
+	src := newTestAdapter(t)
+	for _, doc := range []models.Document{
+		{ID: "doc-2", DocumentBase: models.DocumentBase{Title: "river barge"}},
+		{ID: "doc-1", DocumentBase: models.DocumentBase{Title: "grand hotel"}},
+	} {
+		if err := src.AddDocument(ctx, doc); err != nil {
+			t.Fatalf("AddDocument() error = %v", err)
+		}
+	}
+
+	var ids []string
+	for doc, err := range src.Documents(ctx) {
+		if err != nil {
+			t.Fatalf("Documents() error = %v", err)
+		}
+		ids = append(ids, doc.ID)
+	}
+	if !slices.Equal(ids, []string{"doc-1", "doc-2"}) {
+		t.Fatalf("Documents() = %q, want ID order", ids)
+	}
+
+	dst := newTestAdapter(t)
+	if n, err := search.Reindex(ctx, src, dst); err != nil || n != 2 {
+		t.Fatalf("Reindex() = %d, %v; want 2, nil", n, err)
+	}
+	res, err := dst.SearchDocuments(ctx, "barge", 0, 10)
+	if err != nil || res.TotalResultsCount != 1 || res.ResultData[0].ID != "doc-2" {
+		t.Fatalf("result = %+v, err = %v", res, err)
+	}
+}
+
+// TestAddDocumentDuringSearch is meant for -race.
+func TestAddDocumentDuringSearch(t *testing.T) {
+	const docs = 200
+	ctx := context.Background()
+	adapter := newTestAdapter(t)
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for i := range docs {
+			doc := models.Document{ID: fmt.Sprintf("doc-%d", i), DocumentBase: models.DocumentBase{Title: "grand hotel", Abstract: fmt.Sprintf("abstract %d", i)}}
+			if err := adapter.AddDocument(ctx, doc); err != nil {
+				t.Errorf("AddDocument() error = %v", err)
+				return
+			}
+		}
+	})
+	wg.Go(func() {
+		for range docs {
+			if _, err := adapter.SearchDocuments(ctx, "hotel", 0, 5); err != nil {
+				t.Errorf("SearchDocuments() error = %v", err)
+				return
+			}
+		}
+	})
+	wg.Wait()
+
+	res, err := adapter.SearchDocuments(ctx, "hotel", 0, 1)
+	if err != nil || res.TotalResultsCount != docs {
+		t.Fatalf("TotalResultsCount = %d, err = %v, want %d", res.TotalResultsCount, err, docs)
+	}
+}
+
+func TestStartIndexingWorkers(t *testing.T) {
+	adapter := newTestAdapter(t)
+	cfg := &config.Config{FTS: config.FTSConfig{Indexers: 4, Progress: time.Hour}}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	const docs = 200
+	jobs, finish := startIndexing(context.Background(), log, cfg, adapter, docs)
+	for i := range docs {
+		jobs <- models.Document{ID: fmt.Sprintf("doc-%d", i), DocumentBase: models.DocumentBase{Title: "grand hotel"}}
+	}
+	finish()
+
+	res, err := adapter.SearchDocuments(context.Background(), "hotel", 0, 1)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	if res.TotalResultsCount != docs {
+		t.Fatalf("total = %d, want %d", res.TotalResultsCount, docs)
+	}
+}
+
+// slowEngine takes a while per document and counts the documents it began
+// and finished indexing.
+type slowEngine struct {
+	started, indexed atomic.Int32
+}
+
+func (e *slowEngine) IndexDocument(ctx context.Context, docID string, content string) error {
+	e.started.Add(1)
+	time.Sleep(2 * time.Millisecond)
+	e.indexed.Add(1)
+	return nil
+}
+
+func (e *slowEngine) SearchDocuments(ctx context.Context, query string, offset, maxResults int) (*models.SearchResult, error) {
+	return &models.SearchResult{}, nil
+}
+
+func TestFeedDocumentsInterruptedFinishesIndexing(t *testing.T) {
+	engine := &slowEngine{}
+	cfg := &config.Config{FTS: config.FTSConfig{Indexers: 2, Progress: time.Hour}}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	jobs, finish := startIndexing(context.Background(), log, cfg, engine, 0)
+
+	// An endless dump, cut short the way SIGTERM cuts the bulk load.
+	docs := make(chan models.Document)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case docs <- models.Document{ID: fmt.Sprintf("doc-%d", i)}:
+			case <-done:
+				return
+			}
+		}
+	}()
+	stop := make(chan struct{})
+	time.AfterFunc(20*time.Millisecond, func() { close(stop) })
+
+	store := memory.New()
+	loaded, interrupted := feedDocuments(log, docs, store, jobs, stop)
+	if !interrupted {
+		t.Fatal("feedDocuments() interrupted = false, want true")
+	}
+	finish()
+
+	started, indexed := engine.started.Load(), engine.indexed.Load()
+	if indexed == 0 || started != indexed {
+		t.Fatalf("indexed %d of %d started documents, want every started one finished", indexed, started)
+	}
+	// The document taken when the load stopped is stored but never sent.
+	if int(indexed) != loaded-1 || store.Len() != loaded {
+		t.Fatalf("indexed %d, loaded %d, stored %d; want all but the last loaded one indexed", indexed, loaded, store.Len())
+	}
+}
+
+func TestFeedDocumentsWithoutIndexing(t *testing.T) {
+	docs := make(chan models.Document, 3)
+	for i := range 3 {
+		docs <- models.Document{ID: fmt.Sprintf("doc-%d", i)}
+	}
+	close(docs)
+
+	store := memory.New()
+	loaded, interrupted := feedDocuments(slog.New(slog.DiscardHandler), docs, store, nil, nil)
+	if loaded != 3 || interrupted || store.Len() != 3 {
+		t.Fatalf("feedDocuments() = %d, %v; stored %d; want all 3 stored", loaded, interrupted, store.Len())
+	}
+}
+
+// hookStore runs during once, while Documents is being iterated.
+type hookStore struct {
+	*memory.Store
+	during func()
+}
+
+func (s *hookStore) Documents(ctx context.Context) iter.Seq2[models.Document, error] {
+	return func(yield func(models.Document, error) bool) {
+		for doc, err := range s.Store.Documents(ctx) {
+			if s.during != nil {
+				s.during()
+				s.during = nil
+			}
+			if !yield(doc, err) {
+				return
+			}
+		}
+	}
+}
+
+func TestRebuildSwapsIndex(t *testing.T) {
+	ctx := context.Background()
+	store := &hookStore{Store: memory.New()}
+	adapter := newTestAdapter(t)
+	adapter.documents, adapter.cache = store, search.NewDocumentCache(store)
+	// The new index stems, so "hotel" finds "hotels" only once it is in.
+	adapter.fresh = func() (*pkgfts.Service, error) {
+		index, err := ftsbuiltin.BuildIndex("slicedradix")
+		if err != nil {
+			return nil, err
+		}
+		return pkgfts.New(index, keygen.Word, pkgfts.WithFields("title", "abstract"), pkgfts.WithPipeline(textproc.DefaultEnglishPipeline())), nil
+	}
+
+	for _, title := range []string{"grand hotels", "river barges"} {
+		if err := adapter.AddDocument(ctx, models.Document{ID: title, DocumentBase: models.DocumentBase{Title: title}}); err != nil {
+			t.Fatalf("AddDocument() error = %v", err)
+		}
+	}
+	count := func(query string) int {
+		t.Helper()
+		res, err := adapter.SearchDocuments(ctx, query, 0, 10)
+		if err != nil {
+			t.Fatalf("SearchDocuments(%q) error = %v", query, err)
+		}
+		return res.TotalResultsCount
+	}
+	if n := count("hotel"); n != 0 {
+		t.Fatalf("before rebuild: %d results, want 0", n)
+	}
+
+	store.during = func() {
+		if n := count("hotels"); n != 1 {
+			t.Errorf("during rebuild: %d results, want the old index to answer", n)
+		}
+		if _, err := adapter.Rebuild(ctx); !errors.Is(err, search.ErrRebuildRunning) {
+			t.Errorf("second Rebuild() error = %v, want ErrRebuildRunning", err)
+		}
+		for _, doc := range []models.Document{
+			{ID: "late", DocumentBase: models.DocumentBase{Title: "late hotels"}},
+			{ID: "river barges", DocumentBase: models.DocumentBase{Title: "river barges"}},
+		} {
+			if err := adapter.AddDocument(ctx, doc); err != nil {
+				t.Errorf("AddDocument() error = %v", err)
+			}
+		}
+	}
+	// The re-added document counts once.
+	n, err := adapter.Rebuild(ctx)
+	if err != nil || n != 3 {
+		t.Fatalf("Rebuild() = %d, %v; want 3 documents", n, err)
+	}
+	if n := count("hotel"); n != 2 {
+		t.Fatalf("after rebuild: %d results, want 2 including the document added meanwhile", n)
+	}
+}
+
+func TestFormatLevels(t *testing.T) {
+	if got, want := formatLevels([]float64{12, 3.254, 0}), "L0=12.00 L1=3.25 L2=0.00"; got != want {
+		t.Fatalf("formatLevels() = %q, want %q", got, want)
+	}
+}
+
+func TestFormatMiB(t *testing.T) {
+	var before, after uint64 = 3 << 20, 1 << 20
+	if got := formatMiB(3 << 19); got != "1.5 MiB" {
+		t.Fatalf("formatMiB(1.5 MiB) = %q", got)
+	}
+	if got := formatMiB(after - before); got != "-2.0 MiB" {
+		t.Fatalf("formatMiB(shrunk heap) = %q, want -2.0 MiB", got)
+	}
+}
+
+// slowStore stands in for a store whose reads wait on disk.
+type slowStore struct {
+	*memory.Store
+	delay time.Duration
+}
+
+func (s slowStore) GetDocument(id string) (models.Document, bool) {
+	time.Sleep(s.delay)
+	return s.Store.GetDocument(id)
+}
+
+func (s slowStore) DocumentRef(id string) (*models.Document, bool) {
+	time.Sleep(s.delay)
+	return s.Store.DocumentRef(id)
+}
+
+func newHydrationAdapter(tb testing.TB, n int, delay time.Duration) (*serviceAdapter, []string) {
+	tb.Helper()
+
+	store := memory.New()
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("doc-%03d", i)
+		// Every tenth document is missing from the store.
+		if i%10 == 9 {
+			continue
+		}
+		doc := models.Document{ID: ids[i], DocumentBase: models.DocumentBase{Title: "hotel " + ids[i], Abstract: "a grand hotel"}}
+		if err := store.SaveDocument(doc); err != nil {
+			tb.Fatalf("SaveDocument() error = %v", err)
+		}
+	}
+
+	slow := slowStore{Store: store, delay: delay}
+	adapter := &serviceAdapter{
+		documents:     slow,
+		cache:         search.NewDocumentCache(slow),
+		snippetWindow: pkgfts.DefaultSnippetWindow,
+	}
+	index, err := ftsbuiltin.BuildIndex("slicedradix")
+	if err != nil {
+		tb.Fatalf("BuildIndex() error = %v", err)
+	}
+	adapter.service.Store(pkgfts.New(index, keygen.Word))
+	return adapter, ids
+}
+
+func unhydrated(ids []string) *models.SearchResult {
+	result := &models.SearchResult{ResultData: make([]models.ResultData, len(ids))}
+	for i, id := range ids {
+		result.ResultData[i].ID = id
+	}
+	return result
+}
+
+func TestHydrateKeepsOrder(t *testing.T) {
+	for _, hydrators := range []int{1, 8} {
+		adapter, ids := newHydrationAdapter(t, 100, 0)
+		adapter.hydrators = hydrators
+
+		result := unhydrated(ids)
+		adapter.hydrate(adapter.service.Load(), "hotel", result)
+		for i, data := range result.ResultData {
+			missing := i%10 == 9
+			if data.ID != ids[i] || (data.Document.ID == "") != missing {
+				t.Fatalf("hydrators %d: result %d = %+v, want %s hydrated unless missing", hydrators, i, data, ids[i])
+			}
+			if !missing && data.Document.ID != ids[i] {
+				t.Fatalf("hydrators %d: result %d has document %s", hydrators, i, data.Document.ID)
+			}
+		}
+	}
+}
+
+func BenchmarkHydrate(b *testing.B) {
+	for _, bench := range []struct {
+		name      string
+		hydrators int
+	}{
+		{name: "serial", hydrators: 1},
+		{name: "pooled", hydrators: 8},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			adapter, ids := newHydrationAdapter(b, 500, 20*time.Microsecond)
+			adapter.hydrators = bench.hydrators
+			svc := adapter.service.Load()
+			for b.Loop() {
+				adapter.hydrate(svc, "hotel", unhydrated(ids))
+			}
+		})
+	}
+}
+
+func TestTerminalWriterMutes(t *testing.T) {
+	var out strings.Builder
+	terminal := &terminalWriter{w: &out}
+
+	_, _ = terminal.Write([]byte("before\n"))
+	terminal.muted.Store(true)
+	if n, err := terminal.Write([]byte("during\n")); n != len("during\n") || err != nil {
+		t.Fatalf("muted Write() = %d, %v; want the whole line accepted", n, err)
+	}
+	terminal.muted.Store(false)
+	_, _ = terminal.Write([]byte("after\n"))
+
+	if got := out.String(); got != "before\nafter\n" {
+		t.Fatalf("terminal output = %q, want the muted line dropped", got)
+	}
+}
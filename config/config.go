@@ -2,28 +2,74 @@ package config
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/dariasmyr/fts-engine/pkg/ftsbuiltin"
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Config struct {
-	Env      string     `yaml:"env" env-default:"local"`
-	DumpPath string     `yaml:"dump_path" env-default:"./data/enwiki-latest-abstract10.xml.gz"`
-	FTS      FTSConfig  `yaml:"fts"`
-	Mode     ModeConfig `yaml:"mode"`
+	Env       string   `yaml:"env" env-default:"local"`
+	DumpPath  string   `yaml:"dump_path" env-default:"./data/enwiki-latest-abstract10.xml.gz"`
+	DumpPaths []string `yaml:"dump_paths"`
+	// IDStrategy chooses what document IDs are derived from: content (title,
+	// URL and abstract), url or title-url.
+	IDStrategy string `yaml:"id_strategy" env-default:"content"`
+	// EnrichExtracts fetches each document's full extract from the Wikipedia
+	// API while the dump is loaded; see Enrich.
+	EnrichExtracts bool         `yaml:"enrich_extracts" env-default:"false"`
+	Enrich         EnrichConfig `yaml:"enrich"`
+	FTS            FTSConfig    `yaml:"fts"`
+	Mode           ModeConfig   `yaml:"mode"`
+	HTTP           HTTPConfig   `yaml:"http"`
+	GRPC           GRPCConfig   `yaml:"grpc"`
+}
+
+// EnrichConfig bounds the extract fetches: Workers run at once and together
+// start at most Rate a second, Burst at once after a pause. A request that
+// gets 429 or a 5xx status is retried up to Retries times, Backoff apart at
+// first and twice as long each time.
+type EnrichConfig struct {
+	Workers int           `yaml:"workers" env-default:"4"`
+	Rate    float64       `yaml:"rate" env-default:"10"`
+	Burst   int           `yaml:"burst" env-default:"1"`
+	Timeout time.Duration `yaml:"timeout" env-default:"10s"`
+	Retries int           `yaml:"retries" env-default:"3"`
+	Backoff time.Duration `yaml:"backoff" env-default:"500ms"`
 }
 
 type FTSConfig struct {
-	Engine   string         `yaml:"engine" env-default:"trie"`
-	Index    string         `yaml:"index"`
-	KeyGen   string         `yaml:"keygen"`
-	Filter   string         `yaml:"filter" env-default:"none"`
-	Snapshot SnapshotConfig `yaml:"snapshot"`
-	Bloom    BloomConfig    `yaml:"bloom"`
-	Cuckoo   CuckooConfig   `yaml:"cuckoo"`
-	Ribbon   RibbonConfig   `yaml:"ribbon"`
-	Pipeline PipelineConfig `yaml:"pipeline"`
+	Engine        string             `yaml:"engine" env-default:"trie"`
+	Index         string             `yaml:"index"`
+	KeyGen        string             `yaml:"keygen"`
+	NGram         int                `yaml:"ngram_size" env-default:"3"`
+	Filter        string             `yaml:"filter" env-default:"none"`
+	Positions     bool               `yaml:"positions" env-default:"true"`
+	Reverse       bool               `yaml:"reverse_index" env-default:"false"`
+	AllKeys       bool               `yaml:"require_all_keys" env-default:"false"`
+	Fields        []string           `yaml:"fields"`
+	Weights       map[string]float64 `yaml:"field_weights"`
+	Snippet       int                `yaml:"snippet_window" env-default:"160"`
+	Hydrate       bool               `yaml:"hydrate" env-default:"true"`
+	Suggest       int                `yaml:"suggest_below" env-default:"3"`
+	SearchWorkers int                `yaml:"search_workers" env-default:"0"`
+	SearchTimeout time.Duration      `yaml:"search_timeout" env-default:"0s"`
+	ResultCache   int                `yaml:"result_cache" env-default:"0"`
+	StatsInterval time.Duration      `yaml:"stats_interval" env-default:"0s"`
+	Indexers      int                `yaml:"index_workers" env-default:"1"`
+	Progress      time.Duration      `yaml:"progress_interval" env-default:"10s"`
+	Ranking       string             `yaml:"ranking" env-default:"matches"`
+	BM25          BM25Config         `yaml:"bm25"`
+	Matches       MatchWeightsConfig `yaml:"match_weights"`
+	Snapshot      SnapshotConfig     `yaml:"snapshot"`
+	Bloom         BloomConfig        `yaml:"bloom"`
+	Cuckoo        CuckooConfig       `yaml:"cuckoo"`
+	Ribbon        RibbonConfig       `yaml:"ribbon"`
+	Pipeline      PipelineConfig     `yaml:"pipeline"`
 }
 
 type SnapshotConfig struct {
@@ -36,6 +82,18 @@ type SnapshotConfig struct {
 	BufferSize     int    `yaml:"buffer_size" env-default:"1048576"`
 	FlushThreshold int    `yaml:"flush_threshold" env-default:"262144"`
 	SyncFile       bool   `yaml:"sync_file" env-default:"true"`
+	Compress       bool   `yaml:"compress" env-default:"false"`
+	LogPath        string `yaml:"log_path" env-default:""`
+}
+
+type BM25Config struct {
+	K1 float64 `yaml:"k1" env-default:"1.2"`
+	B  float64 `yaml:"b" env-default:"0.75"`
+}
+
+type MatchWeightsConfig struct {
+	Unique float64 `yaml:"unique" env-default:"1000000"`
+	Total  float64 `yaml:"total" env-default:"1"`
 }
 
 type BloomConfig struct {
@@ -60,15 +118,34 @@ type RibbonConfig struct {
 
 type ModeConfig struct {
 	Type string `yaml:"type" env-default:"prod"`
+	// MemProfile logs the memory taken by indexing in prod and server mode;
+	// experiment mode always does.
+	MemProfile bool `yaml:"mem_profile" env-default:"false"`
+}
+
+// GRPCConfig enables the gRPC API next to the HTTP one in server mode when
+// Address is set.
+type GRPCConfig struct {
+	Address string `yaml:"address" env-default:""`
+}
+
+type HTTPConfig struct {
+	Address    string `yaml:"address" env-default:"localhost:8080"`
+	MaxResults int    `yaml:"max_results" env-default:"10"`
 }
 
 type PipelineConfig struct {
-	Lowercase   bool `yaml:"lowercase" env-default:"true"`
-	StopwordsEN bool `yaml:"stopwords_en" env-default:"true"`
-	StopwordsRU bool `yaml:"stopwords_ru" env-default:"false"`
-	StemEN      bool `yaml:"stem_en" env-default:"true"`
-	StemRU      bool `yaml:"stem_ru" env-default:"false"`
-	MinLength   int  `yaml:"min_length" env-default:"3"`
+	Lowercase   bool     `yaml:"lowercase" env-default:"true"`
+	StopwordsEN bool     `yaml:"stopwords_en" env-default:"true"`
+	StopwordsRU bool     `yaml:"stopwords_ru" env-default:"false"`
+	Stopwords   []string `yaml:"stopwords"`
+	StemEN      bool     `yaml:"stem_en" env-default:"true"`
+	StemRU      bool     `yaml:"stem_ru" env-default:"false"`
+	MinLength   int      `yaml:"min_length" env-default:"3"`
+	InnerChars  string   `yaml:"inner_chars" env-default:""`
+	// PhraseStopwords and PhraseStem run those stages on quoted phrases too.
+	PhraseStopwords bool `yaml:"phrase_stopwords" env-default:"true"`
+	PhraseStem      bool `yaml:"phrase_stem" env-default:"true"`
 }
 
 func MustLoad() (*Config, string) {
@@ -77,9 +154,13 @@ func MustLoad() (*Config, string) {
 
 func mustLoad() (*Config, string) {
 	configPathFlag := flag.String("config", "", "Path to the config file")
+	indexFlag := flag.String("index", "", "Index backend; overrides fts.index")
+	analyzeFlag := flag.Bool("analyze", false, "Build the index, print its structure stats and exit; same as mode.type=experiment")
+	memProfileFlag := flag.Bool("mem-profile", false, "Log the memory taken by indexing; same as mode.mem_profile")
 	flag.Parse()
 
 	cfg := defaultConfig()
+	source := "defaults"
 	configPath := *configPathFlag
 	if configPath == "" {
 		configPath = fetchConfigPath()
@@ -90,13 +171,22 @@ func mustLoad() (*Config, string) {
 			if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
 				panic("error loading config file: " + err.Error())
 			}
-			validateConfig(&cfg)
-			return &cfg, configPath
+			source = configPath
 		}
 	}
 
+	if *indexFlag != "" {
+		cfg.FTS.Index = *indexFlag
+	}
+	if *analyzeFlag {
+		cfg.Mode.Type = "experiment"
+	}
+	if *memProfileFlag {
+		cfg.Mode.MemProfile = true
+	}
+
 	validateConfig(&cfg)
-	return &cfg, "defaults"
+	return &cfg, source
 }
 
 // fetchConfigPath fetches domain path from environment variable or default if it was not set in command line flag.
@@ -112,13 +202,43 @@ func fetchConfigPath() string {
 
 func defaultConfig() Config {
 	return Config{
-		Env:      "local",
-		DumpPath: "./data/enwiki-latest-abstract1.xml.gz",
+		Env:        "local",
+		DumpPath:   "./data/enwiki-latest-abstract1.xml.gz",
+		IDStrategy: "content",
+		Enrich: EnrichConfig{
+			Workers: 4,
+			Rate:    10,
+			Burst:   1,
+			Timeout: 10 * time.Second,
+			Retries: 3,
+			Backoff: 500 * time.Millisecond,
+		},
 		FTS: FTSConfig{
-			Engine: "trie",
-			Index:  "slicedradix",
-			KeyGen: "word",
-			Filter: "ribbon",
+			Engine:        "trie",
+			Index:         "slicedradix",
+			KeyGen:        "word",
+			NGram:         3,
+			Filter:        "ribbon",
+			Positions:     true,
+			Fields:        []string{"title", "abstract", "extract"},
+			Weights:       map[string]float64{"title": 3.0, "abstract": 1.0, "extract": 0.5},
+			Snippet:       160,
+			Hydrate:       true,
+			Suggest:       3,
+			ResultCache:   256,
+			SearchTimeout: 5 * time.Second,
+			StatsInterval: time.Minute,
+			Indexers:      1,
+			Progress:      10 * time.Second,
+			Ranking:       "matches",
+			BM25: BM25Config{
+				K1: 1.2,
+				B:  0.75,
+			},
+			Matches: MatchWeightsConfig{
+				Unique: 1000000,
+				Total:  1,
+			},
 			Snapshot: SnapshotConfig{
 				Enabled:        true,
 				Path:           "./data/segments/local.fidx",
@@ -148,19 +268,53 @@ func defaultConfig() Config {
 				MaxAttempts:   50,
 			},
 			Pipeline: PipelineConfig{
-				Lowercase:   true,
-				StopwordsEN: true,
-				StopwordsRU: false,
-				StemEN:      true,
-				StemRU:      false,
-				MinLength:   3,
+				Lowercase:       true,
+				StopwordsEN:     true,
+				StopwordsRU:     false,
+				StemEN:          true,
+				StemRU:          false,
+				MinLength:       3,
+				PhraseStopwords: true,
+				PhraseStem:      true,
 			},
 		},
 		Mode: ModeConfig{Type: "prod"},
+		HTTP: HTTPConfig{
+			Address:    "localhost:8080",
+			MaxResults: 10,
+		},
 	}
 }
 
 func validateConfig(cfg *Config) {
+	if len(cfg.DumpPaths) == 0 {
+		cfg.DumpPaths = []string{cfg.DumpPath}
+	}
+
+	switch cfg.IDStrategy {
+	case "":
+		cfg.IDStrategy = "content"
+	case "content", "url", "title-url":
+	default:
+		panic("unknown id_strategy: " + cfg.IDStrategy)
+	}
+
+	if cfg.Enrich.Workers < 0 {
+		panic("enrich workers must be >= 0")
+	}
+
+	if cfg.Enrich.Workers == 0 {
+		cfg.Enrich.Workers = 4
+	}
+
+	if cfg.Enrich.Rate < 0 {
+		panic("enrich rate must be >= 0")
+	}
+
+	if cfg.Enrich.Burst < 0 || cfg.Enrich.Retries < 0 || cfg.Enrich.Timeout < 0 || cfg.Enrich.Backoff < 0 {
+		panic("enrich burst, retries, timeout and backoff must be >= 0")
+	}
+
 	if cfg.FTS.Index == "" {
 		cfg.FTS.Index = "radix"
 	}
@@ -173,31 +327,49 @@ func validateConfig(cfg *Config) {
 		cfg.FTS.Filter = "none"
 	}
 
+	if cfg.FTS.Ranking == "" {
+		cfg.FTS.Ranking = "matches"
+	}
+
+	if len(cfg.FTS.Fields) == 0 {
+		cfg.FTS.Fields = []string{"title", "abstract", "extract"}
+	}
+
 	if cfg.FTS.Snapshot.Path == "" {
 		cfg.FTS.Snapshot.Path = "./data/segments/default.fidx"
 	}
 
-	if cfg.FTS.Snapshot.BufferSize <= 0 {
+	if cfg.FTS.Snapshot.BufferSize < 0 || cfg.FTS.Snapshot.FlushThreshold < 0 {
+		panic("snapshot buffer_size and flush_threshold must be >= 0")
+	}
+
+	if cfg.FTS.Snapshot.BufferSize == 0 {
 		cfg.FTS.Snapshot.BufferSize = 1048576
 	}
 
-	if cfg.FTS.Snapshot.FlushThreshold <= 0 {
+	if cfg.FTS.Snapshot.FlushThreshold == 0 {
 		cfg.FTS.Snapshot.FlushThreshold = 262144
 	}
 
+	if cfg.FTS.Snapshot.FlushThreshold > cfg.FTS.Snapshot.BufferSize {
+		panic("snapshot flush_threshold must not exceed buffer_size")
+	}
+
 	switch cfg.FTS.Engine {
 	case "trie":
-		switch cfg.FTS.Index {
-		case "radix", "slicedradix", "hamt", "hamtpointered":
-		default:
-			panic("unknown index type: " + cfg.FTS.Index)
+		if !slices.Contains(ftsbuiltin.IndexNames(), cfg.FTS.Index) {
+			panic(fmt.Sprintf("unknown index type %q (want one of %s)", cfg.FTS.Index, strings.Join(ftsbuiltin.IndexNames(), ", ")))
 		}
 	default:
-		panic("unknown fts engine: " + cfg.FTS.Engine)
+		panic(fmt.Sprintf("unknown fts engine %q (want trie)", cfg.FTS.Engine))
 	}
 
 	switch cfg.FTS.KeyGen {
-	case "word":
+	case "word", "trigram":
+	case "ngram":
+		if cfg.FTS.NGram <= 0 {
+			panic("ngram_size must be > 0")
+		}
 	default:
 		panic("unknown keygen type: " + cfg.FTS.KeyGen)
 	}
@@ -208,6 +380,77 @@ func validateConfig(cfg *Config) {
 		panic("unknown filter type: " + cfg.FTS.Filter)
 	}
 
+	for _, field := range cfg.FTS.Fields {
+		switch field {
+		case "title", "abstract", "extract", "url":
+		default:
+			panic("unknown document field: " + field)
+		}
+	}
+
+	if cfg.FTS.Snippet <= 0 {
+		cfg.FTS.Snippet = 160
+	}
+
+	if cfg.FTS.Suggest < 0 {
+		panic("suggest_below must be >= 0")
+	}
+
+	if cfg.FTS.SearchWorkers < 0 {
+		panic("search_workers must be >= 0")
+	}
+
+	if cfg.FTS.SearchTimeout < 0 {
+		panic("search_timeout must be >= 0")
+	}
+
+	if cfg.FTS.ResultCache < 0 {
+		panic("result_cache must be >= 0")
+	}
+
+	if cfg.FTS.StatsInterval < 0 {
+		panic("stats_interval must be >= 0")
+	}
+
+	if cfg.FTS.Indexers < 0 {
+		panic("index_workers must be >= 0")
+	}
+
+	if cfg.FTS.Indexers == 0 {
+		cfg.FTS.Indexers = 1
+	}
+
+	if cfg.FTS.Progress < 0 {
+		panic("progress_interval must be >= 0")
+	}
+
+	if cfg.FTS.Progress == 0 {
+		cfg.FTS.Progress = 10 * time.Second
+	}
+
+	for field, weight := range cfg.FTS.Weights {
+		if weight < 0 {
+			panic("field weight must be >= 0: " + field)
+		}
+	}
+
+	switch cfg.FTS.Ranking {
+	case "matches":
+	case "bm25":
+		if cfg.FTS.BM25.K1 < 0 {
+			panic("bm25 k1 must be >= 0")
+		}
+		if cfg.FTS.BM25.B < 0 || cfg.FTS.BM25.B > 1 {
+			panic("bm25 b must be in range [0..1]")
+		}
+	case "weighted":
+		if cfg.FTS.Matches.Unique < 0 || cfg.FTS.Matches.Total < 0 {
+			panic("match weights must be >= 0")
+		}
+	default:
+		panic("unknown ranking type: " + cfg.FTS.Ranking)
+	}
+
 	if cfg.FTS.Cuckoo.BucketCount <= 0 {
 		panic("cuckoo bucket_count must be > 0")
 	}
@@ -234,8 +477,16 @@ func validateConfig(cfg *Config) {
 		}
 	}
 
+	if cfg.HTTP.Address == "" {
+		cfg.HTTP.Address = "localhost:8080"
+	}
+
+	if cfg.HTTP.MaxResults <= 0 {
+		cfg.HTTP.MaxResults = 10
+	}
+
 	switch cfg.Mode.Type {
-	case "prod", "experiment":
+	case "prod", "experiment", "server":
 	default:
 		panic("unknown mode type: " + cfg.Mode.Type)
 	}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/dariasmyr/fts-engine/pkg/fts"
+	"github.com/dariasmyr/fts-engine/pkg/keygen"
+)
+
+// mapIndex is the smallest backend fts.New accepts: a map from key to
+// per-document counts. It implements fts.Index only, so phrase queries,
+// fuzzy search and deletes are unavailable; add fts.PositionalIndex,
+// fts.FuzzySearcher or fts.Deleter to unlock them.
+type mapIndex struct {
+	mu   sync.RWMutex
+	keys map[string]map[fts.DocID]uint32
+}
+
+func newMapIndex() *mapIndex {
+	return &mapIndex{keys: make(map[string]map[fts.DocID]uint32)}
+}
+
+func (m *mapIndex) Insert(key string, id fts.DocID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	docs, ok := m.keys[key]
+	if !ok {
+		docs = make(map[fts.DocID]uint32)
+		m.keys[key] = docs
+	}
+	docs[id]++
+	return nil
+}
+
+// Search returns fresh postings, as fts.Index requires: later inserts must
+// not change what a running search already holds.
+func (m *mapIndex) Search(key string) ([]fts.DocRef, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	refs := make([]fts.DocRef, 0, len(m.keys[key]))
+	for id, count := range m.keys[key] {
+		refs = append(refs, fts.DocRef{ID: id, Count: count})
+	}
+	slices.SortFunc(refs, func(a, b fts.DocRef) int { return cmp.Compare(a.ID, b.ID) })
+	return refs, nil
+}
+
+func main() {
+	ctx := context.Background()
+
+	// Any fts.Index goes where the built-in ones do; the key generator
+	// decides what the index stores, here trigrams.
+	engine := fts.New(newMapIndex(), keygen.Trigram)
+
+	_ = engine.IndexDocument(ctx, "doc-1", "Wikipedia: Rosa is a French hotel barge")
+	_ = engine.IndexDocument(ctx, "doc-2", "Rosa runs hotel operations in France")
+
+	res, err := engine.SearchDocuments(ctx, "hotels", 10)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("results=%d\n", res.TotalResultsCount)
+	for _, item := range res.Results {
+		fmt.Printf("id=%s unique=%d total=%d\n", item.ID, item.UniqueMatches, item.TotalMatches)
+	}
+}
@@ -1,11 +1,13 @@
 module github.com/dariasmyr/fts-engine
 
-go 1.25
+go 1.25.0
 
 require (
 	github.com/ilyakaznacheev/cleanenv v1.5.0
 	github.com/jroimartin/gocui v0.5.0
 	github.com/kljensen/snowball v0.10.0
+	google.golang.org/grpc v1.84.0
+	google.golang.org/protobuf v1.36.11
 )
 
 require (
@@ -14,6 +16,10 @@ require (
 	github.com/mattn/go-runewidth v0.0.16 // indirect
 	github.com/nsf/termbox-go v1.1.1 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
+	golang.org/x/net v0.57.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	golang.org/x/text v0.40.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260706201446-f0a921348800 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 	olympos.io/encoding/edn v0.0.0-20201019073823-d3554ca0b0a3 // indirect
 )
@@ -6,38 +6,45 @@ import (
 	"fmt"
 	"github.com/dariasmyr/fts-engine/internal/domain/models"
 	"github.com/dariasmyr/fts-engine/internal/lib/logger/sl"
+	"github.com/dariasmyr/fts-engine/internal/services/search"
+	"github.com/dariasmyr/fts-engine/internal/utils"
+	pkgfts "github.com/dariasmyr/fts-engine/pkg/fts"
+	"io"
 	"log/slog"
 	"os"
-	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/jroimartin/gocui"
 )
 
-type SearchEngine interface {
-	IndexDocument(
-		ctx context.Context,
-		docID string,
-		content string,
-	) error
-	SearchDocuments(
-		ctx context.Context,
-		query string,
-		maxResults int,
-	) (*models.SearchResult, error)
-}
+// fuzzyFallbackDistance is used to retry a query that found nothing when the
+// engine implements search.FuzzySearcher.
+const fuzzyFallbackDistance = 1
+
+// addCommand prefixes a search input that adds a document instead of
+// searching: ":add Title | abstract". It needs a search.DocumentAdder engine.
+const addCommand = ":add "
 
 type CUI struct {
 	ctx        context.Context
 	cui        *gocui.Gui
-	ftsService SearchEngine
-	documents  map[string]models.Document
+	ftsService search.Searcher
+	documents  search.DocumentStore
 	log        *slog.Logger
 	maxResults int
+
+	query  string
+	offset int
+	total  int
+
+	// cancel stops the search in flight; searchSeq numbers the searches so
+	// a late result of a superseded one is dropped.
+	cancel    context.CancelFunc
+	searchSeq uint64
 }
 
-func New(ctx context.Context, log *slog.Logger, ftsService SearchEngine, documents map[string]models.Document, maxResults int) *CUI {
+func New(ctx context.Context, log *slog.Logger, ftsService search.Searcher, documents search.DocumentStore, maxResults int) *CUI {
 	g, err := gocui.NewGui(gocui.OutputNormal)
 	if err != nil {
 		log.Error("Failed to create GUI:", "error", sl.Err(err))
@@ -61,6 +68,7 @@ func (c *CUI) Start() error {
 	c.cui.Cursor = true
 	c.cui.SetManagerFunc(c.layout)
 	defer c.cui.Close()
+	defer c.cancelSearch()
 
 	if err := c.cui.SetKeybinding("", gocui.KeyCtrlC, gocui.ModNone, quit); err != nil {
 		c.log.Error("Failed to set keybinding:", "error", sl.Err(err))
@@ -72,6 +80,19 @@ func (c *CUI) Start() error {
 		c.log.Error("Failed to set keybinding:", "error", sl.Err(err))
 	}
 
+	if err := c.cui.SetKeybinding("", gocui.KeyF5, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
+		return c.refreshStats(g)
+	}); err != nil {
+		c.log.Error("Failed to set keybinding:", "error", sl.Err(err))
+	}
+
+	if err := c.cui.SetKeybinding("", gocui.KeyPgdn, gocui.ModNone, c.nextPage); err != nil {
+		c.log.Error("Failed to set keybinding:", "error", sl.Err(err))
+	}
+	if err := c.cui.SetKeybinding("", gocui.KeyPgup, gocui.ModNone, c.prevPage); err != nil {
+		c.log.Error("Failed to set keybinding:", "error", sl.Err(err))
+	}
+
 	if err := c.cui.SetKeybinding("output", gocui.KeyArrowDown, gocui.ModNone, scrollDown); err != nil {
 		c.log.Error("Failed to set keybinding:", "error", sl.Err(err))
 	}
@@ -105,14 +126,37 @@ func (c *CUI) Start() error {
 	return nil
 }
 
+// setMaxResults applies the Max Results box. Bad input keeps the old value
+// and says so in the time view; the box is rewritten with the value in use.
 func (c *CUI) setMaxResults(g *gocui.Gui, v *gocui.View) error {
-	maxResultsStr := strings.TrimSpace(v.Buffer())
-	if maxResultsInt, err := strconv.Atoi(maxResultsStr); err == nil {
-		c.maxResults = maxResultsInt
+	maxResults, err := parseMaxResults(v.Buffer())
+	if err != nil {
+		timeView, viewErr := g.View("time")
+		if viewErr != nil {
+			return viewErr
+		}
+		timeView.Clear()
+		fmt.Fprintf(timeView, "\033[31mMax Results: %v\033[0m\n", err)
+		maxResults = c.maxResults
 	}
+	c.maxResults = maxResults
+
+	v.Clear()
+	_ = v.SetCursor(0, 0)
+	fmt.Fprintf(v, "%d", c.maxResults)
 	return nil
 }
 
+// parseMaxResults parses the Max Results box. Values below 1 would make
+// every page empty, so they are raised to 1.
+func parseMaxResults(buf string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(buf))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", strings.TrimSpace(buf))
+	}
+	return max(n, 1), nil
+}
+
 func scrollDown(g *gocui.Gui, v *gocui.View) error {
 	_, oy := v.Origin()
 	_, sy := v.Size()
@@ -140,7 +184,7 @@ func (c *CUI) layout(g *gocui.Gui) error {
 		return fmt.Errorf("terminal window is too small")
 	}
 
-	if v, err := g.SetView("time", 0, 0, maxX/4, maxY-2); err != nil {
+	if v, err := g.SetView("time", 0, 0, maxX/4, maxY/2-1); err != nil {
 		if !errors.Is(err, gocui.ErrUnknownView) {
 			return err
 		}
@@ -149,6 +193,18 @@ func (c *CUI) layout(g *gocui.Gui) error {
 		v.Frame = true
 	}
 
+	if v, err := g.SetView("stats", 0, maxY/2, maxX/4, maxY-2); err != nil {
+		if !errors.Is(err, gocui.ErrUnknownView) {
+			return err
+		}
+		v.Title = "Index Stats (F5)"
+		v.Wrap = true
+		v.Frame = true
+		if err := c.refreshStats(g); err != nil {
+			return err
+		}
+	}
+
 	if v, err := g.SetView("input", maxX/4+1, 2, maxX-2, 4); err != nil {
 		if !errors.Is(err, gocui.ErrUnknownView) {
 			return err
@@ -183,10 +239,113 @@ func (c *CUI) layout(g *gocui.Gui) error {
 }
 
 func (c *CUI) search(g *gocui.Gui, v *gocui.View, ctx context.Context, searchQuery string) error {
-	searchQuery = strings.TrimSpace(v.Buffer())
+	if rest, ok := strings.CutPrefix(searchQuery, addCommand); ok {
+		return c.addDocument(g, ctx, rest)
+	}
+
+	c.query = strings.TrimSpace(v.Buffer())
+	c.offset = 0
+	return c.showPage(g, ctx)
+}
+
+// addDocument indexes a document typed as "Title | abstract".
+func (c *CUI) addDocument(g *gocui.Gui, ctx context.Context, input string) error {
+	outputView, err := g.View("output")
+	if err != nil {
+		return err
+	}
+	outputView.Clear()
+
+	adder, ok := c.ftsService.(search.DocumentAdder)
+	if !ok {
+		fmt.Fprintln(outputView, "\033[31mThis engine cannot add documents\033[0m")
+		return nil
+	}
+
+	title, abstract, _ := strings.Cut(input, "|")
+	doc := models.Document{DocumentBase: models.DocumentBase{
+		Title:    strings.TrimSpace(title),
+		Abstract: strings.TrimSpace(abstract),
+	}}
+	if doc.Title == "" {
+		fmt.Fprintln(outputView, "\033[31mUsage: :add Title | abstract\033[0m")
+		return nil
+	}
+
+	if err := adder.AddDocument(ctx, doc); err != nil {
+		fmt.Fprintf(outputView, "\033[31mFailed to add document: %v\033[0m\n", err)
+		return nil
+	}
+	fmt.Fprintf(outputView, "\033[33mAdded %q\033[0m\n", doc.Title)
+	return nil
+}
+
+func (c *CUI) nextPage(g *gocui.Gui, v *gocui.View) error {
+	if c.query == "" || c.offset+c.maxResults >= c.total {
+		return nil
+	}
+	c.offset += c.maxResults
+	return c.showPage(g, c.ctx)
+}
+
+func (c *CUI) prevPage(g *gocui.Gui, v *gocui.View) error {
+	if c.query == "" || c.offset == 0 {
+		return nil
+	}
+	c.offset = max(c.offset-c.maxResults, 0)
+	return c.showPage(g, c.ctx)
+}
 
-	results, elapsedTime, totalResultsCount, err := c.performSearch(searchQuery, ctx)
+// showPage starts the search for the current page on its own goroutine, so
+// the main loop keeps scrolling and editing while it runs. A newer page
+// cancels the search in flight, and only the newest one is rendered.
+func (c *CUI) showPage(g *gocui.Gui, ctx context.Context) error {
+	c.cancelSearch()
+	ctx, cancel := context.WithCancel(ctx)
+	c.cancel = cancel
+	c.searchSeq++
+	seq := c.searchSeq
 
+	outputView, err := g.View("output")
+	if err != nil {
+		cancel()
+		return err
+	}
+	outputView.Clear()
+	outputView.SetOrigin(0, 0)
+	fmt.Fprintln(outputView, "\033[33mSearching...\033[0m")
+	_, _ = g.SetCurrentView("input")
+
+	query, offset, limit := c.query, c.offset, c.maxResults
+	go func() {
+		result, searchErr := c.performSearch(query, offset, limit, ctx)
+		g.Update(func(g *gocui.Gui) error {
+			if seq != c.searchSeq {
+				return nil
+			}
+			cancel()
+			c.cancel = nil
+			if searchErr != nil {
+				result = &models.SearchResult{}
+			}
+			c.total = result.TotalResultsCount
+			return c.renderPage(g, result, offset, limit, searchErr)
+		})
+	}()
+
+	return nil
+}
+
+// cancelSearch cancels the search in flight, if any. Like every CUI field,
+// it is only touched from the main loop.
+func (c *CUI) cancelSearch() {
+	if c.cancel != nil {
+		c.cancel()
+		c.cancel = nil
+	}
+}
+
+func (c *CUI) renderPage(g *gocui.Gui, result *models.SearchResult, offset, limit int, searchErr error) error {
 	timeView, err := g.View("time")
 	if err != nil {
 		return err
@@ -195,8 +354,8 @@ func (c *CUI) search(g *gocui.Gui, v *gocui.View, ctx context.Context, searchQue
 
 	fmt.Fprintln(timeView, "\033[33mSearch Time:\033[0m")
 
-	for phase, duration := range elapsedTime {
-		fmt.Fprintf(timeView, "\033[32m%s: %s\033[0m\n", phase, duration)
+	for phase, duration := range result.Timings {
+		fmt.Fprintf(timeView, "\033[32m%s: %s\033[0m\n", phase, utils.FormatDuration(duration))
 	}
 
 	outputView, err := g.View("output")
@@ -205,53 +364,191 @@ func (c *CUI) search(g *gocui.Gui, v *gocui.View, ctx context.Context, searchQue
 	}
 	outputView.Clear()
 
-	fmt.Fprintf(outputView, "\033[33mTotal Results Count: %d\033[0m\n", totalResultsCount)
+	if searchErr != nil {
+		fmt.Fprintf(outputView, "\033[31m%v\033[0m\n", searchErr)
+		return nil
+	}
+
+	fmt.Fprintf(outputView, "\033[33mTotal Results Count: %d\033[0m\n", result.TotalResultsCount)
+	if result.TotalResultsCount > 0 && limit > 0 {
+		fmt.Fprintf(outputView, "\033[33mShowing %d-%d (PgUp/PgDn to page)\033[0m\n",
+			offset+1, min(offset+limit, result.TotalResultsCount))
+	}
 
-	for i, result := range results {
-		if i >= c.maxResults {
+	for i, data := range result.ResultData {
+		if i >= limit {
 			break
 		}
 
-		highlightedHeader := fmt.Sprintf("\033[32mDoc ID: %s | Unique Matches: %d | Total Matches: %d\033[0m\n",
-			result.ID, result.UniqueMatches, result.TotalMatches)
-		fmt.Fprintf(outputView, "%s\n", highlightedHeader)
+		writeResult(outputView, data)
+	}
+	writeSuggestion(outputView, result.Suggestion)
+
+	return nil
+}
+
+// writeSuggestion shows the corrected query the engine proposed, if any.
+func writeSuggestion(w io.Writer, suggestion string) {
+	if suggestion == "" {
+		return
+	}
+	fmt.Fprintf(w, "\n\033[33mDid you mean:\033[0m %s?\n", suggestion)
+}
 
-		highlightQueryInResult(&result.Document, searchQuery)
-		fmt.Fprintf(outputView, "%s\n%s\n\n", result.Document.URL, result.Document.Abstract)
+// refreshStats fills the stats view. Analyze walks the whole index, which
+// takes a while on a large dump, so it runs off the main loop like a search.
+func (c *CUI) refreshStats(g *gocui.Gui) error {
+	statsView, err := g.View("stats")
+	if err != nil {
+		return err
 	}
+	statsView.Clear()
 
-	_, _ = g.SetCurrentView("input")
+	reporter, ok := c.ftsService.(search.StatsReporter)
+	if !ok {
+		fmt.Fprintln(statsView, "\033[33mNot available for this engine\033[0m")
+		return nil
+	}
+	fmt.Fprintln(statsView, "\033[33mAnalyzing...\033[0m")
+
+	go func() {
+		stats := reporter.IndexStats()
+		g.Update(func(g *gocui.Gui) error {
+			statsView, err := g.View("stats")
+			if err != nil {
+				return err
+			}
+			statsView.Clear()
+			writeStats(statsView, stats)
+			return nil
+		})
+	}()
 	return nil
 }
 
-func highlightQueryInResult(document *models.Document, query string) {
-	words := strings.Fields(query)
-	for _, word := range words {
-		re := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(word) + `\b`)
-		document.Abstract = re.ReplaceAllString(document.Abstract, "\033[31m$0\033[0m")
+func writeStats(w io.Writer, stats models.IndexStats) {
+	fmt.Fprintf(w, "\033[32mDocuments: %d\033[0m\n", stats.Documents)
+	if stats.AvgDocLength > 0 {
+		fmt.Fprintf(w, "\033[32mAvg length: %.1f tokens\033[0m\n", stats.AvgDocLength)
+	}
+	if stats.CacheHits+stats.CacheMisses > 0 {
+		fmt.Fprintf(w, "\033[32mResult cache: %d hits, %d misses, %d cached\033[0m\n", stats.CacheHits, stats.CacheMisses, stats.CacheEntries)
+	}
+	if !stats.Analyzed {
+		fmt.Fprintln(w, "\033[33mIndex structure not reported\033[0m")
+		return
+	}
+	fmt.Fprintf(w, "\033[32mIndex keys: %d\033[0m\n", stats.Keys)
+	fmt.Fprintf(w, "\033[32mPostings: %d\033[0m\n", stats.Postings)
+	fmt.Fprintf(w, "\033[32mNodes: %d\033[0m\n", stats.Nodes)
+	fmt.Fprintf(w, "\033[32mMax depth: %d\033[0m\n", stats.MaxDepth)
+}
+
+// shortIDLen is how much of a document ID the result header shows; the full
+// MD5 hex digest adds nothing for a reader.
+const shortIDLen = 8
+
+// writeResult renders one result: the title, the scores, the URL and the
+// snippet, or the abstract when there is none. A result whose document could
+// not be loaded says so instead of rendering an empty document.
+func writeResult(w io.Writer, result models.ResultData) {
+	if result.Document == (models.Document{}) {
+		fmt.Fprintln(w, "\033[31m[document unavailable]\033[0m")
+	} else {
+		title := result.Document.Title
+		if title == "" {
+			title = "[untitled]"
+		}
+		fmt.Fprintf(w, "%s%s\033[0m\n", titleStyle, highlightSpans(title, result.TitleSpans, titleStyle))
+	}
+
+	fmt.Fprintf(w, "\033[32mID: %s | Unique Matches: %d | Total Matches: %d | Score: %.3f\033[0m\n",
+		shortID(result.ID), result.UniqueMatches, result.TotalMatches, result.Score)
+
+	if len(result.MatchedTerms) > 0 {
+		fmt.Fprintf(w, "\033[33mMatched: %s\033[0m\n", strings.Join(result.MatchedTerms, ", "))
+	}
+	for _, term := range result.FuzzyTerms {
+		fmt.Fprintf(w, "\033[33mFuzzy: %s -> %s (distance %d)\033[0m\n", term.Token, term.Term, term.Distance)
+	}
+
+	if url := result.Document.URL; url != "" {
+		fmt.Fprintf(w, "\033[4;34m%s\033[0m\n", url)
+	}
+
+	text := result.Document.Abstract
+	if result.Snippet != "" {
+		text = highlightSpans(result.Snippet, result.MatchSpans, "")
+	}
+	fmt.Fprintf(w, "%s\n\n", text)
+}
+
+func shortID(id string) string {
+	if len(id) <= shortIDLen {
+		return id
+	}
+	return id[:shortIDLen]
+}
+
+// titleStyle is the escape sequence result titles are written in.
+const titleStyle = "\033[1;36m"
+
+// highlightSpans colors the matched byte ranges of text red and switches back
+// to style after each of them.
+func highlightSpans(text string, spans []models.MatchSpan, style string) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		if span.Start < last || span.End > len(text) {
+			continue
+		}
+		b.WriteString(text[last:span.Start])
+		b.WriteString("\033[31m" + text[span.Start:span.End] + "\033[0m" + style)
+		last = span.End
 	}
+	b.WriteString(text[last:])
+	return b.String()
 }
 
-func (c *CUI) performSearch(query string, ctx context.Context) ([]models.ResultData, map[string]string, int, error) {
+// performSearch runs on the search goroutine, so it gets the page it fetches
+// as arguments rather than reading the CUI fields.
+func (c *CUI) performSearch(query string, offset, limit int, ctx context.Context) (*models.SearchResult, error) {
 	searchResult, err := c.ftsService.SearchDocuments(
 		ctx,
 		query,
-		c.maxResults,
+		offset,
+		limit,
 	)
 	if err != nil {
-		return nil, nil, 0, fmt.Errorf("failed to search documents: %v", err)
+		return nil, fmt.Errorf("failed to search documents: %v", err)
+	}
+
+	if fuzzyEngine, ok := c.ftsService.(search.FuzzySearcher); ok && searchResult.TotalResultsCount == 0 {
+		// SearchFuzzy has no offset, so fetch up to the end of the page and cut.
+		fuzzyResult, fuzzyErr := fuzzyEngine.SearchFuzzy(ctx, query, fuzzyFallbackDistance, offset+limit)
+		switch {
+		case errors.Is(fuzzyErr, pkgfts.ErrFuzzyUnsupported):
+			c.log.Debug("Fuzzy fallback unsupported by index", "error", sl.Err(fuzzyErr))
+		case fuzzyErr != nil:
+			// An empty page would hide that the index could not be read.
+			return nil, fmt.Errorf("fuzzy fallback search failed: %w", fuzzyErr)
+		default:
+			fuzzyResult.ResultData = fuzzyResult.ResultData[min(offset, len(fuzzyResult.ResultData)):]
+			fuzzyResult.Suggestion = searchResult.Suggestion
+			searchResult = fuzzyResult
+		}
 	}
 
 	for i, result := range searchResult.ResultData {
-		if doc, ok := c.documents[result.ID]; ok {
+		if result.Document.ID != "" {
+			continue
+		}
+		if doc, ok := c.documents.GetDocument(result.ID); ok {
 			searchResult.ResultData[i].Document = doc
 		}
 	}
 
-	if err != nil {
-		return nil, nil, 0, err
-	}
-	return searchResult.ResultData, searchResult.Timings, searchResult.TotalResultsCount, nil
+	return searchResult, nil
 }
 
 func quit(g *gocui.Gui, v *gocui.View) error {
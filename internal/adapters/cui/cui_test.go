@@ -0,0 +1,117 @@
+package cui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dariasmyr/fts-engine/internal/domain/models"
+)
+
+func TestWriteResultShowsTitle(t *testing.T) {
+	var b strings.Builder
+	writeResult(&b, models.ResultData{
+		ID: "0123456789abcdef0123456789abcdef",
+		Document: models.Document{
+			ID:           "0123456789abcdef0123456789abcdef",
+			DocumentBase: models.DocumentBase{Title: "Grand Hotel", URL: "https://example.org/grand-hotel", Abstract: "A hotel."},
+		},
+	})
+
+	out := b.String()
+	for _, want := range []string{"Grand Hotel", "ID: 01234567 |", "https://example.org/grand-hotel", "A hotel."} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output %q does not contain %q", out, want)
+		}
+	}
+	if strings.Contains(out, "0123456789abcdef0123456789abcdef") {
+		t.Fatalf("output %q shows the full ID", out)
+	}
+}
+
+func TestWriteResultMissingDocument(t *testing.T) {
+	var b strings.Builder
+	writeResult(&b, models.ResultData{ID: "doc-1"})
+
+	if out := b.String(); !strings.Contains(out, "[document unavailable]") || !strings.Contains(out, "ID: doc-1 |") {
+		t.Fatalf("output = %q, want the unavailable marker and the ID", out)
+	}
+}
+
+func TestParseMaxResults(t *testing.T) {
+	tests := []struct {
+		buf     string
+		want    int
+		wantErr bool
+	}{
+		{buf: "10\n", want: 10},
+		{buf: " 25 \n\n", want: 25},
+		{buf: "0", want: 1},
+		{buf: "-3", want: 1},
+		{buf: "ten", wantErr: true},
+		{buf: "\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseMaxResults(tt.buf)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Fatalf("parseMaxResults(%q) = %d, %v; want %d, error %v", tt.buf, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestWriteStats(t *testing.T) {
+	var b strings.Builder
+	writeStats(&b, models.IndexStats{Documents: 3, Analyzed: true, Keys: 7, Nodes: 12, Postings: 9})
+	for _, want := range []string{"Documents: 3", "Index keys: 7", "Nodes: 12", "Postings: 9"} {
+		if !strings.Contains(b.String(), want) {
+			t.Fatalf("output %q does not contain %q", b.String(), want)
+		}
+	}
+
+	b.Reset()
+	writeStats(&b, models.IndexStats{Documents: 3})
+	if out := b.String(); !strings.Contains(out, "not reported") || strings.Contains(out, "Nodes") || strings.Contains(out, "cache") {
+		t.Fatalf("output = %q, want only the document count", out)
+	}
+
+	b.Reset()
+	writeStats(&b, models.IndexStats{Documents: 3, CacheEntries: 1, CacheHits: 4, CacheMisses: 2})
+	if out := b.String(); !strings.Contains(out, "Result cache: 4 hits, 2 misses, 1 cached") {
+		t.Fatalf("output = %q, want the result cache counters", out)
+	}
+}
+
+func TestWriteResultHighlightsTitle(t *testing.T) {
+	var b strings.Builder
+	writeResult(&b, models.ResultData{
+		ID:         "doc-1",
+		Document:   models.Document{ID: "doc-1", DocumentBase: models.DocumentBase{Title: "Grand Hotels"}},
+		TitleSpans: []models.MatchSpan{{Start: 6, End: 12}},
+	})
+
+	if want := "Grand \033[31mHotels\033[0m" + titleStyle; !strings.Contains(b.String(), want) {
+		t.Fatalf("output %q does not contain %q", b.String(), want)
+	}
+}
+
+func TestWriteResultShowsMatchedTerms(t *testing.T) {
+	var b strings.Builder
+	writeResult(&b, models.ResultData{ID: "doc-1", MatchedTerms: []string{"hotel", "barg"}})
+
+	if want := "Matched: hotel, barg"; !strings.Contains(b.String(), want) {
+		t.Fatalf("output %q does not contain %q", b.String(), want)
+	}
+}
+
+func TestWriteSuggestion(t *testing.T) {
+	var b strings.Builder
+	writeSuggestion(&b, "")
+	if b.Len() != 0 {
+		t.Fatalf("output = %q, want nothing without a suggestion", b.String())
+	}
+
+	writeSuggestion(&b, "hotel")
+	if want := "Did you mean:\033[0m hotel?"; !strings.Contains(b.String(), want) {
+		t.Fatalf("output %q does not contain %q", b.String(), want)
+	}
+}
@@ -0,0 +1,4 @@
+// Package ftspb holds the generated messages and stubs of the gRPC search API.
+package ftspb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative search.proto
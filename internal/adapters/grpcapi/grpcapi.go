@@ -0,0 +1,159 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dariasmyr/fts-engine/internal/adapters/grpcapi/ftspb"
+	"github.com/dariasmyr/fts-engine/internal/domain/models"
+	"github.com/dariasmyr/fts-engine/internal/lib/logger/sl"
+	"github.com/dariasmyr/fts-engine/internal/services/search"
+	"log/slog"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// TruncatedTrailer is the trailer key set to "true" when a search ran out of
+// time and answered with only the results it found by then.
+const TruncatedTrailer = "fts-truncated"
+
+// Server implements ftspb.SearchServiceServer. Results carry the stored
+// document when the engine did not attach one.
+type Server struct {
+	ftspb.UnimplementedSearchServiceServer
+
+	log        *slog.Logger
+	engine     search.Searcher
+	documents  search.DocumentStore
+	maxResults int
+}
+
+func New(log *slog.Logger, engine search.Searcher, documents search.DocumentStore, maxResults int) *Server {
+	return &Server{
+		log:        log,
+		engine:     engine,
+		documents:  documents,
+		maxResults: maxResults,
+	}
+}
+
+// Run serves on addr until ctx is done and then stops gracefully.
+func (s *Server) Run(ctx context.Context, addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("grpcapi: listen: %w", err)
+	}
+
+	srv := grpc.NewServer()
+	ftspb.RegisterSearchServiceServer(srv, s)
+
+	errCh := make(chan error, 1)
+	go func() {
+		s.log.Info("gRPC API listening", "addr", lis.Addr().String())
+		errCh <- srv.Serve(lis)
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("grpcapi: serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	srv.GracefulStop()
+	return nil
+}
+
+func (s *Server) Search(ctx context.Context, req *ftspb.SearchRequest) (*ftspb.SearchResponse, error) {
+	result, err := s.search(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ftspb.SearchResponse{
+		Results:           make([]*ftspb.ResultData, 0, len(result.ResultData)),
+		TotalResultsCount: int32(result.TotalResultsCount),
+		Timings:           make(map[string]*durationpb.Duration, len(result.Timings)),
+	}
+	for phase, d := range result.Timings {
+		resp.Timings[phase] = durationpb.New(d)
+	}
+	for _, data := range result.ResultData {
+		resp.Results = append(resp.Results, s.toProto(data))
+	}
+	return resp, nil
+}
+
+func (s *Server) StreamResults(req *ftspb.SearchRequest, stream grpc.ServerStreamingServer[ftspb.ResultData]) error {
+	result, err := s.search(stream.Context(), req)
+	if err != nil {
+		return err
+	}
+
+	for _, data := range result.ResultData {
+		if err := stream.Send(s.toProto(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) search(ctx context.Context, req *ftspb.SearchRequest) (*models.SearchResult, error) {
+	if req.GetQuery() == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+	if req.GetMaxResults() < 0 || req.GetOffset() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "max_results and offset must be non-negative")
+	}
+
+	limit := search.ClampLimit(int(req.GetMaxResults()), s.maxResults)
+	result, err := s.engine.SearchDocuments(ctx, req.GetQuery(), int(req.GetOffset()), limit)
+	if err != nil {
+		switch {
+		case search.IsQueryError(err):
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		case errors.Is(err, context.Canceled):
+			return nil, status.Error(codes.Canceled, err.Error())
+		case errors.Is(err, context.DeadlineExceeded):
+			return nil, status.Error(codes.DeadlineExceeded, err.Error())
+		}
+		s.log.Error("Search failed", "query", req.GetQuery(), "error", sl.Err(err))
+		return nil, status.Error(codes.Internal, "search failed")
+	}
+	if result.Truncated {
+		if err := grpc.SetTrailer(ctx, metadata.Pairs(TruncatedTrailer, "true")); err != nil {
+			s.log.Warn("Failed to mark truncated results", "error", sl.Err(err))
+		}
+	}
+	return result, nil
+}
+
+func (s *Server) toProto(data models.ResultData) *ftspb.ResultData {
+	doc := data.Document
+	if doc.ID == "" && s.documents != nil {
+		doc, _ = s.documents.GetDocument(data.ID)
+	}
+
+	out := &ftspb.ResultData{
+		Id:            data.ID,
+		UniqueMatches: int32(data.UniqueMatches),
+		TotalMatches:  int32(data.TotalMatches),
+		Score:         data.Score,
+		Snippet:       data.Snippet,
+		Document: &ftspb.Document{
+			Id:       doc.ID,
+			Title:    doc.Title,
+			Url:      doc.URL,
+			Abstract: doc.Abstract,
+			Extract:  doc.Extract,
+		},
+	}
+	for _, span := range data.MatchSpans {
+		out.MatchSpans = append(out.MatchSpans, &ftspb.MatchSpan{Start: int32(span.Start), End: int32(span.End)})
+	}
+	return out
+}
@@ -0,0 +1,182 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/dariasmyr/fts-engine/internal/adapters/grpcapi/ftspb"
+	"github.com/dariasmyr/fts-engine/internal/domain/models"
+	"github.com/dariasmyr/fts-engine/internal/services/search"
+	pkgfts "github.com/dariasmyr/fts-engine/pkg/fts"
+	"github.com/dariasmyr/fts-engine/pkg/query"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type stubEngine struct {
+	offset, limit int
+	err           error
+	truncated     bool
+}
+
+func (e *stubEngine) SearchDocuments(_ context.Context, _ string, offset, maxResults int) (*models.SearchResult, error) {
+	e.offset, e.limit = offset, maxResults
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &models.SearchResult{
+		ResultData: []models.ResultData{
+			{ID: "doc-1", UniqueMatches: 2, Score: 1.5, Snippet: "grand hotel", MatchSpans: []models.MatchSpan{{Start: 6, End: 11}}},
+			{ID: "doc-2", UniqueMatches: 1},
+		},
+		TotalResultsCount: 7,
+		Truncated:         e.truncated,
+	}, nil
+}
+
+func (e *stubEngine) IndexDocument(context.Context, string, string) error {
+	return nil
+}
+
+type stubStore map[string]models.Document
+
+func (s stubStore) GetDocument(id string) (models.Document, bool) {
+	doc, ok := s[id]
+	return doc, ok
+}
+
+func dial(t *testing.T, engine search.Searcher) ftspb.SearchServiceClient {
+	t.Helper()
+
+	docs := stubStore{
+		"doc-1": {ID: "doc-1", DocumentBase: models.DocumentBase{Title: "Grand Hotel"}},
+		"doc-2": {ID: "doc-2", DocumentBase: models.DocumentBase{Title: "River Barge"}},
+	}
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	ftspb.RegisterSearchServiceServer(srv, New(slog.New(slog.NewTextHandler(io.Discard, nil)), engine, docs, 10))
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return ftspb.NewSearchServiceClient(conn)
+}
+
+func TestSearch(t *testing.T) {
+	engine := &stubEngine{}
+	client := dial(t, engine)
+
+	resp, err := client.Search(context.Background(), &ftspb.SearchRequest{Query: "hotel", MaxResults: 2, Offset: 4})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if engine.offset != 4 || engine.limit != 2 {
+		t.Fatalf("engine called with offset %d limit %d", engine.offset, engine.limit)
+	}
+	if resp.GetTotalResultsCount() != 7 || len(resp.GetResults()) != 2 {
+		t.Fatalf("response = %v", resp)
+	}
+
+	first := resp.GetResults()[0]
+	if first.GetId() != "doc-1" || first.GetScore() != 1.5 || first.GetDocument().GetTitle() != "Grand Hotel" {
+		t.Fatalf("first result = %v", first)
+	}
+	if spans := first.GetMatchSpans(); len(spans) != 1 || spans[0].GetStart() != 6 || spans[0].GetEnd() != 11 {
+		t.Fatalf("match spans = %v", spans)
+	}
+}
+
+func TestSearchTruncatedTrailer(t *testing.T) {
+	for _, truncated := range []bool{false, true} {
+		client := dial(t, &stubEngine{truncated: truncated})
+
+		var trailer metadata.MD
+		if _, err := client.Search(context.Background(), &ftspb.SearchRequest{Query: "hotel"}, grpc.Trailer(&trailer)); err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+		if got := len(trailer.Get(TruncatedTrailer)) > 0; got != truncated {
+			t.Fatalf("truncated %v: trailer = %v", truncated, trailer)
+		}
+	}
+}
+
+func TestSearchDefaultLimit(t *testing.T) {
+	engine := &stubEngine{}
+	client := dial(t, engine)
+
+	for _, maxResults := range []int32{0, 1000000} {
+		if _, err := client.Search(context.Background(), &ftspb.SearchRequest{Query: "hotel", MaxResults: maxResults}); err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+		if engine.limit != 10 {
+			t.Fatalf("max_results %d: limit = %d, want 10", maxResults, engine.limit)
+		}
+	}
+}
+
+func TestStreamResults(t *testing.T) {
+	client := dial(t, &stubEngine{})
+
+	stream, err := client.StreamResults(context.Background(), &ftspb.SearchRequest{Query: "hotel"})
+	if err != nil {
+		t.Fatalf("StreamResults() error = %v", err)
+	}
+
+	var titles []string
+	for {
+		data, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		titles = append(titles, data.GetDocument().GetTitle())
+	}
+	if len(titles) != 2 || titles[1] != "River Barge" {
+		t.Fatalf("titles = %q", titles)
+	}
+}
+
+func TestSearchErrorCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *ftspb.SearchRequest
+		err  error
+		want codes.Code
+	}{
+		{name: "empty query", req: &ftspb.SearchRequest{}, want: codes.InvalidArgument},
+		{name: "negative offset", req: &ftspb.SearchRequest{Query: "hotel", Offset: -1}, want: codes.InvalidArgument},
+		{name: "syntax", req: &ftspb.SearchRequest{Query: "hotel AND"}, err: fmt.Errorf("fts: search: %w", query.ErrSyntax), want: codes.InvalidArgument},
+		{name: "unknown field", req: &ftspb.SearchRequest{Query: "url:wikipedia"}, err: fmt.Errorf("fts: search: %w", pkgfts.ErrUnknownField), want: codes.InvalidArgument},
+		{name: "engine", req: &ftspb.SearchRequest{Query: "hotel"}, err: errors.New("index broken"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := dial(t, &stubEngine{err: tt.err})
+			_, err := client.Search(context.Background(), tt.req)
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("code = %v, want %v (err %v)", got, tt.want, err)
+			}
+		})
+	}
+}
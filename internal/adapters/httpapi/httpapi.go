@@ -0,0 +1,252 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dariasmyr/fts-engine/internal/lib/logger/sl"
+	"github.com/dariasmyr/fts-engine/internal/services/search"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const shutdownTimeout = 10 * time.Second
+
+// statusClientClosedRequest answers a search whose client went away, as
+// nginx does, since the client never reads it and it is not a server error.
+const statusClientClosedRequest = 499
+
+// Server exposes the search engine as a JSON API:
+//
+//	GET /search?q=...&limit=...&offset=...
+//	GET /doc/{id}
+//	GET /stats?refresh=true
+//	GET /analyze?text=...
+//	POST /reindex
+//	GET /healthz
+type Server struct {
+	log        *slog.Logger
+	engine     search.Searcher
+	documents  search.DocumentStore
+	maxResults int
+	draining   atomic.Bool
+	srv        *http.Server
+}
+
+func New(log *slog.Logger, addr string, engine search.Searcher, documents search.DocumentStore, maxResults int) *Server {
+	s := &Server{
+		log:        log,
+		engine:     engine,
+		documents:  documents,
+		maxResults: maxResults,
+	}
+	s.srv = &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /search", s.search)
+	mux.HandleFunc("GET /doc/{id}", s.document)
+	mux.HandleFunc("GET /stats", s.stats)
+	mux.HandleFunc("GET /analyze", s.analyze)
+	mux.HandleFunc("POST /reindex", s.reindex)
+	mux.HandleFunc("GET /healthz", s.health)
+	return mux
+}
+
+// Run serves until ctx is done and then shuts down, letting in-flight
+// requests finish.
+func (s *Server) Run(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() {
+		s.log.Info("HTTP API listening", "addr", s.srv.Addr)
+		errCh <- s.srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("httpapi: serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := s.srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("httpapi: shutdown: %w", err)
+	}
+	return nil
+}
+
+// Drain makes the health endpoint report unavailable, so load balancers stop
+// routing new requests before Run shuts down.
+func (s *Server) Drain() {
+	s.draining.Store(true)
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	q := params.Get("q")
+	if q == "" {
+		writeError(w, http.StatusBadRequest, "missing query parameter q")
+		return
+	}
+
+	limit, err := intParam(params.Get("limit"), 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "limit: "+err.Error())
+		return
+	}
+	limit = search.ClampLimit(limit, s.maxResults)
+	offset, err := intParam(params.Get("offset"), 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "offset: "+err.Error())
+		return
+	}
+
+	result, err := s.engine.SearchDocuments(r.Context(), q, offset, limit)
+	if err != nil {
+		if search.IsQueryError(err) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			w.WriteHeader(statusClientClosedRequest)
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			writeError(w, http.StatusGatewayTimeout, "search timed out")
+			return
+		}
+		s.log.Error("Search failed", "query", q, "error", sl.Err(err))
+		writeError(w, http.StatusInternalServerError, "search failed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) document(w http.ResponseWriter, r *http.Request) {
+	doc, ok := s.documents.GetDocument(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "document not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, doc)
+}
+
+// stats reports the engine's search.StatsReporter figures. refresh=true asks
+// a search.StatsRefresher to walk the index again instead of reusing its
+// last analysis.
+func (s *Server) stats(w http.ResponseWriter, r *http.Request) {
+	reporter, ok := s.engine.(search.StatsReporter)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "engine does not report stats")
+		return
+	}
+
+	refresh := false
+	if value := r.URL.Query().Get("refresh"); value != "" {
+		var err error
+		if refresh, err = strconv.ParseBool(value); err != nil {
+			writeError(w, http.StatusBadRequest, "refresh: must be true or false")
+			return
+		}
+	}
+
+	if refresher, ok := reporter.(search.StatsRefresher); ok && refresh {
+		writeJSON(w, http.StatusOK, refresher.RefreshIndexStats())
+		return
+	}
+	writeJSON(w, http.StatusOK, reporter.IndexStats())
+}
+
+// analyze reports the tokens and keys the engine derives from text, to debug
+// why a query does or does not match.
+func (s *Server) analyze(w http.ResponseWriter, r *http.Request) {
+	analyzer, ok := s.engine.(search.TextAnalyzer)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "engine does not analyze text")
+		return
+	}
+
+	params := r.URL.Query()
+	if !params.Has("text") {
+		writeError(w, http.StatusBadRequest, "missing query parameter text")
+		return
+	}
+
+	analysis, err := analyzer.AnalyzeText(params.Get("text"))
+	if err != nil {
+		s.log.Error("Analyze failed", "error", sl.Err(err))
+		writeError(w, http.StatusInternalServerError, "analyze failed")
+		return
+	}
+	writeJSON(w, http.StatusOK, analysis)
+}
+
+// reindex rebuilds the index from the stored documents and answers once the
+// new index serves searches. A client that goes away cancels the rebuild and
+// the old index stays.
+func (s *Server) reindex(w http.ResponseWriter, r *http.Request) {
+	rebuilder, ok := s.engine.(search.Rebuilder)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "engine does not support reindexing")
+		return
+	}
+
+	start := time.Now()
+	n, err := rebuilder.Rebuild(r.Context())
+	if err != nil {
+		if errors.Is(err, search.ErrRebuildRunning) {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		s.log.Error("Reindex failed", "error", sl.Err(err))
+		writeError(w, http.StatusInternalServerError, "reindex failed")
+		return
+	}
+
+	s.log.Info("Reindexed", "documents", n, "duration", time.Since(start))
+	writeJSON(w, http.StatusOK, map[string]int{"documents": n})
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	if s.draining.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "draining"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func intParam(value string, def int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("must be a non-negative integer")
+	}
+	return n, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,281 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dariasmyr/fts-engine/internal/domain/models"
+	"github.com/dariasmyr/fts-engine/internal/services/search"
+	"github.com/dariasmyr/fts-engine/pkg/query"
+)
+
+type stubEngine struct {
+	query         string
+	offset, limit int
+	err           error
+}
+
+func (e *stubEngine) SearchDocuments(_ context.Context, q string, offset, maxResults int) (*models.SearchResult, error) {
+	e.query, e.offset, e.limit = q, offset, maxResults
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &models.SearchResult{
+		ResultData:        []models.ResultData{{ID: "doc-1", UniqueMatches: 1, TotalMatches: 2}},
+		TotalResultsCount: 1,
+	}, nil
+}
+
+func (e *stubEngine) IndexDocument(context.Context, string, string) error {
+	return nil
+}
+
+type stubStore map[string]models.Document
+
+func (s stubStore) GetDocument(id string) (models.Document, bool) {
+	doc, ok := s[id]
+	return doc, ok
+}
+
+func newTestServer(engine search.Searcher) *Server {
+	docs := stubStore{"doc-1": {ID: "doc-1", DocumentBase: models.DocumentBase{Title: "Hotel"}}}
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), "", engine, docs, 10)
+}
+
+func get(t *testing.T, s *Server, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func TestSearch(t *testing.T) {
+	engine := &stubEngine{}
+	rec := get(t, newTestServer(engine), "/search?q=hotel+barge&limit=5&offset=20")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if engine.query != "hotel barge" || engine.offset != 20 || engine.limit != 5 {
+		t.Fatalf("engine called with %q offset %d limit %d", engine.query, engine.offset, engine.limit)
+	}
+
+	var got models.SearchResult
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.TotalResultsCount != 1 || got.ResultData[0].ID != "doc-1" {
+		t.Fatalf("result = %+v", got)
+	}
+}
+
+func TestSearchDefaultLimit(t *testing.T) {
+	for _, target := range []string{"/search?q=hotel", "/search?q=hotel&limit=0", "/search?q=hotel&limit=1000000"} {
+		engine := &stubEngine{}
+		get(t, newTestServer(engine), target)
+
+		if engine.limit != 10 || engine.offset != 0 {
+			t.Fatalf("%s: engine called with offset %d limit %d, want 0 and 10", target, engine.offset, engine.limit)
+		}
+	}
+}
+
+func TestSearchBadRequest(t *testing.T) {
+	for _, target := range []string{"/search", "/search?q=hotel&limit=-1", "/search?q=hotel&offset=x"} {
+		if rec := get(t, newTestServer(&stubEngine{}), target); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status = %d, want 400", target, rec.Code)
+		}
+	}
+
+	engine := &stubEngine{err: fmt.Errorf("fts: search: %w", query.ErrSyntax)}
+	if rec := get(t, newTestServer(engine), "/search?q=hotel+AND"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("syntax error: status = %d, want 400", rec.Code)
+	}
+
+	engine = &stubEngine{err: fmt.Errorf("fts: search: index search: %w", context.DeadlineExceeded)}
+	if rec := get(t, newTestServer(engine), "/search?q=hotel"); rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("timeout: status = %d, want 504", rec.Code)
+	}
+
+	engine = &stubEngine{err: fmt.Errorf("fts: search: index search: %w", context.Canceled)}
+	if rec := get(t, newTestServer(engine), "/search?q=hotel"); rec.Code != statusClientClosedRequest {
+		t.Fatalf("client gone: status = %d, want %d", rec.Code, statusClientClosedRequest)
+	}
+
+	engine = &stubEngine{err: fmt.Errorf("index broken")}
+	if rec := get(t, newTestServer(engine), "/search?q=hotel"); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("engine error: status = %d, want 500", rec.Code)
+	}
+}
+
+func TestDocument(t *testing.T) {
+	s := newTestServer(&stubEngine{})
+
+	rec := get(t, s, "/doc/doc-1")
+	var doc models.Document
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil || doc.Title != "Hotel" {
+		t.Fatalf("status %d, doc = %+v, err = %v", rec.Code, doc, err)
+	}
+
+	if rec := get(t, s, "/doc/missing"); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing doc: status = %d, want 404", rec.Code)
+	}
+}
+
+type statsEngine struct {
+	stubEngine
+}
+
+func (statsEngine) IndexStats() models.IndexStats {
+	return models.IndexStats{Documents: 3, Analyzed: true, Keys: 7}
+}
+
+type refreshEngine struct {
+	statsEngine
+	refreshed int
+}
+
+func (e *refreshEngine) RefreshIndexStats() models.IndexStats {
+	e.refreshed++
+	return models.IndexStats{Documents: 3, Analyzed: true, Keys: 8, AnalyzedAt: time.Unix(1, 0)}
+}
+
+func TestStats(t *testing.T) {
+	rec := get(t, newTestServer(&statsEngine{}), "/stats")
+	var stats models.IndexStats
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil || stats.Documents != 3 || stats.Keys != 7 {
+		t.Fatalf("status %d, stats = %+v, err = %v", rec.Code, stats, err)
+	}
+
+	if rec := get(t, newTestServer(&stubEngine{}), "/stats"); rec.Code != http.StatusNotImplemented {
+		t.Fatalf("engine without stats: status = %d, want 501", rec.Code)
+	}
+}
+
+func TestStatsKeys(t *testing.T) {
+	rec := get(t, newTestServer(&refreshEngine{}), "/stats?refresh=true")
+	var body map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("status %d, err = %v", rec.Code, err)
+	}
+	for _, key := range []string{"documents", "avg_doc_length", "analyzed", "analyzed_at", "keys", "nodes", "max_depth", "postings"} {
+		if _, ok := body[key]; !ok {
+			t.Errorf("body = %v, missing %q", body, key)
+		}
+	}
+	if _, ok := body["cache_hits"]; ok {
+		t.Errorf("body = %v, want no cache fields without a result cache", body)
+	}
+}
+
+func TestStatsRefresh(t *testing.T) {
+	engine := &refreshEngine{}
+	server := newTestServer(engine)
+
+	for path, want := range map[string]int{"/stats": 0, "/stats?refresh=false": 0, "/stats?refresh=1": 1} {
+		engine.refreshed = 0
+		if rec := get(t, server, path); rec.Code != http.StatusOK || engine.refreshed != want {
+			t.Errorf("%s: status %d, %d refreshes, want %d", path, rec.Code, engine.refreshed, want)
+		}
+	}
+	if rec := get(t, server, "/stats?refresh=soon"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad refresh: status = %d, want 400", rec.Code)
+	}
+}
+
+type analyzeEngine struct {
+	stubEngine
+	text string
+	err  error
+}
+
+func (e *analyzeEngine) AnalyzeText(text string) (models.TextAnalysis, error) {
+	e.text = text
+	return models.TextAnalysis{Tokens: []models.AnalyzedToken{{Token: "hotel", Keys: []string{"hot", "ote", "tel"}}}}, e.err
+}
+
+func TestAnalyze(t *testing.T) {
+	engine := &analyzeEngine{}
+	rec := get(t, newTestServer(engine), "/analyze?text=The+Hotels")
+	var analysis models.TextAnalysis
+	if err := json.NewDecoder(rec.Body).Decode(&analysis); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("status %d, err = %v", rec.Code, err)
+	}
+	if engine.text != "The Hotels" || len(analysis.Tokens) != 1 || analysis.Tokens[0].Token != "hotel" || len(analysis.Tokens[0].Keys) != 3 {
+		t.Fatalf("analyzed %q into %+v, want the hotel token and its trigrams", engine.text, analysis)
+	}
+
+	for path, want := range map[string]int{
+		"/analyze?text=": http.StatusOK,
+		"/analyze":       http.StatusBadRequest,
+	} {
+		if rec := get(t, newTestServer(&analyzeEngine{}), path); rec.Code != want {
+			t.Errorf("%s: status = %d, want %d", path, rec.Code, want)
+		}
+	}
+	if rec := get(t, newTestServer(&analyzeEngine{err: fmt.Errorf("keygen failed")}), "/analyze?text=x"); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("failing engine: status = %d, want 500", rec.Code)
+	}
+	if rec := get(t, newTestServer(&stubEngine{}), "/analyze?text=x"); rec.Code != http.StatusNotImplemented {
+		t.Fatalf("engine without analysis: status = %d, want 501", rec.Code)
+	}
+}
+
+type rebuildEngine struct {
+	stubEngine
+	err error
+}
+
+func (e *rebuildEngine) Rebuild(context.Context) (int, error) {
+	return 3, e.err
+}
+
+func TestReindex(t *testing.T) {
+	post := func(engine search.Searcher) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		newTestServer(engine).Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/reindex", nil))
+		return rec
+	}
+
+	rec := post(&rebuildEngine{})
+	var body map[string]int
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || rec.Code != http.StatusOK || body["documents"] != 3 {
+		t.Fatalf("status %d, body = %v, err = %v", rec.Code, body, err)
+	}
+
+	tests := []struct {
+		engine search.Searcher
+		want   int
+	}{
+		{engine: &rebuildEngine{err: search.ErrRebuildRunning}, want: http.StatusConflict},
+		{engine: &rebuildEngine{err: fmt.Errorf("rebuild: %w", context.Canceled)}, want: http.StatusInternalServerError},
+		{engine: &stubEngine{}, want: http.StatusNotImplemented},
+	}
+	for _, tt := range tests {
+		if rec := post(tt.engine); rec.Code != tt.want {
+			t.Fatalf("%T: status = %d, want %d", tt.engine, rec.Code, tt.want)
+		}
+	}
+	if rec := get(t, newTestServer(&rebuildEngine{}), "/reindex"); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET /reindex status = %d, want 405", rec.Code)
+	}
+}
+
+func TestHealthDrain(t *testing.T) {
+	s := newTestServer(&stubEngine{})
+
+	if rec := get(t, s, "/healthz"); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	s.Drain()
+	if rec := get(t, s, "/healthz"); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("draining status = %d, want 503", rec.Code)
+	}
+}
@@ -0,0 +1,59 @@
+package wiki
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dariasmyr/fts-engine/internal/domain/models"
+	"github.com/dariasmyr/fts-engine/internal/lib/logger/sl"
+)
+
+// EnrichDocuments fetches the full extract of every document received on in
+// with FetchAndProcessDocument, on workers goroutines, and sends the
+// documents on as they complete, so their order is not kept. The loader's
+// FetchOptions limit the rate of the fetches across all workers. A document
+// whose extract cannot be fetched is sent on unchanged and indexed by its
+// abstract alone. The returned channel is closed once in is closed and
+// drained, or ctx is done.
+func (l *Loader) EnrichDocuments(ctx context.Context, in <-chan models.Document, workers int) <-chan models.Document {
+	out := make(chan models.Document)
+
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var doc models.Document
+				select {
+				case d, ok := <-in:
+					if !ok {
+						return
+					}
+					doc = d
+				case <-ctx.Done():
+					return
+				}
+
+				enriched, err := l.FetchAndProcessDocument(ctx, doc)
+				if err != nil {
+					l.log.Debug("Extract unavailable, keeping the abstract", "id", doc.ID, "url", doc.URL, "error", sl.Err(err))
+					enriched = doc
+				}
+
+				select {
+				case out <- enriched:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
@@ -0,0 +1,86 @@
+package wiki
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dariasmyr/fts-engine/internal/domain/models"
+)
+
+// newExtractServer answers extract queries with "Extract of <title>.", and
+// with a server error for titles starting with "Broken".
+func newExtractServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/w/api.php" {
+			http.NotFound(w, r)
+			return
+		}
+		title := r.URL.Query().Get("titles")
+		if strings.HasPrefix(title, "Broken") {
+			http.Error(w, "unavailable", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(w, `{"batchcomplete":"","query":{"pages":{"1":{"pageid":1,"ns":0,"title":%q,"extract":"Extract  of\n%s."}}}}`, title, title)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func sendDocuments(docs ...models.Document) <-chan models.Document {
+	in := make(chan models.Document, len(docs))
+	for _, doc := range docs {
+		in <- doc
+	}
+	close(in)
+	return in
+}
+
+func TestEnrichDocuments(t *testing.T) {
+	server := newExtractServer(t)
+	l := newTestLoader()
+	l.SetFetchOptions(FetchOptions{Timeout: time.Second})
+
+	in := sendDocuments(
+		models.Document{ID: "doc-1", DocumentBase: models.DocumentBase{URL: server.URL + "/wiki/Grand_Hotel", Abstract: "A hotel."}},
+		models.Document{ID: "doc-2", DocumentBase: models.DocumentBase{URL: server.URL + "/wiki/Broken_Page", Abstract: "A page."}},
+	)
+
+	got := make(map[string]models.Document)
+	for doc := range l.EnrichDocuments(context.Background(), in, 2) {
+		got[doc.ID] = doc
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("enriched %d documents, want 2", len(got))
+	}
+	if doc := got["doc-1"]; doc.Extract != "Extract of Grand_Hotel." || doc.Abstract != "A hotel." {
+		t.Fatalf("doc-1 = %+v, want the cleaned extract next to the abstract", doc)
+	}
+	if doc := got["doc-2"]; doc.Extract != "" || doc.Abstract != "A page." {
+		t.Fatalf("doc-2 = %+v, want the document unchanged after a failed fetch", doc)
+	}
+}
+
+func TestEnrichDocumentsCancel(t *testing.T) {
+	server := newExtractServer(t)
+	l := newTestLoader()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	in := make(chan models.Document)
+	out := l.EnrichDocuments(ctx, in, 1)
+	go func() {
+		in <- models.Document{ID: "doc-1", DocumentBase: models.DocumentBase{URL: server.URL + "/wiki/Grand_Hotel"}}
+	}()
+	cancel()
+
+	// A cancelled enrichment closes its output without waiting for in.
+	for range out {
+	}
+}
@@ -0,0 +1,172 @@
+package wiki
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FetchOptions controls how the loader calls the Wikipedia API.
+type FetchOptions struct {
+	// Timeout bounds each request, including reading the body; 0 means none.
+	Timeout time.Duration
+	// Retries is how many times a request answered with 429 or a 5xx status,
+	// or failed in transit, is sent again.
+	Retries int
+	// Backoff is the wait before the first retry; it doubles on each one. A
+	// Retry-After header takes precedence when it asks for longer.
+	Backoff time.Duration
+	// Rate is the number of requests started per second across all the
+	// loader's concurrent fetches; 0 means unlimited. Burst requests may
+	// start at once after a pause.
+	Rate  float64
+	Burst int
+}
+
+// DefaultFetchOptions returns the options a new Loader uses.
+func DefaultFetchOptions() FetchOptions {
+	return FetchOptions{
+		Timeout: 10 * time.Second,
+		Retries: 3,
+		Backoff: 500 * time.Millisecond,
+		Rate:    10,
+		Burst:   1,
+	}
+}
+
+// SetFetchOptions replaces the options of the loader's API requests. It must
+// not be called while documents are being fetched.
+func (l *Loader) SetFetchOptions(opts FetchOptions) {
+	l.fetch = opts
+	l.client = &http.Client{Timeout: opts.Timeout}
+	l.limiter = nil
+	if opts.Rate > 0 {
+		l.limiter = newTokenBucket(opts.Rate, max(opts.Burst, 1), time.Now())
+	}
+}
+
+// get returns the body of a successful GET of apiURL, retrying as the fetch
+// options allow. It gives up as soon as ctx is done.
+func (l *Loader) get(ctx context.Context, apiURL string) ([]byte, error) {
+	backoff := l.fetch.Backoff
+	for attempt := 0; ; attempt++ {
+		if l.limiter != nil {
+			if err := l.limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		body, retryAfter, err := l.getOnce(ctx, apiURL)
+		if err == nil {
+			return body, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if retryAfter < 0 || attempt >= l.fetch.Retries {
+			return nil, err
+		}
+
+		wait := max(backoff, retryAfter)
+		backoff *= 2
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// getOnce sends a single request. On failure retryAfter is negative when the
+// request is not worth repeating, and otherwise the wait the server asked
+// for, if any.
+func (l *Loader) getOnce(ctx context.Context, apiURL string) (body []byte, retryAfter time.Duration, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("wiki api: %s", resp.Status)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return nil, parseRetryAfter(resp.Header.Get("Retry-After")), err
+		}
+		return nil, -1, err
+	}
+
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return body, 0, nil
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds. Dates and
+// malformed values count as no request.
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// tokenBucket lets requests start at rate a second on average, and up to
+// burst at once after a pause. It is safe for concurrent use.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: now}
+}
+
+// reserve takes a token and returns how long to wait before it may be used.
+// Tokens go negative while waiters queue up, so each one waits its turn.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.tokens+elapsed.Seconds()*b.rate, b.burst)
+		b.last = now
+	}
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// Wait blocks until a request may start or ctx is done.
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	wait := b.reserve(time.Now())
+	if wait == 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,154 @@
+package wiki
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dariasmyr/fts-engine/internal/domain/models"
+)
+
+// newFlakyServer answers the first failures requests with status and the
+// rest with an extract, counting every request.
+func newFlakyServer(t *testing.T, failures int32, status int, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		fmt.Fprint(w, `{"query":{"pages":{"1":{"title":"Grand Hotel","extract":"A grand hotel."}}}}`)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func testFetchOptions() FetchOptions {
+	return FetchOptions{Timeout: time.Second, Retries: 3, Backoff: time.Millisecond}
+}
+
+func TestFetchRetriesTooManyRequests(t *testing.T) {
+	var requests atomic.Int32
+	server := newFlakyServer(t, 1, http.StatusTooManyRequests, &requests)
+	l := newTestLoader()
+	l.SetFetchOptions(testFetchOptions())
+
+	doc, err := l.FetchAndProcessDocument(context.Background(), models.Document{DocumentBase: models.DocumentBase{URL: server.URL + "/wiki/Grand_Hotel"}})
+	if err != nil {
+		t.Fatalf("FetchAndProcessDocument() error = %v", err)
+	}
+	if doc.Extract != "A grand hotel." {
+		t.Fatalf("Extract = %q, want the extract of the retried request", doc.Extract)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Fatalf("requests = %d, want 2", got)
+	}
+}
+
+func TestFetchGivesUpAfterRetries(t *testing.T) {
+	var requests atomic.Int32
+	server := newFlakyServer(t, 100, http.StatusServiceUnavailable, &requests)
+	l := newTestLoader()
+	l.SetFetchOptions(testFetchOptions())
+
+	doc := models.Document{DocumentBase: models.DocumentBase{URL: server.URL + "/wiki/Grand_Hotel", Abstract: "A hotel."}}
+	got, err := l.FetchAndProcessDocument(context.Background(), doc)
+	if err == nil {
+		t.Fatal("FetchAndProcessDocument() error = nil, want the last failure")
+	}
+	if got != doc {
+		t.Fatalf("document = %+v, want it unchanged", got)
+	}
+	if n := requests.Load(); n != 4 {
+		t.Fatalf("requests = %d, want 1 plus 3 retries", n)
+	}
+}
+
+func TestFetchDoesNotRetryClientErrors(t *testing.T) {
+	var requests atomic.Int32
+	server := newFlakyServer(t, 100, http.StatusNotFound, &requests)
+	l := newTestLoader()
+	l.SetFetchOptions(testFetchOptions())
+
+	if _, err := l.FetchAndProcessDocument(context.Background(), models.Document{DocumentBase: models.DocumentBase{URL: server.URL + "/wiki/Missing"}}); err == nil {
+		t.Fatal("FetchAndProcessDocument() error = nil")
+	}
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("requests = %d, want 1", n)
+	}
+}
+
+func TestFetchStopsRetryingOnCancel(t *testing.T) {
+	var requests atomic.Int32
+	server := newFlakyServer(t, 100, http.StatusTooManyRequests, &requests)
+	l := newTestLoader()
+	opts := testFetchOptions()
+	opts.Backoff = time.Hour
+	l.SetFetchOptions(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	_, err := l.FetchAndProcessDocument(ctx, models.Document{DocumentBase: models.DocumentBase{URL: server.URL + "/wiki/Grand_Hotel"}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("FetchAndProcessDocument() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatalf("FetchAndProcessDocument() returned after %v, want it to stop waiting on cancel", elapsed)
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+
+	l := newTestLoader()
+	l.SetFetchOptions(FetchOptions{Timeout: 20 * time.Millisecond})
+
+	if _, err := l.FetchAndProcessDocument(context.Background(), models.Document{DocumentBase: models.DocumentBase{URL: server.URL + "/wiki/Stalled"}}); err == nil {
+		t.Fatal("FetchAndProcessDocument() error = nil, want a timeout")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":                              0,
+		"2":                             2 * time.Second,
+		"-1":                            0,
+		"Wed, 21 Oct 2015 07:28:00 GMT": 0,
+	}
+	for value, want := range tests {
+		if got := parseRetryAfter(value); got != want {
+			t.Fatalf("parseRetryAfter(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newTokenBucket(10, 2, now)
+
+	// The burst starts at once; the requests after it queue 100ms apart.
+	for i, want := range []time.Duration{0, 0, 100 * time.Millisecond, 200 * time.Millisecond} {
+		if got := b.reserve(now); got != want {
+			t.Fatalf("reserve #%d = %v, want %v", i, got, want)
+		}
+	}
+
+	// After a long pause the bucket refills up to the burst, no further.
+	now = now.Add(time.Hour)
+	for i, want := range []time.Duration{0, 0, 100 * time.Millisecond} {
+		if got := b.reserve(now); got != want {
+			t.Fatalf("reserve #%d after pause = %v, want %v", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package wiki
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/dariasmyr/fts-engine/internal/domain/models"
+)
+
+// IDStrategy chooses which fields of a document its ID is derived from.
+type IDStrategy string
+
+const (
+	// IDContent hashes the title, URL and abstract, so an edited abstract
+	// gives the article a new ID. It is the default, and the zero value
+	// behaves the same.
+	IDContent IDStrategy = "content"
+	// IDURL hashes the URL alone, which stays the same across edits of the
+	// article. Re-adding an edited article then replaces it in the index
+	// instead of leaving the old version behind.
+	IDURL IDStrategy = "url"
+	// IDTitleURL hashes the title and URL, so renamed articles get a new ID
+	// but edited ones keep theirs.
+	IDTitleURL IDStrategy = "title-url"
+)
+
+// ParseIDStrategy returns the strategy named name.
+func ParseIDStrategy(name string) (IDStrategy, error) {
+	switch s := IDStrategy(name); s {
+	case IDContent, IDURL, IDTitleURL:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown id strategy %q (want content, url or title-url)", name)
+	}
+}
+
+// ID derives the ID of document. Documents without a URL fall back to
+// IDContent, since their URL-based IDs would all be the same.
+func (s IDStrategy) ID(document models.Document) string {
+	hasher := md5.New()
+	switch {
+	case s == IDURL && document.URL != "":
+		io.WriteString(hasher, document.URL)
+	case s == IDTitleURL && document.URL != "":
+		io.WriteString(hasher, document.Title+"|"+document.URL)
+	default:
+		io.WriteString(hasher, document.Title+"|"+document.URL+"|"+document.Abstract)
+	}
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// DocumentID derives an ID from the document's title, URL and abstract, as
+// IDContent does.
+func DocumentID(document models.Document) string {
+	return IDContent.ID(document)
+}
+
+// SetIDStrategy sets how the loader derives the IDs of the documents it
+// reads. It must not be called while the dump is being read.
+func (l *Loader) SetIDStrategy(s IDStrategy) {
+	l.ids = s
+}
@@ -0,0 +1,74 @@
+package wiki
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dariasmyr/fts-engine/internal/domain/models"
+)
+
+func TestIDStrategies(t *testing.T) {
+	doc := models.Document{DocumentBase: models.DocumentBase{Title: "Grand Hotel", URL: "https://en.wikipedia.org/wiki/Grand_Hotel", Abstract: "A hotel."}}
+	edited := doc
+	edited.Abstract = "A grand hotel in Berlin."
+	renamed := doc
+	renamed.Title = "Grand Hotel (Berlin)"
+
+	tests := []struct {
+		strategy      IDStrategy
+		keepsOnEdit   bool
+		keepsOnRename bool
+	}{
+		{strategy: IDContent},
+		{strategy: IDURL, keepsOnEdit: true, keepsOnRename: true},
+		{strategy: IDTitleURL, keepsOnEdit: true},
+	}
+	for _, tt := range tests {
+		id := tt.strategy.ID(doc)
+		if got := tt.strategy.ID(edited) == id; got != tt.keepsOnEdit {
+			t.Fatalf("%s: ID kept after an abstract edit = %v, want %v", tt.strategy, got, tt.keepsOnEdit)
+		}
+		if got := tt.strategy.ID(renamed) == id; got != tt.keepsOnRename {
+			t.Fatalf("%s: ID kept after a rename = %v, want %v", tt.strategy, got, tt.keepsOnRename)
+		}
+	}
+
+	if IDStrategy("").ID(doc) != DocumentID(doc) || IDContent.ID(doc) != DocumentID(doc) {
+		t.Fatal("the default strategy does not match DocumentID")
+	}
+}
+
+func TestIDStrategyWithoutURL(t *testing.T) {
+	a := models.Document{DocumentBase: models.DocumentBase{Title: "Grand Hotel"}}
+	b := models.Document{DocumentBase: models.DocumentBase{Title: "River Barge"}}
+
+	if IDURL.ID(a) == IDURL.ID(b) {
+		t.Fatal("documents without a URL share an ID")
+	}
+}
+
+func TestParseIDStrategy(t *testing.T) {
+	for _, name := range []string{"content", "url", "title-url"} {
+		if s, err := ParseIDStrategy(name); err != nil || string(s) != name {
+			t.Fatalf("ParseIDStrategy(%q) = %q, %v", name, s, err)
+		}
+	}
+	if _, err := ParseIDStrategy("title"); err == nil {
+		t.Fatal("ParseIDStrategy(title) error = nil")
+	}
+}
+
+func TestLoaderIDStrategy(t *testing.T) {
+	l := newTestLoader(writeDump(t, 2))
+	l.SetIDStrategy(IDURL)
+
+	documents, err := l.LoadDocuments(context.Background())
+	if err != nil {
+		t.Fatalf("LoadDocuments() error = %v", err)
+	}
+	for _, doc := range documents {
+		if doc.ID != IDURL.ID(doc) {
+			t.Fatalf("doc %q has ID %q, want the URL-based ID", doc.Title, doc.ID)
+		}
+	}
+}
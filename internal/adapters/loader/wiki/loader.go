@@ -1,10 +1,10 @@
 package wiki
 
 import (
+	"bufio"
+	"bytes"
 	"compress/gzip"
 	"context"
-	"crypto/md5"
-	"encoding/hex"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
@@ -17,64 +17,257 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 )
 
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Loader reads Wikipedia abstract dumps, gzipped or plain XML. A dump may be split into shards;
+// each path given to New is a file or a glob matching several of them.
 type Loader struct {
-	log      *slog.Logger
-	dumpPath string
+	log       *slog.Logger
+	dumpPaths []string
+	ids       IDStrategy
+	skipped   atomic.Int64
+
+	fetch   FetchOptions
+	client  *http.Client
+	limiter *tokenBucket
 }
 
-func New(log *slog.Logger, dumpPath string) *Loader {
-	return &Loader{log: log, dumpPath: dumpPath}
+// New returns a loader for dumpPaths that calls the Wikipedia API with
+// DefaultFetchOptions.
+func New(log *slog.Logger, dumpPaths ...string) *Loader {
+	l := &Loader{log: log, dumpPaths: dumpPaths}
+	l.SetFetchOptions(DefaultFetchOptions())
+	return l
 }
 
-func (l *Loader) LoadDocuments(ctx context.Context) (documents []models.Document, err error) {
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	default:
+// ExpandPaths resolves globs in paths, in order and without duplicates.
+// A path that matches nothing is kept as is, so opening it reports why.
+func ExpandPaths(paths []string) []string {
+	var out []string
+	seen := make(map[string]struct{})
+	for _, pattern := range paths {
+		matches, err := filepath.Glob(pattern)
+		if err != nil || len(matches) == 0 {
+			matches = []string{pattern}
+		}
+		for _, path := range matches {
+			if _, ok := seen[path]; ok {
+				continue
+			}
+			seen[path] = struct{}{}
+			out = append(out, path)
+		}
 	}
+	return out
+}
 
-	f, err := os.Open(l.dumpPath)
-	if err != nil {
-		l.log.Error("Failed to open file", "error", err)
+// LoadDocuments reads the whole dump into memory. Use StreamDocuments to
+// process documents while the dump is being parsed.
+func (l *Loader) LoadDocuments(ctx context.Context) ([]models.Document, error) {
+	var documents []models.Document
+
+	docs, errc := l.StreamDocuments(ctx)
+	for doc := range docs {
+		documents = append(documents, doc)
+	}
+	if err := <-errc; err != nil {
 		return nil, err
 	}
+
+	return documents, nil
+}
+
+// StreamDocuments parses the dump on a separate goroutine and sends each
+// document, with its ID set, as soon as it is decoded. Malformed documents
+// are skipped; see Skipped. Both channels are closed when the dump ends, a
+// shard cannot be read or ctx is done; the error channel then yields the
+// error, if any.
+func (l *Loader) StreamDocuments(ctx context.Context) (<-chan models.Document, <-chan error) {
+	docs := make(chan models.Document)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(docs)
+		defer close(errc)
+
+		if err := l.streamDocuments(ctx, docs); err != nil {
+			errc <- err
+		}
+	}()
+
+	return docs, errc
+}
+
+// streamDocuments reads the shards one after another. A shard that cannot be
+// opened is skipped; the load only fails on it when no shard could be opened.
+func (l *Loader) streamDocuments(ctx context.Context, out chan<- models.Document) error {
+	l.skipped.Store(0)
 	defer func() {
-		err = f.Close()
+		if n := l.skipped.Load(); n > 0 {
+			l.log.Warn("Skipped malformed documents", "count", n)
+		}
+	}()
+
+	var openErrs []error
+	opened := 0
+
+	for _, path := range ExpandPaths(l.dumpPaths) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		ok, err := l.streamShard(ctx, path, out)
+		if !ok {
+			l.log.Warn("Skipping dump shard", "path", path, "error", sl.Err(err))
+			openErrs = append(openErrs, err)
+			continue
+		}
+		opened++
 		if err != nil {
+			return fmt.Errorf("read %s: %w", path, err)
+		}
+	}
+
+	if opened == 0 {
+		return errors.Join(openErrs...)
+	}
+	return nil
+}
+
+// streamShard reports whether the shard could be opened, and the error that
+// stopped it. Shards starting with the gzip magic bytes are decompressed;
+// anything else is read as plain XML.
+func (l *Loader) streamShard(ctx context.Context, path string, out chan<- models.Document) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
 			l.log.Error("Failed to close file", "error", err)
 		}
 	}()
 
-	gz, err := gzip.NewReader(f)
+	br := bufio.NewReader(f)
+	magic, err := br.Peek(len(gzipMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+	if !bytes.Equal(magic, gzipMagic) {
+		return true, l.decodeDocuments(ctx, br, out)
+	}
+
+	gz, err := gzip.NewReader(br)
 	if err != nil {
-		return nil, err
+		return false, fmt.Errorf("%s: %w", path, err)
 	}
 	defer func() {
 		_ = gz.Close()
 	}()
 
-	dec := xml.NewDecoder(gz)
-	dump := struct {
-		Documents []models.Document `xml:"doc"`
-	}{}
+	return true, l.decodeDocuments(ctx, gz, out)
+}
+
+// maxDocSize bounds a single <doc> element. A dump with a longer one fails to
+// load rather than being buffered whole.
+const maxDocSize = 64 << 20
+
+var (
+	docOpen  = []byte("<doc")
+	docClose = []byte("</doc>")
+)
+
+// decodeDocuments splits the dump into <doc> elements and decodes each on its
+// own, so a malformed document is logged, counted in Skipped and passed over
+// instead of ending the load. A document is malformed when it does not
+// decode, or when a new <doc> starts before it is closed.
+func (l *Loader) decodeDocuments(ctx context.Context, r io.Reader, out chan<- models.Document) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxDocSize)
+	scanner.Split(splitDocs)
+
+	offset := int64(0)
+	for scanner.Scan() {
+		chunk := scanner.Bytes()
+		starts := docStarts(chunk)
+		closed := bytes.HasSuffix(chunk, docClose)
+
+		for i, start := range starts {
+			if i < len(starts)-1 || !closed {
+				l.skipDocument(offset+int64(start), chunk[start:], errors.New("unclosed <doc>"))
+				continue
+			}
+
+			var doc models.Document
+			if err := xml.Unmarshal(chunk[start:], &doc); err != nil {
+				l.skipDocument(offset+int64(start), chunk[start:], err)
+				continue
+			}
+			doc.ID = l.ids.ID(doc)
+
+			select {
+			case out <- doc:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		offset += int64(len(chunk))
+	}
+	return scanner.Err()
+}
+
+// skipDocument logs the start of the malformed element at byte offset of its
+// shard and counts it.
+func (l *Loader) skipDocument(offset int64, element []byte, err error) {
+	l.skipped.Add(1)
+	if len(element) > 200 {
+		element = element[:200]
+	}
+	l.log.Warn("Skipping malformed document", "offset", offset, "element", string(element), "error", sl.Err(err))
+}
 
-	if decodeErr := dec.Decode(&dump); decodeErr != nil {
-		return nil, decodeErr
+// splitDocs is a bufio.SplitFunc whose tokens end after a </doc>. The last
+// token holds what follows the last </doc>, such as the closing </feed>.
+func splitDocs(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if end := bytes.Index(data, docClose); end >= 0 {
+		end += len(docClose)
+		return end, data[:end], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
 	}
+	return 0, nil, nil
+}
 
-	for i := range dump.Documents {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-			dump.Documents[i].ID = l.generateID(dump.Documents[i])
+// docStarts returns the offsets of the <doc> start tags in chunk.
+func docStarts(chunk []byte) []int {
+	var starts []int
+	for at := 0; ; {
+		i := bytes.Index(chunk[at:], docOpen)
+		if i < 0 {
+			return starts
 		}
+		at += i
+		if next := at + len(docOpen); next < len(chunk) && (chunk[next] == '>' || chunk[next] == '/' || isXMLSpace(chunk[next])) {
+			starts = append(starts, at)
+		}
+		at += len(docOpen)
 	}
+}
 
-	return dump.Documents, nil
+func isXMLSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+// Skipped returns how many malformed documents the last load passed over. It
+// is complete once the load has finished.
+func (l *Loader) Skipped() int {
+	return int(l.skipped.Load())
 }
 
 func (l *Loader) ChunkDocuments(documents []models.Document, chunkSize int) [][]models.Document {
@@ -93,12 +286,6 @@ func (l *Loader) ChunkDocuments(documents []models.Document, chunkSize int) [][]
 	return chunks
 }
 
-func (l *Loader) generateID(document models.Document) string {
-	hasher := md5.New()
-	io.WriteString(hasher, document.Title+"|"+document.URL+"|"+document.Abstract)
-	return hex.EncodeToString(hasher.Sum(nil))
-}
-
 func (l *Loader) parseURL(docURL string) (host string, title string, err error) {
 	parsedURL, err := url.Parse(docURL)
 	if err != nil {
@@ -116,41 +303,28 @@ func (l *Loader) parseURL(docURL string) (host string, title string, err error)
 	return host, title, nil
 }
 
+// FetchAndProcessDocument fills in doc.Extract with the cleaned plain-text
+// extract of its article, fetched from the API of the wiki doc.URL points to.
+// On error doc is returned unchanged.
 func (l *Loader) FetchAndProcessDocument(ctx context.Context, doc models.Document) (models.Document, error) {
 	host, title, err := l.parseURL(doc.URL)
 	if err != nil {
-		l.log.Error("Error parsing url", "error", sl.Err(err))
 		return doc, err
 	}
 
 	apiURL := fmt.Sprintf("%s/w/api.php?action=query&prop=extracts&explaintext=true&format=json&titles=%s", host, title)
-	req, reqErr := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
-	if reqErr != nil {
-		return doc, reqErr
-	}
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		l.log.Error("Error getting url", "error", sl.Err(err))
-		return doc, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	body, err := l.get(ctx, apiURL)
 	if err != nil {
-		l.log.Error("Error reading body", "error", sl.Err(err))
-		return doc, err
+		return doc, fmt.Errorf("fetch extract: %w", err)
 	}
 
 	var apiResponse models.ArticleResponse
 	if err := json.Unmarshal(body, &apiResponse); err != nil {
-		l.log.Error("Error unmarshalling body", "error", sl.Err(err))
-		return doc, err
+		return doc, fmt.Errorf("decode extract: %w", err)
 	}
 
 	for _, page := range apiResponse.Query.Pages {
 		if page.Extract == "" {
-			l.log.Error("Empty extract")
 			return doc, errors.New("empty extract in response")
 		}
 
@@ -0,0 +1,238 @@
+package wiki
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func dumpXML(docs int) string {
+	var b strings.Builder
+	b.WriteString("<feed>\n")
+	for i := range docs {
+		fmt.Fprintf(&b, "<doc><title>Wikipedia: Doc %d</title><url>https://en.wikipedia.org/wiki/Doc_%d</url><abstract>abstract %d</abstract><links/></doc>\n", i, i, i)
+	}
+	b.WriteString("</feed>\n")
+	return b.String()
+}
+
+func writeDump(t *testing.T, docs int) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "dump.xml.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create dump: %v", err)
+	}
+	gz := gzip.NewWriter(f)
+	if _, err := io.WriteString(gz, dumpXML(docs)); err != nil {
+		t.Fatalf("write dump: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("close gzip: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("close dump: %v", err)
+	}
+	return path
+}
+
+func newTestLoader(paths ...string) *Loader {
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), paths...)
+}
+
+func TestStreamDocuments(t *testing.T) {
+	l := newTestLoader(writeDump(t, 3))
+
+	docs, errc := l.StreamDocuments(context.Background())
+	var titles []string
+	for doc := range docs {
+		if doc.ID != DocumentID(doc) {
+			t.Fatalf("doc %q has ID %q, want generated ID", doc.Title, doc.ID)
+		}
+		titles = append(titles, doc.Title)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("StreamDocuments() error = %v", err)
+	}
+	if len(titles) != 3 || titles[2] != "Wikipedia: Doc 2" {
+		t.Fatalf("titles = %q", titles)
+	}
+}
+
+func TestLoadDocumentsPlainXML(t *testing.T) {
+	gzipped, err := newTestLoader(writeDump(t, 4)).LoadDocuments(context.Background())
+	if err != nil {
+		t.Fatalf("LoadDocuments(gzip) error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "dump.xml")
+	if err := os.WriteFile(path, []byte(dumpXML(4)), 0o644); err != nil {
+		t.Fatalf("write dump: %v", err)
+	}
+	plain, err := newTestLoader(path).LoadDocuments(context.Background())
+	if err != nil {
+		t.Fatalf("LoadDocuments(xml) error = %v", err)
+	}
+
+	if len(plain) != 4 || len(gzipped) != 4 {
+		t.Fatalf("loaded %d plain and %d gzipped documents, want 4", len(plain), len(gzipped))
+	}
+	for i := range plain {
+		if plain[i] != gzipped[i] {
+			t.Fatalf("document %d: plain %+v, gzipped %+v", i, plain[i], gzipped[i])
+		}
+	}
+}
+
+func TestLoadDocumentsMatchesStream(t *testing.T) {
+	l := newTestLoader(writeDump(t, 5))
+
+	documents, err := l.LoadDocuments(context.Background())
+	if err != nil {
+		t.Fatalf("LoadDocuments() error = %v", err)
+	}
+	if len(documents) != 5 || documents[4].Abstract != "abstract 4" {
+		t.Fatalf("documents = %+v", documents)
+	}
+}
+
+// brokenDumpXML holds three valid documents around one that does not decode
+// and one that is never closed.
+const brokenDumpXML = `<feed>
+<doc><title>Wikipedia: Grand Hotel</title><url>https://en.wikipedia.org/wiki/Grand_Hotel</url><abstract>A hotel.</abstract><links/></doc>
+<doc><title>Wikipedia: Broken</title><url>https://en.wikipedia.org/wiki/Broken</url><abstract>An <b>unclosed tag.</abstract></doc>
+<doc><title>Wikipedia: River Barge</title><url>https://en.wikipedia.org/wiki/River_Barge</url><abstract>A barge &amp; a river.</abstract></doc>
+<doc><title>Wikipedia: Truncated</title><abstract>Cut off
+<doc><title>Wikipedia: Old Town</title><url>https://en.wikipedia.org/wiki/Old_Town</url><abstract>A town.</abstract></doc>
+</feed>
+`
+
+func TestLoadDocumentsSkipsMalformed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.xml")
+	if err := os.WriteFile(path, []byte(brokenDumpXML), 0o644); err != nil {
+		t.Fatalf("write dump: %v", err)
+	}
+	l := newTestLoader(path)
+
+	documents, err := l.LoadDocuments(context.Background())
+	if err != nil {
+		t.Fatalf("LoadDocuments() error = %v", err)
+	}
+	var titles []string
+	for _, doc := range documents {
+		titles = append(titles, doc.Title)
+	}
+	want := []string{"Wikipedia: Grand Hotel", "Wikipedia: River Barge", "Wikipedia: Old Town"}
+	if strings.Join(titles, ",") != strings.Join(want, ",") {
+		t.Fatalf("titles = %q, want %q", titles, want)
+	}
+	if documents[1].Abstract != "A barge & a river." {
+		t.Fatalf("abstract = %q, want entities decoded", documents[1].Abstract)
+	}
+	if got := l.Skipped(); got != 2 {
+		t.Fatalf("Skipped() = %d, want 2", got)
+	}
+
+	// A later load counts its own skips.
+	l.dumpPaths = []string{writeDump(t, 2)}
+	if _, err := l.LoadDocuments(context.Background()); err != nil {
+		t.Fatalf("LoadDocuments() error = %v", err)
+	}
+	if got := l.Skipped(); got != 0 {
+		t.Fatalf("Skipped() after a clean load = %d, want 0", got)
+	}
+}
+
+func TestLoadDocumentsSkipsTruncatedEnd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.xml")
+	dump := strings.TrimSuffix(dumpXML(2), "</feed>\n") + "<doc><title>Wikipedia: Cut"
+	if err := os.WriteFile(path, []byte(dump), 0o644); err != nil {
+		t.Fatalf("write dump: %v", err)
+	}
+	l := newTestLoader(path)
+
+	documents, err := l.LoadDocuments(context.Background())
+	if err != nil {
+		t.Fatalf("LoadDocuments() error = %v", err)
+	}
+	if len(documents) != 2 || l.Skipped() != 1 {
+		t.Fatalf("loaded %d documents and skipped %d, want 2 and 1", len(documents), l.Skipped())
+	}
+}
+
+func TestStreamDocumentsCancel(t *testing.T) {
+	l := newTestLoader(writeDump(t, 100))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	docs, errc := l.StreamDocuments(ctx)
+	<-docs
+	cancel()
+	for range docs {
+	}
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("StreamDocuments() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestStreamDocumentsMissingFile(t *testing.T) {
+	l := newTestLoader(filepath.Join(t.TempDir(), "missing.xml.gz"))
+
+	docs, errc := l.StreamDocuments(context.Background())
+	for range docs {
+	}
+	if err := <-errc; !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("StreamDocuments() error = %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestStreamDocumentsShards(t *testing.T) {
+	dir := t.TempDir()
+	for i, docs := range []int{2, 3} {
+		if err := os.Rename(writeDump(t, docs), filepath.Join(dir, fmt.Sprintf("abstract%d.xml.gz", i+1))); err != nil {
+			t.Fatalf("rename shard: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "abstract9.xml.gz"), []byte{0x1f, 0x8b, 0x00}, 0o644); err != nil {
+		t.Fatalf("write broken shard: %v", err)
+	}
+
+	l := newTestLoader(filepath.Join(dir, "abstract*.xml.gz"), filepath.Join(dir, "missing.xml.gz"))
+	documents, err := l.LoadDocuments(context.Background())
+	if err != nil {
+		t.Fatalf("LoadDocuments() error = %v", err)
+	}
+	if len(documents) != 5 {
+		t.Fatalf("loaded %d documents, want 5 from the two readable shards", len(documents))
+	}
+
+	single, err := newTestLoader(filepath.Join(dir, "abstract1.xml.gz")).LoadDocuments(context.Background())
+	if err != nil {
+		t.Fatalf("LoadDocuments() error = %v", err)
+	}
+	if single[0].ID != documents[0].ID {
+		t.Fatalf("ID = %q when loaded alone, %q when loaded with other shards", single[0].ID, documents[0].ID)
+	}
+}
+
+func TestExpandPaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"abstract1.xml.gz", "abstract2.xml.gz"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatalf("write shard: %v", err)
+		}
+	}
+
+	got := ExpandPaths([]string{filepath.Join(dir, "abstract2.xml.gz"), filepath.Join(dir, "*.gz"), "missing.xml.gz"})
+	want := []string{filepath.Join(dir, "abstract2.xml.gz"), filepath.Join(dir, "abstract1.xml.gz"), "missing.xml.gz"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("ExpandPaths() = %q, want %q", got, want)
+	}
+}
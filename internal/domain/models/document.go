@@ -1,5 +1,12 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/dariasmyr/fts-engine/internal/utils"
+)
+
 type DocumentBase struct {
 	Title    string `xml:"title" json:"title"`
 	URL      string `xml:"url" json:"url"`
@@ -13,14 +20,122 @@ type Document struct {
 }
 
 type ResultData struct {
-	ID            string   `json:"id"`
-	UniqueMatches int      `json:"unique_matches"`
-	TotalMatches  int      `json:"total_matches"`
-	Document      Document `json:"document"`
+	ID            string      `json:"id"`
+	UniqueMatches int         `json:"unique_matches"`
+	TotalMatches  int         `json:"total_matches"`
+	Score         float64     `json:"score"`
+	FuzzyTerms    []FuzzyTerm `json:"fuzzy_terms,omitempty"`
+	MatchedTerms  []string    `json:"matched_terms,omitempty"`
+	Snippet       string      `json:"snippet,omitempty"`
+	MatchSpans    []MatchSpan `json:"match_spans,omitempty"`
+	TitleSpans    []MatchSpan `json:"title_spans,omitempty"`
+	Document      Document    `json:"document"`
+}
+
+// MatchSpan is the byte range [Start, End) of a matched word in Snippet, or in
+// the document title for TitleSpans.
+type MatchSpan struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// FuzzyTerm tells which indexed term a fuzzy query token matched.
+type FuzzyTerm struct {
+	Token    string `json:"token"`
+	Term     string `json:"term"`
+	Distance int    `json:"distance"`
 }
 
 type SearchResult struct {
-	ResultData        []ResultData
-	TotalResultsCount int
-	Timings           map[string]string
+	ResultData        []ResultData `json:"results"`
+	TotalResultsCount int          `json:"total_results_count"`
+	// Timings holds the duration of each search phase. JSON carries it twice:
+	// formatted under "timings" and in nanoseconds under "timings_ns".
+	Timings map[string]time.Duration `json:"-"`
+	// Suggestion is a corrected query for a search that found little.
+	Suggestion string `json:"suggestion,omitempty"`
+	// Truncated is set when the search ran out of time and the results hold
+	// only what it found by then.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// searchResultJSON is the wire form of SearchResult.
+type searchResultJSON struct {
+	ResultData        []ResultData      `json:"results"`
+	TotalResultsCount int               `json:"total_results_count"`
+	Timings           map[string]string `json:"timings"`
+	TimingsNS         map[string]int64  `json:"timings_ns"`
+	Suggestion        string            `json:"suggestion,omitempty"`
+	Truncated         bool              `json:"truncated,omitempty"`
+}
+
+func (r SearchResult) MarshalJSON() ([]byte, error) {
+	out := searchResultJSON{
+		ResultData:        r.ResultData,
+		TotalResultsCount: r.TotalResultsCount,
+		Timings:           make(map[string]string, len(r.Timings)),
+		TimingsNS:         make(map[string]int64, len(r.Timings)),
+		Suggestion:        r.Suggestion,
+		Truncated:         r.Truncated,
+	}
+	for phase, d := range r.Timings {
+		out.Timings[phase] = utils.FormatDuration(d)
+		out.TimingsNS[phase] = d.Nanoseconds()
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON reads the timings back from "timings_ns"; the formatted
+// ones are rounded.
+func (r *SearchResult) UnmarshalJSON(data []byte) error {
+	var in searchResultJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	*r = SearchResult{
+		ResultData:        in.ResultData,
+		TotalResultsCount: in.TotalResultsCount,
+		Suggestion:        in.Suggestion,
+		Truncated:         in.Truncated,
+	}
+	if in.TimingsNS != nil {
+		r.Timings = make(map[string]time.Duration, len(in.TimingsNS))
+		for phase, ns := range in.TimingsNS {
+			r.Timings[phase] = time.Duration(ns)
+		}
+	}
+	return nil
+}
+
+// IndexStats describes the whole index rather than one search. The structure
+// fields are only set when Analyzed is true; not every index can report them.
+// AnalyzedAt is when the structure fields were computed, which may be before
+// the latest changes to the index. The cache fields count searches answered
+// by the result cache and are zero when it is off.
+type IndexStats struct {
+	Documents    int       `json:"documents"`
+	AvgDocLength float64   `json:"avg_doc_length"`
+	Analyzed     bool      `json:"analyzed"`
+	Keys         int       `json:"keys"`
+	Nodes        int       `json:"nodes"`
+	MaxDepth     int       `json:"max_depth"`
+	Postings     int       `json:"postings"`
+	AnalyzedAt   time.Time `json:"analyzed_at,omitzero"`
+	CacheEntries int       `json:"cache_entries,omitempty"`
+	CacheHits    uint64    `json:"cache_hits,omitempty"`
+	CacheMisses  uint64    `json:"cache_misses,omitempty"`
+}
+
+// TextAnalysis is what the engine makes of a text when indexing or searching
+// it. PhraseTokens is set when quoted phrases are processed differently.
+type TextAnalysis struct {
+	Tokens       []AnalyzedToken `json:"tokens"`
+	PhraseTokens []AnalyzedToken `json:"phrase_tokens,omitempty"`
+}
+
+// AnalyzedToken is a processed word and the index keys it is stored under.
+type AnalyzedToken struct {
+	Token string   `json:"token"`
+	Keys  []string `json:"keys"`
 }
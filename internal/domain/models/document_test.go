@@ -0,0 +1,62 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSearchResultJSON(t *testing.T) {
+	result := SearchResult{
+		ResultData:        []ResultData{{ID: "doc-1", UniqueMatches: 1, TotalMatches: 2, Score: 0.5}},
+		TotalResultsCount: 1,
+		Timings:           map[string]time.Duration{"total": 1250 * time.Microsecond},
+		Suggestion:        "grand hotel",
+		Truncated:         true,
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	var shape map[string]any
+	if err := json.Unmarshal(data, &shape); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got := shape["timings"]; !reflect.DeepEqual(got, map[string]any{"total": "1.250ms"}) {
+		t.Fatalf("timings = %v, want formatted durations", got)
+	}
+	if got := shape["timings_ns"]; !reflect.DeepEqual(got, map[string]any{"total": float64(1250000)}) {
+		t.Fatalf("timings_ns = %v, want nanoseconds", got)
+	}
+	if got := shape["suggestion"]; got != "grand hotel" {
+		t.Fatalf("suggestion = %v, want %q", got, "grand hotel")
+	}
+	if got := shape["truncated"]; got != true {
+		t.Fatalf("truncated = %v, want true", got)
+	}
+	results := shape["results"].([]any)
+	if doc := results[0].(map[string]any); doc["id"] != "doc-1" || doc["total_matches"] != float64(2) {
+		t.Fatalf("results = %v", results)
+	}
+
+	var back SearchResult
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(back, result) {
+		t.Fatalf("round trip = %+v, want %+v", back, result)
+	}
+}
+
+func TestSearchResultJSONWithoutTimings(t *testing.T) {
+	data, err := json.Marshal(&SearchResult{})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if want := `{"results":null,"total_results_count":0,"timings":{},"timings_ns":{}}`; string(data) != want {
+		t.Fatalf("JSON = %s, want %s", data, want)
+	}
+}
@@ -0,0 +1,153 @@
+package persist
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// logHeaderSize is the length and CRC-32 that precede every log record.
+const logHeaderSize = 8
+
+// maxLogRecord bounds the record length read back, so a corrupt length does
+// not make replay allocate gigabytes.
+const maxLogRecord = 64 << 20
+
+// Log is an append-only file of records. It makes changes to an in-memory
+// index durable between snapshots: append a record for every change, replay
+// the log after loading the snapshot, and truncate it once a snapshot covers
+// the logged changes. It is safe for concurrent use.
+type Log struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenLog replays the records of the log at path through apply, in the order
+// they were appended, and opens the log for appending. A record cut short by
+// a crash, or that fails its checksum, ends the log: it and everything after
+// it are dropped, so new records are not appended behind it. A missing file
+// is an empty log. It returns the number of records replayed.
+func OpenLog(path string, apply func(record []byte) error) (*Log, int, error) {
+	if path == "" {
+		return nil, 0, fmt.Errorf("persist: open log: empty path")
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, 0, fmt.Errorf("persist: open log: mkdir %q: %w", dir, err)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, 0, fmt.Errorf("persist: open log: %w", err)
+	}
+
+	valid, replayed, err := replayLog(file, apply)
+	if err != nil {
+		_ = file.Close()
+		return nil, replayed, fmt.Errorf("persist: open log: replay record %d: %w", replayed+1, err)
+	}
+	if err := file.Truncate(valid); err != nil {
+		_ = file.Close()
+		return nil, replayed, fmt.Errorf("persist: open log: drop torn tail: %w", err)
+	}
+	if _, err := file.Seek(valid, io.SeekStart); err != nil {
+		_ = file.Close()
+		return nil, replayed, fmt.Errorf("persist: open log: %w", err)
+	}
+
+	return &Log{file: file}, replayed, nil
+}
+
+// replayLog applies the intact records of r and returns the length of the
+// intact prefix.
+func replayLog(r io.Reader, apply func(record []byte) error) (int64, int, error) {
+	br := bufio.NewReader(r)
+	var (
+		valid    int64
+		replayed int
+		header   [logHeaderSize]byte
+	)
+	for {
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return valid, replayed, nil
+			}
+			return valid, replayed, err
+		}
+		size := binary.BigEndian.Uint32(header[:4])
+		if size > maxLogRecord {
+			return valid, replayed, nil
+		}
+		record := make([]byte, size)
+		if _, err := io.ReadFull(br, record); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return valid, replayed, nil
+			}
+			return valid, replayed, err
+		}
+		if crc32.ChecksumIEEE(record) != binary.BigEndian.Uint32(header[4:]) {
+			return valid, replayed, nil
+		}
+
+		if err := apply(record); err != nil {
+			return valid, replayed, err
+		}
+		valid += logHeaderSize + int64(size)
+		replayed++
+	}
+}
+
+// Append writes record to the log and syncs it to disk before returning.
+func (l *Log) Append(record []byte) error {
+	if len(record) > maxLogRecord {
+		return fmt.Errorf("persist: log append: record of %d bytes exceeds %d", len(record), maxLogRecord)
+	}
+
+	buf := make([]byte, logHeaderSize+len(record))
+	binary.BigEndian.PutUint32(buf[:4], uint32(len(record)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(record))
+	copy(buf[logHeaderSize:], record)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.file.Write(buf); err != nil {
+		return fmt.Errorf("persist: log append: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("persist: log append: sync: %w", err)
+	}
+	return nil
+}
+
+// Truncate empties the log. Call it once a snapshot holds every change
+// appended so far.
+func (l *Log) Truncate() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.file.Truncate(0); err != nil {
+		return fmt.Errorf("persist: log truncate: %w", err)
+	}
+	if _, err := l.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("persist: log truncate: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("persist: log truncate: sync: %w", err)
+	}
+	return nil
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.file.Close()
+}
@@ -0,0 +1,103 @@
+package persist
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func openTestLog(t *testing.T, path string) (*Log, []string) {
+	t.Helper()
+
+	var records []string
+	l, _, err := OpenLog(path, func(record []byte) error {
+		records = append(records, string(record))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("OpenLog() error = %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l, records
+}
+
+func TestLogReplaysAppendedRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.log")
+
+	l, records := openTestLog(t, path)
+	if len(records) != 0 {
+		t.Fatalf("new log replayed %q", records)
+	}
+	for _, record := range []string{"doc-1", "", "doc-2"} {
+		if err := l.Append([]byte(record)); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	l.Close()
+
+	if _, records = openTestLog(t, path); !slices.Equal(records, []string{"doc-1", "", "doc-2"}) {
+		t.Fatalf("replayed %q", records)
+	}
+}
+
+func TestLogDropsTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.log")
+
+	l, _ := openTestLog(t, path)
+	if err := l.Append([]byte("doc-1")); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	l.Close()
+
+	// A crash in the middle of the second append leaves half a record.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	f.Write([]byte{0, 0, 0, 9, 1, 2})
+	f.Close()
+
+	l, records := openTestLog(t, path)
+	if !slices.Equal(records, []string{"doc-1"}) {
+		t.Fatalf("replayed %q, want the intact record", records)
+	}
+	if err := l.Append([]byte("doc-2")); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	l.Close()
+
+	if _, records = openTestLog(t, path); !slices.Equal(records, []string{"doc-1", "doc-2"}) {
+		t.Fatalf("replayed %q, want records appended after the torn tail", records)
+	}
+}
+
+func TestLogTruncate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.log")
+
+	l, _ := openTestLog(t, path)
+	l.Append([]byte("doc-1"))
+	if err := l.Truncate(); err != nil {
+		t.Fatalf("Truncate() error = %v", err)
+	}
+	l.Append([]byte("doc-2"))
+	l.Close()
+
+	if _, records := openTestLog(t, path); !slices.Equal(records, []string{"doc-2"}) {
+		t.Fatalf("replayed %q, want only the record after Truncate", records)
+	}
+}
+
+func TestLogReplayError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.log")
+
+	l, _ := openTestLog(t, path)
+	l.Append([]byte("doc-1"))
+	l.Close()
+
+	broken := errors.New("index broken")
+	if _, _, err := OpenLog(path, func([]byte) error { return broken }); !errors.Is(err, broken) {
+		t.Fatalf("OpenLog() error = %v, want %v", err, broken)
+	}
+}
@@ -0,0 +1,115 @@
+package search
+
+import (
+	"runtime"
+	"sync"
+	"weak"
+
+	"github.com/dariasmyr/fts-engine/internal/domain/models"
+)
+
+// DocumentCache is a DocumentStore that keeps the documents it has read from
+// another store behind weak pointers. The pointers target the document the
+// store handed out (the one it holds, for a DocumentRefStore), and Document
+// hands that same document to its callers, so an entry lives as long as the
+// store or any caller still refers to it. A document requested again before
+// the garbage collector reclaims it is served from memory; after that the
+// next read goes to the store and repopulates the entry. The cache never
+// holds documents alive itself, so it costs no memory under pressure. It is
+// safe for concurrent use.
+type DocumentCache struct {
+	store DocumentStore
+
+	mu      sync.Mutex
+	entries map[string]weak.Pointer[models.Document]
+}
+
+var (
+	_ DocumentStore    = (*DocumentCache)(nil)
+	_ DocumentRefStore = (*DocumentCache)(nil)
+)
+
+// NewDocumentCache returns a cache in front of store.
+func NewDocumentCache(store DocumentStore) *DocumentCache {
+	return &DocumentCache{
+		store:   store,
+		entries: make(map[string]weak.Pointer[models.Document]),
+	}
+}
+
+// GetDocument returns a copy of DocumentRef(id).
+func (c *DocumentCache) GetDocument(id string) (models.Document, bool) {
+	doc, ok := c.DocumentRef(id)
+	if !ok {
+		return models.Document{}, false
+	}
+	return *doc, true
+}
+
+// DocumentRef returns the cached document id, reading it from the store when
+// it is not cached or has been reclaimed. Callers must not modify it.
+func (c *DocumentCache) DocumentRef(id string) (*models.Document, bool) {
+	c.mu.Lock()
+	entry, ok := c.entries[id]
+	c.mu.Unlock()
+	if ok {
+		if doc := entry.Value(); doc != nil {
+			return doc, true
+		}
+	}
+
+	doc, ok := c.read(id)
+	if !ok {
+		return nil, false
+	}
+	c.put(id, doc)
+	return doc, true
+}
+
+// Forget drops id from the cache. Call it when the document changes in the
+// store, so the old version is not served.
+func (c *DocumentCache) Forget(id string) {
+	c.mu.Lock()
+	delete(c.entries, id)
+	c.mu.Unlock()
+}
+
+// Len returns how many documents have an entry, including entries whose
+// document has been reclaimed but not yet removed.
+func (c *DocumentCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// read returns the document the store holds when it can share it, and
+// otherwise the copy it returns, which callers of DocumentRef then share.
+func (c *DocumentCache) read(id string) (*models.Document, bool) {
+	if refs, ok := c.store.(DocumentRefStore); ok {
+		return refs.DocumentRef(id)
+	}
+	doc, ok := c.store.GetDocument(id)
+	if !ok {
+		return nil, false
+	}
+	return &doc, true
+}
+
+func (c *DocumentCache) put(id string, doc *models.Document) {
+	entry := weak.Make(doc)
+
+	c.mu.Lock()
+	c.entries[id] = entry
+	c.mu.Unlock()
+
+	// Remove the entry once the document is reclaimed, unless it has been
+	// replaced in the meantime.
+	runtime.AddCleanup(doc, func(id string) {
+		c.mu.Lock()
+		if c.entries[id] == entry {
+			delete(c.entries, id)
+		}
+		c.mu.Unlock()
+	}, id)
+}
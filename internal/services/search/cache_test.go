@@ -0,0 +1,130 @@
+package search
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/dariasmyr/fts-engine/internal/domain/models"
+)
+
+// countingStore returns a fresh copy on every read, as a store decoding
+// documents from disk does.
+type countingStore struct {
+	docs  map[string]models.Document
+	reads int
+}
+
+func (s *countingStore) GetDocument(id string) (models.Document, bool) {
+	s.reads++
+	doc, ok := s.docs[id]
+	return doc, ok
+}
+
+func newCountingStore() *countingStore {
+	return &countingStore{docs: map[string]models.Document{
+		"doc-1": {ID: "doc-1", DocumentBase: models.DocumentBase{Title: "Grand Hotel"}},
+	}}
+}
+
+// refStore hands out the documents it holds.
+type refStore struct {
+	countingStore
+	held map[string]*models.Document
+}
+
+func (s *refStore) DocumentRef(id string) (*models.Document, bool) {
+	s.reads++
+	doc, ok := s.held[id]
+	return doc, ok
+}
+
+func TestDocumentCacheHit(t *testing.T) {
+	store := newCountingStore()
+	cache := NewDocumentCache(store)
+
+	first, ok := cache.DocumentRef("doc-1")
+	if !ok || first.Title != "Grand Hotel" {
+		t.Fatalf("DocumentRef() = %+v, %v", first, ok)
+	}
+	// A result still showing the document keeps its entry alive.
+	runtime.GC()
+	second, ok := cache.DocumentRef("doc-1")
+	if !ok || second != first {
+		t.Fatalf("second DocumentRef() = %p, %v, want the cached %p", second, ok, first)
+	}
+	if store.reads != 1 {
+		t.Fatalf("store reads = %d, want 1", store.reads)
+	}
+	runtime.KeepAlive(first)
+}
+
+func TestDocumentCacheMissAfterGC(t *testing.T) {
+	store := newCountingStore()
+	cache := NewDocumentCache(store)
+
+	if doc, ok := cache.GetDocument("doc-1"); !ok || doc.Title != "Grand Hotel" {
+		t.Fatalf("GetDocument() = %+v, %v", doc, ok)
+	}
+	runtime.GC()
+
+	doc, ok := cache.GetDocument("doc-1")
+	if !ok || doc.Title != "Grand Hotel" {
+		t.Fatalf("GetDocument() = %+v, %v", doc, ok)
+	}
+	if store.reads != 2 {
+		t.Fatalf("store reads = %d, want 2 after the document was reclaimed", store.reads)
+	}
+}
+
+func TestDocumentCacheSharesStoreDocument(t *testing.T) {
+	held := &models.Document{ID: "doc-1", DocumentBase: models.DocumentBase{Title: "Grand Hotel"}}
+	store := &refStore{held: map[string]*models.Document{"doc-1": held}}
+	cache := NewDocumentCache(store)
+
+	for range 2 {
+		doc, ok := cache.DocumentRef("doc-1")
+		if !ok || doc != held {
+			t.Fatalf("DocumentRef() = %p, %v, want the store's %p", doc, ok, held)
+		}
+		runtime.GC()
+	}
+	if store.reads != 1 {
+		t.Fatalf("store reads = %d, want 1 while the store holds the document", store.reads)
+	}
+}
+
+func TestDocumentCacheDropsReclaimedEntries(t *testing.T) {
+	cache := NewDocumentCache(newCountingStore())
+	_, _ = cache.GetDocument("doc-1")
+
+	// Cleanups run on their own goroutine after the collection.
+	deadline := time.Now().Add(time.Second)
+	for cache.Len() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Len() = %d, want the reclaimed entry removed", cache.Len())
+		}
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDocumentCacheMissingAndForget(t *testing.T) {
+	store := newCountingStore()
+	cache := NewDocumentCache(store)
+
+	if _, ok := cache.GetDocument("doc-2"); ok {
+		t.Fatal("GetDocument(missing) ok = true")
+	}
+	if cache.Len() != 0 {
+		t.Fatalf("Len() = %d, want missing documents not cached", cache.Len())
+	}
+
+	old, _ := cache.DocumentRef("doc-1")
+	store.docs["doc-1"] = models.Document{ID: "doc-1", DocumentBase: models.DocumentBase{Title: "Grand Hotel Europa"}}
+	cache.Forget("doc-1")
+	if doc, _ := cache.GetDocument("doc-1"); doc.Title != "Grand Hotel Europa" {
+		t.Fatalf("GetDocument() after Forget = %+v, want the new version", doc)
+	}
+	runtime.KeepAlive(old)
+}
@@ -0,0 +1,138 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dariasmyr/fts-engine/internal/domain/models"
+)
+
+// EnsembleMember is one searcher of an Ensemble and the weight of its scores.
+type EnsembleMember struct {
+	Searcher Searcher
+	Weight   float64
+}
+
+// Ensemble runs every query on several searchers at once, for instance a
+// precise word index next to a trigram one, and merges their rankings.
+// Each member's scores are normalized to its best result first, so members
+// that score on different scales are comparable. A member that does not score
+// its results is ranked by position instead: its i-th result counts
+// 1/(i+1). A document found by several members gets the sum of its weighted
+// scores.
+type Ensemble struct {
+	members []EnsembleMember
+}
+
+var _ Searcher = (*Ensemble)(nil)
+
+// NewEnsemble returns an Ensemble of members.
+func NewEnsemble(members ...EnsembleMember) *Ensemble {
+	return &Ensemble{members: append([]EnsembleMember(nil), members...)}
+}
+
+// IndexDocument indexes content into every member, stopping at the first
+// error.
+func (e *Ensemble) IndexDocument(ctx context.Context, docID string, content string) error {
+	for i, member := range e.members {
+		if err := member.Searcher.IndexDocument(ctx, docID, content); err != nil {
+			return fmt.Errorf("search: ensemble member %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// SearchDocuments asks every member for its first offset+maxResults results
+// and returns that window of the merged ranking. TotalResultsCount counts the
+// distinct documents the members returned, so with a limit it is a lower
+// bound. Results keep the match counts of the first member that found them.
+func (e *Ensemble) SearchDocuments(ctx context.Context, query string, offset, maxResults int) (*models.SearchResult, error) {
+	start := time.Now()
+	offset = max(offset, 0)
+	window := 0
+	if maxResults > 0 {
+		window = offset + maxResults
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]*models.SearchResult, len(e.members))
+	errs := make([]error, len(e.members))
+	var wg sync.WaitGroup
+	for i, member := range e.members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if results[i], errs[i] = member.Searcher.SearchDocuments(ctx, query, 0, window); errs[i] != nil {
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("search: ensemble member %d: %w", i, err)
+		}
+	}
+
+	merged := make(map[string]*models.ResultData)
+	for i, result := range results {
+		weight := e.members[i].Weight
+		for rank, score := range normalizedScores(result.ResultData) {
+			data := result.ResultData[rank]
+			entry, ok := merged[data.ID]
+			if !ok {
+				entry = &data
+				entry.Score = 0
+				merged[data.ID] = entry
+			}
+			entry.Score += weight * score
+		}
+	}
+
+	ranked := make([]models.ResultData, 0, len(merged))
+	for _, data := range merged {
+		ranked = append(ranked, *data)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].ID < ranked[j].ID
+	})
+
+	total := len(ranked)
+	ranked = ranked[min(offset, len(ranked)):]
+	if maxResults > 0 && maxResults < len(ranked) {
+		ranked = ranked[:maxResults]
+	}
+
+	return &models.SearchResult{
+		ResultData:        ranked,
+		TotalResultsCount: total,
+		Timings:           map[string]time.Duration{"total": time.Since(start)},
+	}, nil
+}
+
+// normalizedScores scales the scores of results, in ranking order, to at most
+// 1. Results without positive scores are scored by rank.
+func normalizedScores(results []models.ResultData) []float64 {
+	best := 0.0
+	for _, data := range results {
+		best = max(best, data.Score)
+	}
+
+	scores := make([]float64, len(results))
+	for i, data := range results {
+		if best > 0 {
+			scores[i] = max(data.Score, 0) / best
+		} else {
+			scores[i] = 1 / float64(i+1)
+		}
+	}
+	return scores
+}
@@ -0,0 +1,95 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/dariasmyr/fts-engine/internal/domain/models"
+)
+
+type stubSearcher struct {
+	recordingEngine
+	results []models.ResultData
+	err     error
+}
+
+func (s *stubSearcher) SearchDocuments(_ context.Context, _ string, offset, maxResults int) (*models.SearchResult, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	results := s.results[min(offset, len(s.results)):]
+	if maxResults > 0 && maxResults < len(results) {
+		results = results[:maxResults]
+	}
+	return &models.SearchResult{ResultData: results, TotalResultsCount: len(s.results)}, nil
+}
+
+func newTestEnsemble() *Ensemble {
+	scored := &stubSearcher{results: []models.ResultData{
+		{ID: "doc-a", Score: 4, UniqueMatches: 2},
+		{ID: "doc-b", Score: 2, UniqueMatches: 1},
+	}}
+	unscored := &stubSearcher{results: []models.ResultData{
+		{ID: "doc-b", UniqueMatches: 3},
+		{ID: "doc-c", UniqueMatches: 1},
+	}}
+	return NewEnsemble(EnsembleMember{Searcher: scored, Weight: 2}, EnsembleMember{Searcher: unscored, Weight: 1})
+}
+
+func TestEnsembleMergesRankings(t *testing.T) {
+	res, err := newTestEnsemble().SearchDocuments(context.Background(), "hotel", 0, 0)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+
+	// doc-a: 2*4/4; doc-b: 2*2/4 + 1*1; doc-c: 1*1/2.
+	want := []models.ResultData{
+		{ID: "doc-a", Score: 2, UniqueMatches: 2},
+		{ID: "doc-b", Score: 2, UniqueMatches: 1},
+		{ID: "doc-c", Score: 0.5, UniqueMatches: 1},
+	}
+	if !slices.EqualFunc(res.ResultData, want, func(a, b models.ResultData) bool {
+		return a.ID == b.ID && a.Score == b.Score && a.UniqueMatches == b.UniqueMatches
+	}) {
+		t.Fatalf("results = %+v, want %+v", res.ResultData, want)
+	}
+	if res.TotalResultsCount != 3 {
+		t.Fatalf("TotalResultsCount = %d, want 3", res.TotalResultsCount)
+	}
+}
+
+func TestEnsemblePaginates(t *testing.T) {
+	res, err := newTestEnsemble().SearchDocuments(context.Background(), "hotel", 1, 1)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	if len(res.ResultData) != 1 || res.ResultData[0].ID != "doc-b" {
+		t.Fatalf("results = %+v, want doc-b", res.ResultData)
+	}
+}
+
+func TestEnsembleErrors(t *testing.T) {
+	broken := errors.New("index broken")
+	ensemble := NewEnsemble(
+		EnsembleMember{Searcher: &stubSearcher{}, Weight: 1},
+		EnsembleMember{Searcher: &stubSearcher{err: broken}, Weight: 1},
+	)
+
+	if _, err := ensemble.SearchDocuments(context.Background(), "hotel", 0, 10); !errors.Is(err, broken) {
+		t.Fatalf("SearchDocuments() error = %v, want %v", err, broken)
+	}
+}
+
+func TestEnsembleIndexesEveryMember(t *testing.T) {
+	first, second := &stubSearcher{}, &stubSearcher{}
+	ensemble := NewEnsemble(EnsembleMember{Searcher: first, Weight: 1}, EnsembleMember{Searcher: second, Weight: 1})
+
+	if err := ensemble.IndexDocument(context.Background(), "doc-1", "grand hotel"); err != nil {
+		t.Fatalf("IndexDocument() error = %v", err)
+	}
+	if len(first.content) != 1 || len(second.content) != 1 {
+		t.Fatalf("indexed %q and %q, want the document in both", first.content, second.content)
+	}
+}
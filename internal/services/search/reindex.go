@@ -0,0 +1,35 @@
+package search
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dariasmyr/fts-engine/internal/domain/models"
+)
+
+// IndexDocument indexes doc into engine: every field when the engine is a
+// FieldIndexer, the abstract otherwise.
+func IndexDocument(ctx context.Context, engine Searcher, doc models.Document) error {
+	if fielded, ok := engine.(FieldIndexer); ok {
+		return fielded.IndexFields(ctx, doc)
+	}
+
+	return engine.IndexDocument(ctx, doc.ID, doc.Abstract)
+}
+
+// Reindex indexes every document of src into dst, for instance after the
+// pipeline changed, and returns how many it indexed. It stops at the first
+// error, including ctx being done.
+func Reindex(ctx context.Context, src DocumentIterator, dst Searcher) (int, error) {
+	indexed := 0
+	for doc, err := range src.Documents(ctx) {
+		if err != nil {
+			return indexed, fmt.Errorf("search: reindex: %w", err)
+		}
+		if err := IndexDocument(ctx, dst, doc); err != nil {
+			return indexed, fmt.Errorf("search: reindex %q: %w", doc.ID, err)
+		}
+		indexed++
+	}
+	return indexed, nil
+}
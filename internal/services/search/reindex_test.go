@@ -0,0 +1,95 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+
+	"github.com/dariasmyr/fts-engine/internal/domain/models"
+)
+
+type sliceSource []models.Document
+
+func (s sliceSource) Documents(ctx context.Context) iter.Seq2[models.Document, error] {
+	return func(yield func(models.Document, error) bool) {
+		for _, doc := range s {
+			if err := ctx.Err(); err != nil {
+				yield(models.Document{}, err)
+				return
+			}
+			if !yield(doc, nil) {
+				return
+			}
+		}
+	}
+}
+
+type recordingEngine struct {
+	content []string
+	err     error
+}
+
+func (e *recordingEngine) IndexDocument(_ context.Context, docID, content string) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.content = append(e.content, docID+":"+content)
+	return nil
+}
+
+func (e *recordingEngine) SearchDocuments(context.Context, string, int, int) (*models.SearchResult, error) {
+	return &models.SearchResult{}, nil
+}
+
+type fieldEngine struct {
+	recordingEngine
+	titles []string
+}
+
+func (e *fieldEngine) IndexFields(_ context.Context, doc models.Document) error {
+	e.titles = append(e.titles, doc.Title)
+	return nil
+}
+
+var docs = sliceSource{
+	{ID: "doc-1", DocumentBase: models.DocumentBase{Title: "Grand Hotel", Abstract: "a hotel"}},
+	{ID: "doc-2", DocumentBase: models.DocumentBase{Title: "River Barge", Abstract: "a barge"}},
+}
+
+func TestReindex(t *testing.T) {
+	engine := &recordingEngine{}
+
+	n, err := Reindex(context.Background(), docs, engine)
+	if err != nil || n != 2 {
+		t.Fatalf("Reindex() = %d, %v; want 2, nil", n, err)
+	}
+	if want := []string{"doc-1:a hotel", "doc-2:a barge"}; !slices.Equal(engine.content, want) {
+		t.Fatalf("indexed %q, want %q", engine.content, want)
+	}
+}
+
+func TestReindexUsesFieldIndexer(t *testing.T) {
+	engine := &fieldEngine{}
+
+	if _, err := Reindex(context.Background(), docs, engine); err != nil {
+		t.Fatalf("Reindex() error = %v", err)
+	}
+	if len(engine.content) != 0 || !slices.Equal(engine.titles, []string{"Grand Hotel", "River Barge"}) {
+		t.Fatalf("content %q, titles %q; want only fields indexed", engine.content, engine.titles)
+	}
+}
+
+func TestReindexStops(t *testing.T) {
+	broken := errors.New("index broken")
+	if n, err := Reindex(context.Background(), docs, &recordingEngine{err: broken}); !errors.Is(err, broken) || n != 0 {
+		t.Fatalf("Reindex() = %d, %v; want 0 and the engine error", n, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if n, err := Reindex(ctx, docs, &recordingEngine{}); !errors.Is(err, context.Canceled) || n != 0 {
+		t.Fatalf("Reindex() = %d, %v; want 0 and context.Canceled", n, err)
+	}
+}
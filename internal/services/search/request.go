@@ -0,0 +1,27 @@
+package search
+
+import (
+	"errors"
+
+	pkgfts "github.com/dariasmyr/fts-engine/pkg/fts"
+	"github.com/dariasmyr/fts-engine/pkg/query"
+)
+
+// IsQueryError reports whether err is caused by the query rather than the
+// engine, so a frontend can answer it as a bad request.
+func IsQueryError(err error) bool {
+	return errors.Is(err, query.ErrSyntax) ||
+		errors.Is(err, pkgfts.ErrNegatedQuery) ||
+		errors.Is(err, pkgfts.ErrUnknownField) ||
+		errors.Is(err, pkgfts.ErrPhraseUnsupported)
+}
+
+// ClampLimit returns how many results a frontend asks the engine for. A
+// limit of 0 means maxResults rather than every result, and no request gets
+// more than maxResults.
+func ClampLimit(limit, maxResults int) int {
+	if limit == 0 || limit > maxResults {
+		return maxResults
+	}
+	return limit
+}
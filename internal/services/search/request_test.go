@@ -0,0 +1,42 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	pkgfts "github.com/dariasmyr/fts-engine/pkg/fts"
+	"github.com/dariasmyr/fts-engine/pkg/query"
+)
+
+func TestIsQueryError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: fmt.Errorf("fts: search: %w", query.ErrSyntax), want: true},
+		{err: pkgfts.ErrNegatedQuery, want: true},
+		{err: fmt.Errorf("fts: search: %w", pkgfts.ErrUnknownField), want: true},
+		{err: pkgfts.ErrPhraseUnsupported, want: true},
+		{err: errors.New("index broken"), want: false},
+	}
+	for _, tt := range tests {
+		if got := IsQueryError(tt.err); got != tt.want {
+			t.Fatalf("IsQueryError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestClampLimit(t *testing.T) {
+	tests := []struct{ limit, want int }{
+		{limit: 0, want: 10},
+		{limit: 3, want: 3},
+		{limit: 10, want: 10},
+		{limit: 1000, want: 10},
+	}
+	for _, tt := range tests {
+		if got := ClampLimit(tt.limit, 10); got != tt.want {
+			t.Fatalf("ClampLimit(%d, 10) = %d, want %d", tt.limit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Package search holds the engine contract shared by the frontends (CUI, HTTP
+// and gRPC APIs), so any backend that implements it can be plugged into all of them.
+package search
+
+import (
+	"context"
+	"errors"
+	"iter"
+
+	"github.com/dariasmyr/fts-engine/internal/domain/models"
+)
+
+type Searcher interface {
+	IndexDocument(
+		ctx context.Context,
+		docID string,
+		content string,
+	) error
+	SearchDocuments(
+		ctx context.Context,
+		query string,
+		offset int,
+		maxResults int,
+	) (*models.SearchResult, error)
+}
+
+// FuzzySearcher is implemented by engines that can match misspelled terms.
+type FuzzySearcher interface {
+	SearchFuzzy(
+		ctx context.Context,
+		query string,
+		maxDist int,
+		maxResults int,
+	) (*models.SearchResult, error)
+}
+
+// FieldIndexer is implemented by engines that index every field of a
+// document rather than only its content string.
+type FieldIndexer interface {
+	IndexFields(ctx context.Context, doc models.Document) error
+}
+
+// DocumentAdder is implemented by engines that can index and store a new
+// document while searches are being served.
+type DocumentAdder interface {
+	AddDocument(ctx context.Context, doc models.Document) error
+}
+
+// DocumentStore returns stored documents by ID.
+type DocumentStore interface {
+	GetDocument(id string) (models.Document, bool)
+}
+
+// DocumentRefStore is implemented by document stores that can hand out the
+// document they hold rather than a copy of it. Callers must not modify it.
+type DocumentRefStore interface {
+	DocumentRef(id string) (*models.Document, bool)
+}
+
+// DocumentWriter is implemented by document stores that documents can be
+// saved to and deleted from. Saving replaces the document with the same ID,
+// and deleting a missing document is not an error.
+type DocumentWriter interface {
+	SaveDocument(doc models.Document) error
+	DeleteDocument(id string) error
+}
+
+// DocumentIterator is implemented by document stores that can list every
+// stored document, so an index can be rebuilt without reading the dump again.
+// The sequence ends with ctx's error if ctx is done before it is exhausted.
+type DocumentIterator interface {
+	Documents(ctx context.Context) iter.Seq2[models.Document, error]
+}
+
+// ErrRebuildRunning is returned by Rebuild while an earlier rebuild has not
+// finished.
+var ErrRebuildRunning = errors.New("search: rebuild already running")
+
+// Rebuilder is implemented by engines that can rebuild their index from the
+// stored documents while serving searches, and swap it in once it is
+// complete. Rebuild returns how many documents the new index holds.
+type Rebuilder interface {
+	Rebuild(ctx context.Context) (int, error)
+}
+
+// StatsReporter is implemented by engines that can describe their index.
+type StatsReporter interface {
+	IndexStats() models.IndexStats
+}
+
+// TextAnalyzer is implemented by engines that can show the tokens and keys
+// they derive from a text, without searching.
+type TextAnalyzer interface {
+	AnalyzeText(text string) (models.TextAnalysis, error)
+}
+
+// StatsRefresher is implemented by StatsReporters that reuse an earlier
+// analysis of the index. RefreshIndexStats analyzes the index again.
+type StatsRefresher interface {
+	RefreshIndexStats() models.IndexStats
+}
@@ -0,0 +1,109 @@
+// Package memory is a document store that keeps every document in a map. It
+// is what the server uses today, and it lets tests run without touching disk.
+package memory
+
+import (
+	"context"
+	"iter"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/dariasmyr/fts-engine/internal/domain/models"
+	"github.com/dariasmyr/fts-engine/internal/services/search"
+)
+
+// Store holds documents by ID. It is safe for concurrent use.
+type Store struct {
+	mu sync.RWMutex
+	// documents are never modified once stored; saving a document replaces
+	// the pointer, so DocumentRef can share them.
+	documents map[string]*models.Document
+}
+
+var (
+	_ search.DocumentStore    = (*Store)(nil)
+	_ search.DocumentRefStore = (*Store)(nil)
+	_ search.DocumentWriter   = (*Store)(nil)
+	_ search.DocumentIterator = (*Store)(nil)
+)
+
+// New returns an empty store.
+func New() *Store {
+	return &Store{documents: make(map[string]*models.Document)}
+}
+
+func (s *Store) GetDocument(id string) (models.Document, bool) {
+	doc, ok := s.DocumentRef(id)
+	if !ok {
+		return models.Document{}, false
+	}
+	return *doc, true
+}
+
+// DocumentRef returns the stored document itself. Callers must not modify it.
+func (s *Store) DocumentRef(id string) (*models.Document, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	doc, ok := s.documents[id]
+	return doc, ok
+}
+
+// SaveDocument stores doc, replacing the document with the same ID.
+func (s *Store) SaveDocument(doc models.Document) error {
+	s.mu.Lock()
+	s.documents[doc.ID] = &doc
+	s.mu.Unlock()
+	return nil
+}
+
+// SaveDocuments stores docs under one lock.
+func (s *Store) SaveDocuments(docs []models.Document) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, doc := range docs {
+		s.documents[doc.ID] = &doc
+	}
+	return nil
+}
+
+// DeleteDocument removes the document with the given ID. Deleting a missing
+// document is not an error.
+func (s *Store) DeleteDocument(id string) error {
+	s.mu.Lock()
+	delete(s.documents, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// Len returns how many documents are stored.
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.documents)
+}
+
+// Documents yields the stored documents in ID order. It iterates a copy, so
+// the consumer may write to the store meanwhile.
+func (s *Store) Documents(ctx context.Context) iter.Seq2[models.Document, error] {
+	return func(yield func(models.Document, error) bool) {
+		s.mu.RLock()
+		docs := slices.Collect(maps.Values(s.documents))
+		s.mu.RUnlock()
+		slices.SortFunc(docs, func(a, b *models.Document) int { return strings.Compare(a.ID, b.ID) })
+
+		for _, doc := range docs {
+			if err := ctx.Err(); err != nil {
+				yield(models.Document{}, err)
+				return
+			}
+			if !yield(*doc, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dariasmyr/fts-engine/internal/domain/models"
+)
+
+func TestStore(t *testing.T) {
+	s := New()
+
+	if err := s.SaveDocuments([]models.Document{
+		{ID: "doc-2", DocumentBase: models.DocumentBase{Title: "River Barge"}},
+		{ID: "doc-1", DocumentBase: models.DocumentBase{Title: "Grand Hotel"}},
+	}); err != nil {
+		t.Fatalf("SaveDocuments() error = %v", err)
+	}
+	if err := s.SaveDocument(models.Document{ID: "doc-1", DocumentBase: models.DocumentBase{Title: "Grand Hotels"}}); err != nil {
+		t.Fatalf("SaveDocument() error = %v", err)
+	}
+
+	if doc, ok := s.GetDocument("doc-1"); !ok || doc.Title != "Grand Hotels" {
+		t.Fatalf("GetDocument(doc-1) = %+v, %v; want the replaced document", doc, ok)
+	}
+	if s.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", s.Len())
+	}
+
+	if err := s.DeleteDocument("doc-2"); err != nil {
+		t.Fatalf("DeleteDocument() error = %v", err)
+	}
+	if err := s.DeleteDocument("doc-2"); err != nil {
+		t.Fatalf("DeleteDocument(missing) error = %v", err)
+	}
+	if _, ok := s.GetDocument("doc-2"); ok || s.Len() != 1 {
+		t.Fatalf("doc-2 still stored, Len() = %d", s.Len())
+	}
+}
+
+func TestStoreDocuments(t *testing.T) {
+	s := New()
+	for _, id := range []string{"c", "a", "b"} {
+		s.SaveDocument(models.Document{ID: id})
+	}
+
+	var ids []string
+	for doc, err := range s.Documents(context.Background()) {
+		if err != nil {
+			t.Fatalf("Documents() error = %v", err)
+		}
+		// Writing while iterating must not deadlock.
+		s.SaveDocument(models.Document{ID: doc.ID + "-copy"})
+		ids = append(ids, doc.ID)
+	}
+	if want := "a b c"; len(ids) != 3 || ids[0]+" "+ids[1]+" "+ids[2] != want {
+		t.Fatalf("Documents() ids = %q, want %q", ids, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, err := range s.Documents(ctx) {
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Documents(canceled) error = %v, want context.Canceled", err)
+		}
+	}
+}
+
+func TestStoreDocumentRef(t *testing.T) {
+	s := New()
+	_ = s.SaveDocument(models.Document{ID: "doc-1", DocumentBase: models.DocumentBase{Title: "Grand Hotel"}})
+
+	first, ok := s.DocumentRef("doc-1")
+	if second, _ := s.DocumentRef("doc-1"); !ok || second != first {
+		t.Fatalf("DocumentRef() = %p, %p; want the stored document twice", first, second)
+	}
+
+	_ = s.SaveDocument(models.Document{ID: "doc-1", DocumentBase: models.DocumentBase{Title: "Grand Hotels"}})
+	if replaced, _ := s.DocumentRef("doc-1"); replaced == first || first.Title != "Grand Hotel" {
+		t.Fatalf("after SaveDocument: %+v, old %+v; want a new document and the old one unchanged", replaced, first)
+	}
+	if _, ok := s.DocumentRef("doc-2"); ok {
+		t.Fatal("DocumentRef(missing) ok = true")
+	}
+}
@@ -3,14 +3,36 @@ package utils
 import (
 	"regexp"
 	"strings"
+	"unicode"
 )
 
-func Clean(text string) string {
-	text = regexp.MustCompile(`\n+`).ReplaceAllString(text, " ")
+var unprintable = regexp.MustCompile(`[^\p{L}\p{N}\p{P}\p{Z}\s]`)
+
+// asciiPunct maps typographic quotes and dashes to their ASCII forms, so
+// "don’t" reads and indexes the same as "don't".
+var asciiPunct = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'",
+	"“", `"`, "”", `"`, "„", `"`, "‟", `"`,
+	"‐", "-", "‑", "-", "‒", "-", "–", "-", "—", "-", "―", "-",
+	"…", "...",
+)
 
-	text = regexp.MustCompile(`[^\p{L}\p{N}\p{P}\p{Z}]`).ReplaceAllString(text, "")
+// Clean normalizes text from a dump for display and indexing: line breaks and
+// runs of spaces become one space, runes that are not letters, numbers,
+// punctuation or spaces are dropped, typographic quotes and dashes become
+// ASCII, and words made only of punctuation, such as "--" or "...", are
+// removed.
+func Clean(text string) string {
+	text = unprintable.ReplaceAllString(text, "")
+	text = asciiPunct.Replace(text)
 
-	text = strings.TrimSpace(text)
+	words := strings.Fields(text)
+	kept := words[:0]
+	for _, word := range words {
+		if strings.IndexFunc(word, func(r rune) bool { return !unicode.IsPunct(r) }) >= 0 {
+			kept = append(kept, word)
+		}
+	}
 
-	return text
+	return strings.Join(kept, " ")
 }
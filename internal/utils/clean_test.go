@@ -0,0 +1,21 @@
+package utils
+
+import "testing"
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "Grand\n\nHotel ☺ built  in 1803.", want: "Grand Hotel built in 1803."},
+		{text: "don’t “stop” — ever…", want: `don't "stop" ever...`},
+		{text: "well-known – sort of -- maybe ... or „not“", want: `well-known sort of maybe or "not"`},
+		{text: " \n ... \n ", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := Clean(tt.text); got != tt.want {
+			t.Fatalf("Clean(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// progressWindow is how many reports the rolling rate averages over.
+const progressWindow = 6
+
+// Progress counts processed items during a bulk load. Record is a few atomic
+// increments, so the hot loop does not pay for reporting; the rate is computed
+// only when a report is taken. It is safe for concurrent use.
+type Progress struct {
+	total  int
+	done   atomic.Int64
+	failed atomic.Int64
+	busy   atomic.Int64 // nanoseconds spent processing
+	start  time.Time
+
+	mu      sync.Mutex
+	samples []progressSample
+}
+
+type progressSample struct {
+	at   time.Time
+	done int
+}
+
+// ProgressReport is a snapshot of a Progress. Done counts failed items too.
+// Rate is items per second over the last few reports, and Latency the mean
+// time one item took. Remaining is zero when the total is unknown.
+type ProgressReport struct {
+	Done      int
+	Failed    int
+	Total     int
+	Rate      float64
+	Latency   time.Duration
+	Elapsed   time.Duration
+	Remaining time.Duration
+}
+
+// NewProgress starts counting at start. total is the expected number of items,
+// or 0 when it is not known up front, as with a streamed dump.
+func NewProgress(total int, start time.Time) *Progress {
+	return &Progress{
+		total:   total,
+		start:   start,
+		samples: []progressSample{{at: start}},
+	}
+}
+
+// Record counts one item that took d to process. A non-nil err counts it as
+// failed.
+func (p *Progress) Record(d time.Duration, err error) {
+	p.busy.Add(int64(d))
+	if err != nil {
+		p.failed.Add(1)
+	}
+	p.done.Add(1)
+}
+
+// Report takes a snapshot at now and adds it to the rolling window.
+func (p *Progress) Report(now time.Time) ProgressReport {
+	done := int(p.done.Load())
+
+	p.mu.Lock()
+	oldest := p.samples[0]
+	p.samples = append(p.samples, progressSample{at: now, done: done})
+	if len(p.samples) > progressWindow {
+		p.samples = p.samples[1:]
+	}
+	p.mu.Unlock()
+
+	report := ProgressReport{
+		Done:    done,
+		Failed:  int(p.failed.Load()),
+		Total:   p.total,
+		Elapsed: now.Sub(p.start),
+	}
+	if done > 0 {
+		report.Latency = time.Duration(p.busy.Load() / int64(done))
+	}
+	if span := now.Sub(oldest.at); span > 0 {
+		report.Rate = float64(done-oldest.done) / span.Seconds()
+	}
+	if p.total > done && report.Rate > 0 {
+		report.Remaining = time.Duration(float64(p.total-done) / report.Rate * float64(time.Second))
+	}
+	return report
+}
+
+// Log writes a report every interval until ctx is done.
+func (p *Progress) Log(ctx context.Context, log *slog.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			p.Report(now).Log(log, "Indexing progress")
+		}
+	}
+}
+
+// Log writes the report as one info line with message msg.
+func (r ProgressReport) Log(log *slog.Logger, msg string) {
+	attrs := []any{
+		"indexed", r.Done,
+		"failed", r.Failed,
+		"docs_per_sec", int(r.Rate),
+		"latency", r.Latency,
+		"elapsed", r.Elapsed.Round(time.Second),
+	}
+	if r.Total > 0 {
+		attrs = append(attrs, "total", r.Total, "remaining", r.Remaining.Round(time.Second))
+	}
+	log.Info(msg, attrs...)
+}
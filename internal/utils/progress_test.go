@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func record(p *Progress, n int, d time.Duration, err error) {
+	for range n {
+		p.Record(d, err)
+	}
+}
+
+func TestProgressReport(t *testing.T) {
+	start := time.Unix(0, 0)
+	p := NewProgress(1000, start)
+
+	record(p, 100, time.Millisecond, nil)
+	got := p.Report(start.Add(time.Second))
+	if got.Done != 100 || got.Rate != 100 || got.Elapsed != time.Second {
+		t.Fatalf("report = %+v", got)
+	}
+	if got.Remaining != 9*time.Second {
+		t.Fatalf("remaining = %v, want 9s", got.Remaining)
+	}
+}
+
+func TestProgressFailuresAndLatency(t *testing.T) {
+	start := time.Unix(0, 0)
+	p := NewProgress(0, start)
+
+	record(p, 3, time.Millisecond, nil)
+	record(p, 1, 5*time.Millisecond, errors.New("index broken"))
+	got := p.Report(start.Add(time.Second))
+	if got.Done != 4 || got.Failed != 1 {
+		t.Fatalf("done %d failed %d, want 4 and 1", got.Done, got.Failed)
+	}
+	if got.Latency != 2*time.Millisecond {
+		t.Fatalf("latency = %v, want 2ms", got.Latency)
+	}
+}
+
+func TestProgressRateIsRolling(t *testing.T) {
+	start := time.Unix(0, 0)
+	p := NewProgress(0, start)
+
+	// A fast start must not keep inflating the rate once the load slows down.
+	record(p, 10000, 0, nil)
+	p.Report(start.Add(time.Second))
+	for i := 2; i <= 2*progressWindow; i++ {
+		record(p, 10, 0, nil)
+		got := p.Report(start.Add(time.Duration(i) * time.Second))
+		if i == 2*progressWindow && got.Rate != 10 {
+			t.Fatalf("rate = %v, want 10", got.Rate)
+		}
+	}
+}
+
+func TestProgressUnknownTotal(t *testing.T) {
+	start := time.Unix(0, 0)
+	p := NewProgress(0, start)
+
+	record(p, 5, 0, nil)
+	if got := p.Report(start.Add(time.Second)); got.Remaining != 0 || got.Total != 0 {
+		t.Fatalf("report = %+v, want no estimate", got)
+	}
+}
@@ -0,0 +1,50 @@
+package fts
+
+import "fmt"
+
+// AnalyzedToken is a token the pipeline made of a text, with the index keys
+// the key generator derives from it.
+type AnalyzedToken struct {
+	Token string
+	Keys  []string
+}
+
+// TextAnalysis is what indexing or searching a text reads from it.
+type TextAnalysis struct {
+	// Tokens are the words of the text after the pipeline, in order.
+	Tokens []AnalyzedToken
+	// PhraseTokens are the words of the text in a quoted phrase, set only
+	// for a service built WithPhrasePipeline.
+	PhraseTokens []AnalyzedToken
+}
+
+// AnalyzeText runs text through the pipeline and the key generator the way
+// IndexDocument and Search do, without reading the index, so it shows why a
+// word matches or not: with the English pipeline and trigram keys "Hotels"
+// becomes "hotel" with keys "hot", "ote" and "tel". Keys are shown without
+// their field.
+func (s *Service) AnalyzeText(text string) (TextAnalysis, error) {
+	var analysis TextAnalysis
+	var err error
+	if analysis.Tokens, err = s.analyzeTokens(s.pipeline.Process(text)); err != nil {
+		return TextAnalysis{}, err
+	}
+	if s.phrasePipeline != nil {
+		if analysis.PhraseTokens, err = s.analyzeTokens(s.phrasePipeline.Process(text)); err != nil {
+			return TextAnalysis{}, err
+		}
+	}
+	return analysis, nil
+}
+
+func (s *Service) analyzeTokens(tokens []string) ([]AnalyzedToken, error) {
+	analyzed := make([]AnalyzedToken, 0, len(tokens))
+	for _, token := range tokens {
+		keys, err := s.keyGen(token)
+		if err != nil {
+			return nil, fmt.Errorf("fts: analyze text: keygen: %w", err)
+		}
+		analyzed = append(analyzed, AnalyzedToken{Token: token, Keys: keys})
+	}
+	return analyzed, nil
+}
@@ -0,0 +1,64 @@
+package fts
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dariasmyr/fts-engine/pkg/keygen"
+	"github.com/dariasmyr/fts-engine/pkg/textproc"
+)
+
+func TestAnalyzeText(t *testing.T) {
+	svc := New(newPostingIndex(), keygen.Trigram, WithPipeline(textproc.DefaultEnglishPipeline()))
+
+	analysis, err := svc.AnalyzeText("The Hotels of Paris were full in 1990")
+	if err != nil {
+		t.Fatalf("AnalyzeText() error = %v", err)
+	}
+	want := []AnalyzedToken{
+		{Token: "hotel", Keys: []string{"hot", "ote", "tel"}},
+		{Token: "pari", Keys: []string{"par", "ari"}},
+		{Token: "full", Keys: []string{"ful", "ull"}},
+		{Token: "1990", Keys: []string{"199", "990"}},
+	}
+	if !reflect.DeepEqual(analysis.Tokens, want) {
+		t.Fatalf("Tokens = %+v, want %+v", analysis.Tokens, want)
+	}
+	if analysis.PhraseTokens != nil {
+		t.Fatalf("PhraseTokens = %+v, want none without a phrase pipeline", analysis.PhraseTokens)
+	}
+}
+
+func TestAnalyzeTextPhrasePipeline(t *testing.T) {
+	pipeline := textproc.DefaultEnglishPipeline()
+	svc := New(newPostingIndex(), WordKeys,
+		WithPipeline(pipeline),
+		WithPhrasePipeline(pipeline.Without(textproc.StageStopWords|textproc.StageStem)),
+	)
+
+	analysis, err := svc.AnalyzeText("To be hotels")
+	if err != nil {
+		t.Fatalf("AnalyzeText() error = %v", err)
+	}
+	if want := []AnalyzedToken{{Token: "hotel", Keys: []string{"hotel"}}}; !reflect.DeepEqual(analysis.Tokens, want) {
+		t.Fatalf("Tokens = %+v, want %+v", analysis.Tokens, want)
+	}
+	want := []AnalyzedToken{
+		{Token: "to", Keys: []string{"to"}},
+		{Token: "be", Keys: []string{"be"}},
+		{Token: "hotels", Keys: []string{"hotels"}},
+	}
+	if !reflect.DeepEqual(analysis.PhraseTokens, want) {
+		t.Fatalf("PhraseTokens = %+v, want %+v", analysis.PhraseTokens, want)
+	}
+}
+
+func TestAnalyzeTextKeygenError(t *testing.T) {
+	errKeys := errors.New("no keys")
+	svc := New(newPostingIndex(), func(string) ([]string, error) { return nil, errKeys })
+
+	if _, err := svc.AnalyzeText("hotel"); !errors.Is(err, errKeys) {
+		t.Fatalf("AnalyzeText() error = %v, want the keygen error", err)
+	}
+}
@@ -1,36 +1,14 @@
 package fts
 
-import (
-	"strings"
-	"unicode"
-)
+import "github.com/dariasmyr/fts-engine/pkg/textproc"
 
+// defaultPipeline lowercases the tokens of textproc.AlnumTokenizer, so a
+// service without WithPipeline splits words exactly as the textproc
+// pipelines do.
 type defaultPipeline struct{}
 
-func (defaultPipeline) Process(text string) []string {
-	if text == "" {
-		return nil
-	}
-
-	tokens := make([]string, 0, 16)
-	var b strings.Builder
-
-	flush := func() {
-		if b.Len() == 0 {
-			return
-		}
-		tokens = append(tokens, strings.ToLower(b.String()))
-		b.Reset()
-	}
+var defaultTokens = textproc.NewPipeline(textproc.AlnumTokenizer{}, textproc.LowercaseFilter{})
 
-	for _, r := range text {
-		if unicode.IsLetter(r) || unicode.IsDigit(r) {
-			b.WriteRune(r)
-			continue
-		}
-		flush()
-	}
-	flush()
-
-	return tokens
+func (defaultPipeline) Process(text string) []string {
+	return defaultTokens.Process(text)
 }
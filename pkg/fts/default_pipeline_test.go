@@ -15,3 +15,36 @@ func TestDefaultPipelineProcess(t *testing.T) {
 		t.Fatalf("tokens = %#v, want %#v", got, want)
 	}
 }
+
+func TestDefaultPipelineProcessKeepsAccentsAndMarks(t *testing.T) {
+	pipe := defaultPipeline{}
+
+	got := pipe.Process("Café naïve cafe\u0301 Ωmega")
+	want := []string{"café", "naïve", "cafe\u0301", "ωmega"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("tokens = %#v, want %#v", got, want)
+	}
+}
+
+func TestDefaultPipelineProcessTypographicPunctuation(t *testing.T) {
+	pipe := defaultPipeline{}
+
+	curly := pipe.Process("Don’t — stop… --")
+	ascii := pipe.Process("Don't - stop... --")
+	want := []string{"don", "t", "stop"}
+
+	if !reflect.DeepEqual(curly, want) || !reflect.DeepEqual(ascii, want) {
+		t.Fatalf("tokens = %#v and %#v, want %#v for both", curly, ascii, want)
+	}
+}
+
+func BenchmarkDefaultPipelineProcess(b *testing.B) {
+	text := "The Grand Hotel is a luxury hotel on the waterfront of Stockholm, Sweden. " +
+		"It opened in 1874 and has hosted the Nobel laureates every December since 1901."
+	b.ReportAllocs()
+	b.SetBytes(int64(len(text)))
+	for b.Loop() {
+		defaultPipeline{}.Process(text)
+	}
+}
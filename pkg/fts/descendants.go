@@ -0,0 +1,31 @@
+package fts
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrDescendantsUnsupported = errors.New("fts: index does not support descendant search")
+
+// descendantLookup reads, for SearchOptions.Descendants, the merged postings
+// of every key under the looked-up one. The Filter holds whole keys, so it
+// cannot rule a prefix out and is not consulted. Query evaluation is serial,
+// so the cache needs no lock.
+func descendantLookup(ctx context.Context, searcher DescendantSearcher) postingLookup {
+	cache := make(map[string][]DocRef)
+	return func(key string) ([]DocRef, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if docs, ok := cache[key]; ok {
+			return docs, nil
+		}
+
+		docs, err := searcher.SearchWithDescendants(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		cache[key] = docs
+		return docs, nil
+	}
+}
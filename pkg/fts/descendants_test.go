@@ -0,0 +1,62 @@
+package fts
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSearchDescendants(t *testing.T) {
+	ctx := context.Background()
+	svc := New(newPostingIndex(), WordKeys)
+	_ = svc.IndexDocument(ctx, "hotel", "grand hotel")
+	_ = svc.IndexDocument(ctx, "hotelier", "the hotelier and her hotels")
+	_ = svc.IndexDocument(ctx, "hot", "hot dog")
+
+	res, err := svc.Search(ctx, "hotel", SearchOptions{})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if got := resultIDs(res); !slices.Equal(got, []DocID{"hotel"}) {
+		t.Fatalf("exact: results = %v, want [hotel]", got)
+	}
+
+	res, err = svc.Search(ctx, "hotel", SearchOptions{Descendants: true})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if got := resultIDs(res); len(got) != 2 || !slices.Contains(got, "hotelier") || !slices.Contains(got, "hotel") {
+		t.Fatalf("descendants: results = %v, want hotel and hotelier", got)
+	}
+	for _, r := range res.Results {
+		// hotelier and hotels merge into one match of the query key.
+		if r.ID == "hotelier" && (r.UniqueMatches != 1 || r.TotalMatches != 2) {
+			t.Fatalf("hotelier = %+v, want 1 unique and 2 total matches", r)
+		}
+	}
+}
+
+func TestSearchDescendantsKeepsPhrasesExact(t *testing.T) {
+	ctx := context.Background()
+	svc := New(newPostingIndex(), WordKeys, WithPositions())
+	_ = svc.IndexDocument(ctx, "exact", "grand hotel")
+	_ = svc.IndexDocument(ctx, "longer", "grand hotelier")
+
+	res, err := svc.Search(ctx, `"grand hotel"`, SearchOptions{Descendants: true})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if got := resultIDs(res); !slices.Equal(got, []DocID{"exact"}) {
+		t.Fatalf("results = %v, want [exact]", got)
+	}
+}
+
+func TestSearchDescendantsUnsupportedIndex(t *testing.T) {
+	svc := New(newMemoryIndex(), WordKeys)
+
+	_, err := svc.Search(context.Background(), "hotel", SearchOptions{Descendants: true})
+	if !errors.Is(err, ErrDescendantsUnsupported) {
+		t.Fatalf("Search() error = %v, want ErrDescendantsUnsupported", err)
+	}
+}
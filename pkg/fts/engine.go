@@ -2,24 +2,67 @@ package fts
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"hash/maphash"
 	"io"
+	"log/slog"
 	"sort"
+	"sync"
 	"time"
 )
 
+var ErrDeleteUnsupported = errors.New("fts: index does not support document deletion")
+
 type Service struct {
 	index    Index
 	keyGen   KeyGenerator
 	pipeline Pipeline
 	filter   Filter
+	scorer   Scorer
+	log      *slog.Logger
+
+	positions    bool
+	fields       []string
+	fieldWeights FieldWeights
+	workers      int
+
+	mu          sync.RWMutex
+	docLengths  map[DocID]int
+	totalLength int
+	// docKeys is the reverse index kept WithReverseIndex; nil otherwise.
+	docKeys map[DocID][]string
+	// docWords holds the tokens of every field of every document
+	// WithExactWords; nil otherwise.
+	docWords map[DocID]fieldWords
+
+	// docLocks serialize indexing and deleting of one document, so that
+	// replacing it is a single step. IDs hash onto a fixed set of stripes.
+	docLocks [docLockStripes]sync.Mutex
+	docSeed  maphash.Seed
+
+	// results is the cache kept WithResultCache; nil otherwise.
+	results *resultCache
+	// phrasePipeline processes phrases WithPhrasePipeline; nil otherwise.
+	phrasePipeline Pipeline
+	// restored is set WithRestoredIndex: the index may hold documents that
+	// docLengths does not know.
+	restored bool
 }
 
+const docLockStripes = 64
+
 func New(index Index, keyGen KeyGenerator, opts ...Option) *Service {
 	s := &Service{
-		index:    index,
-		keyGen:   keyGen,
-		pipeline: defaultPipeline{},
+		index:        index,
+		keyGen:       keyGen,
+		pipeline:     defaultPipeline{},
+		fields:       []string{""},
+		fieldWeights: DefaultFieldWeights(),
+		workers:      defaultSearchWorkers(),
+		docLengths:   make(map[DocID]int),
+		docSeed:      maphash.MakeSeed(),
+		log:          slog.New(slog.DiscardHandler),
 	}
 
 	for _, opt := range opts {
@@ -45,95 +88,436 @@ func NewFromReader(r io.Reader, loader IndexLoader, keyGen KeyGenerator, opts ..
 		return nil, fmt.Errorf("fts: load index: %w", err)
 	}
 
-	return New(index, keyGen, opts...), nil
+	s := New(index, keyGen, opts...)
+	s.restored = true
+	return s, nil
+}
+
+// WithRestoredIndex tells the service that its index already holds documents
+// it did not index itself, as one loaded from a snapshot does. Indexing such
+// an ID then first deletes its postings when the index implements Deleter,
+// so it replaces the document rather than adding to it. That delete walks
+// the whole index, once per ID. NewFromReader sets it.
+func WithRestoredIndex() Option {
+	return func(s *Service) {
+		s.restored = true
+	}
 }
 
+// IndexDocument indexes content as docID. Indexing an ID again replaces the
+// earlier content, which needs an index implementing Deleter.
 func (s *Service) IndexDocument(ctx context.Context, docID DocID, content string) error {
+	defer s.lockDoc(docID)()
+	defer s.invalidateResults()
+
+	if err := s.dropIndexed(ctx, docID); err != nil {
+		return err
+	}
+	return s.indexField(ctx, docID, s.fields[0], content)
+}
+
+// lockDoc locks the stripe of docID and returns its unlock func.
+func (s *Service) lockDoc(docID DocID) func() {
+	m := &s.docLocks[maphash.String(s.docSeed, string(docID))%docLockStripes]
+	m.Lock()
+	return m.Unlock
+}
+
+// dropIndexed deletes the postings of docID if it was indexed before, so
+// that its keys are not counted twice. The caller holds lockDoc(docID).
+func (s *Service) dropIndexed(ctx context.Context, docID DocID) error {
+	s.mu.RLock()
+	_, indexed := s.docLengths[docID]
+	s.mu.RUnlock()
+	if !indexed {
+		return s.dropRestored(ctx, docID)
+	}
+
+	if err := s.deleteDocument(ctx, docID); err != nil {
+		return fmt.Errorf("fts: reindex document %q: %w", docID, err)
+	}
+	return nil
+}
+
+// dropRestored deletes the postings a restored index may hold for docID,
+// which the service has not indexed since it was built. An index without
+// Deleter keeps them, as indexing into it never replaces anything.
+func (s *Service) dropRestored(ctx context.Context, docID DocID) error {
+	deleter, ok := s.index.(Deleter)
+	if !s.restored || !ok {
+		return nil
+	}
 	if err := ctx.Err(); err != nil {
 		return err
 	}
+	if err := deleter.Delete(docID); err != nil {
+		return fmt.Errorf("fts: reindex document %q: %w", docID, err)
+	}
+	return nil
+}
 
-	tokens := s.pipeline.Process(content)
-	for _, token := range tokens {
-		if err := ctx.Err(); err != nil {
-			return err
-		}
+// indexField indexes content under field. Token positions restart at zero for
+// every field, so phrases never span two fields.
+func (s *Service) indexField(ctx context.Context, docID DocID, field, content string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var positional PositionalIndex
+	if s.positions {
+		positional, _ = s.index.(PositionalIndex)
+	}
 
+	var keySet map[string]struct{}
+	if s.docKeys != nil {
+		keySet = make(map[string]struct{})
+	}
+	var wordSet map[string]struct{}
+	if s.docWords != nil {
+		wordSet = make(map[string]struct{})
+	}
+
+	insert := func(token, prefix string, pos int) error {
 		keys, err := s.keyGen(token)
 		if err != nil {
 			return fmt.Errorf("fts: index document: keygen: %w", err)
 		}
 
 		for _, key := range keys {
+			key = prefix + fieldKey(field, key)
 			if s.filter != nil {
 				if ok := s.filter.Add([]byte(key)); !ok {
 					return fmt.Errorf("fts: index document: filter add failed for key %q", key)
 				}
 			}
-			if err := s.index.Insert(key, docID); err != nil {
+			if positional != nil {
+				err = positional.InsertAt(key, docID, uint32(pos))
+			} else {
+				err = s.index.Insert(key, docID)
+			}
+			if err != nil {
 				return fmt.Errorf("fts: index document: insert: %w", err)
 			}
+			if keySet != nil {
+				keySet[key] = struct{}{}
+			}
+		}
+		return nil
+	}
+
+	tokens := s.pipeline.Process(content)
+	for pos, token := range tokens {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if wordSet != nil {
+			wordSet[token] = struct{}{}
+		}
+		if err := insert(token, "", pos); err != nil {
+			return err
+		}
+	}
+
+	if s.phrasePipeline != nil && positional != nil {
+		for pos, token := range s.phrasePipeline.Process(content) {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := insert(token, phraseMarker, pos); err != nil {
+				return err
+			}
+		}
+	}
+
+	s.mu.Lock()
+	s.docLengths[docID] += len(tokens)
+	s.totalLength += len(tokens)
+	if keySet != nil {
+		keys := s.docKeys[docID]
+		for key := range keySet {
+			keys = append(keys, key)
+		}
+		s.docKeys[docID] = keys
+	}
+	if wordSet != nil {
+		fields := s.docWords[docID]
+		if fields == nil {
+			fields = make(fieldWords)
+			s.docWords[docID] = fields
+		}
+		words := fields[field]
+		if words == nil {
+			words = make(map[string]struct{}, len(wordSet))
+			fields[field] = words
 		}
+		for word := range wordSet {
+			words[word] = struct{}{}
+		}
+	}
+	s.mu.Unlock()
+
+	return nil
+}
+
+// DeleteDocument removes docID from the index. With WithReverseIndex and a
+// KeyDeleter index only the document's own keys are touched; otherwise the
+// index walks all of its keys. Filters are append-only, so keys of a deleted
+// document may still pass the filter and simply miss in the index.
+func (s *Service) DeleteDocument(ctx context.Context, docID DocID) error {
+	defer s.lockDoc(docID)()
+	defer s.invalidateResults()
+
+	return s.deleteDocument(ctx, docID)
+}
+
+func (s *Service) deleteDocument(ctx context.Context, docID DocID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	keys, tracked := s.docKeys[docID]
+	s.mu.RUnlock()
+
+	var err error
+	if keyDeleter, ok := s.index.(KeyDeleter); ok && tracked {
+		err = keyDeleter.DeleteKeys(docID, keys)
+	} else if deleter, ok := s.index.(Deleter); ok {
+		err = deleter.Delete(docID)
+	} else {
+		return ErrDeleteUnsupported
 	}
+	if err != nil {
+		return fmt.Errorf("fts: delete document: %w", err)
+	}
+
+	s.mu.Lock()
+	s.totalLength -= s.docLengths[docID]
+	delete(s.docLengths, docID)
+	delete(s.docKeys, docID)
+	delete(s.docWords, docID)
+	s.mu.Unlock()
 
 	return nil
 }
 
+// SearchDocuments returns the first maxResults results; maxResults <= 0 returns all.
 func (s *Service) SearchDocuments(ctx context.Context, query string, maxResults int) (*SearchResult, error) {
+	return s.Search(ctx, query, SearchOptions{Limit: maxResults})
+}
+
+// Search runs query and returns the results window described by opts.
+// Results are ordered with the document ID as the final tiebreaker, so pages
+// of the same query do not overlap. A search checks ctx before every index
+// lookup. When ctx ends during the search, it returns the documents matched by
+// the postings read so far, marked Truncated; a query with a NOT returns the
+// error instead, since postings its NOT did not read could exclude documents.
+// A search started with ctx already done returns ctx.Err(). WithResultCache a
+// repeated search is answered from the cache, with only the "total" timing;
+// truncated results are not cached.
+func (s *Service) Search(ctx context.Context, query string, opts SearchOptions) (*SearchResult, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
+	if s.results == nil {
+		return s.search(ctx, query, opts)
+	}
+
+	start := time.Now()
+	key := newResultKey(query, opts)
+	result, gen, ok := s.results.get(key)
+	if ok {
+		result.Timings = map[string]time.Duration{"total": time.Since(start)}
+		return result, nil
+	}
+
+	result, err := s.search(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Truncated {
+		s.results.put(key, gen, result)
+	}
+	return result, nil
+}
 
+func (s *Service) search(ctx context.Context, query string, opts SearchOptions) (*SearchResult, error) {
 	start := time.Now()
-	timings := make(map[string]string, 3)
+	timings := make(map[string]time.Duration, 3)
 
 	preStart := time.Now()
-	tokens := s.pipeline.Process(query)
-	timings["preprocess"] = formatDuration(time.Since(preStart))
+	root, err := parseQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("fts: search: %w", err)
+	}
+	timings["preprocess"] = time.Since(preStart)
+
+	if opts.Exact && s.docWords == nil {
+		return nil, ErrExactUnsupported
+	}
 
 	searchStart := time.Now()
-	uniqueMatches := make(map[DocID]int)
-	totalMatches := make(map[DocID]int)
+	matches := make(map[DocID]*DocMatch)
+	cache := s.newPostingCache(ctx)
+	lookup := cache.lookup
+
+	// With Descendants the query terms read prefix postings, and only the
+	// phrases go through the cache.
+	lookups := queryLookup{terms: lookup, phrases: lookup}
+	if opts.Descendants {
+		searcher, ok := s.index.(DescendantSearcher)
+		if !ok {
+			return nil, ErrDescendantsUnsupported
+		}
+		lookups.terms = descendantLookup(ctx, searcher)
+	}
 
-	for _, token := range tokens {
-		if err := ctx.Err(); err != nil {
-			return nil, err
+	keys, err := s.queryKeys(root, opts.Descendants)
+	if err != nil {
+		return nil, fmt.Errorf("fts: search: keygen: %w", err)
+	}
+	mode := matchMode{allKeys: opts.RequireAllKeys, exact: opts.Exact}
+	selected := docSet{neutral: true}
+	if err = s.prefetch(ctx, keys, cache); err != nil {
+		err = fmt.Errorf("fts: search: index search: %w", err)
+	} else if root != nil {
+		if selected, err = s.evalQuery(root, lookups, matches, false, mode); err != nil {
+			err = fmt.Errorf("fts: search: %w", err)
 		}
+	}
 
-		keys, err := s.keyGen(token)
-		if err != nil {
-			return nil, fmt.Errorf("fts: search: keygen: %w", err)
+	// Once ctx ends, the query is evaluated again over the postings read so
+	// far. A key not read yet matches nothing, which only drops documents
+	// unless a NOT depends on it, so a query with a NOT fails instead.
+	var truncated bool
+	if err != nil {
+		if root == nil || hasNegation(root) || ctx.Err() == nil || !errors.Is(err, ctx.Err()) {
+			return nil, err
 		}
+		truncated = true
+		matches = make(map[DocID]*DocMatch)
+		read := queryLookup{terms: cache.read, phrases: cache.read}
+		if selected, err = s.evalQuery(root, read, matches, false, mode); err != nil {
+			return nil, fmt.Errorf("fts: search: %w", err)
+		}
+	}
+	if selected.complement {
+		return nil, ErrNegatedQuery
+	}
 
-		for _, key := range keys {
-			if s.filter != nil && !s.filter.Contains([]byte(key)) {
-				continue
+	if !selected.neutral {
+		for id := range matches {
+			if _, ok := selected.docs[id]; ok == selected.complement {
+				delete(matches, id)
 			}
+		}
+	}
 
-			docs, err := s.index.Search(key)
-			if err != nil {
-				return nil, fmt.Errorf("fts: search: index search: %w", err)
-			}
+	timings["search_tokens"] = time.Since(searchStart)
 
-			for _, doc := range docs {
-				uniqueMatches[doc.ID]++
-				totalMatches[doc.ID] += int(doc.Count)
-			}
+	weights := opts.FieldWeights
+	if weights == nil {
+		weights = s.fieldWeights
+	}
+	results := s.rank(matches, weights)
+
+	var suggestion string
+	if len(results) < opts.SuggestBelow {
+		suggestStart := time.Now()
+		suggestion, err = s.suggest(ctx, root, lookup)
+		switch {
+		case err == nil:
+			timings["suggest"] = time.Since(suggestStart)
+		case ctx.Err() != nil && errors.Is(err, ctx.Err()):
+			// The results stand without the suggestion ctx left no time for.
+			suggestion, truncated = "", true
+		default:
+			return nil, fmt.Errorf("fts: search: suggest: %w", err)
 		}
 	}
 
-	timings["search_tokens"] = formatDuration(time.Since(searchStart))
+	timings["total"] = time.Since(start)
+	if s.log.Enabled(ctx, slog.LevelDebug) {
+		s.log.DebugContext(ctx, "Search",
+			"query", query,
+			"tokens", len(s.queryTokens(root, true)),
+			"results", len(results),
+			"duration", timings["total"],
+		)
+	}
+
+	return &SearchResult{
+		Results:           paginate(results, opts.Offset, opts.Limit),
+		TotalResultsCount: len(results),
+		Timings:           timings,
+		Suggestion:        suggestion,
+		Truncated:         truncated,
+	}, nil
+}
+
+// paginate returns results[offset:offset+limit], clamped to the slice. A limit
+// <= 0 means no limit.
+func paginate(results []Result, offset, limit int) []Result {
+	offset = min(max(offset, 0), len(results))
+	results = results[offset:]
+	if limit > 0 && limit < len(results) {
+		results = results[:limit]
+	}
+	return results
+}
+
+func (s *Service) hasPositions() bool {
+	if !s.positions {
+		return false
+	}
+	_, ok := s.index.(PositionalIndex)
+	return ok
+}
+
+// keepTerms reports whether matches record their TermMatch details, which
+// are needed by a Scorer and by field weighting.
+func (s *Service) keepTerms() bool {
+	return s.scorer != nil || s.weighted()
+}
 
-	results := make([]Result, 0, len(uniqueMatches))
-	for id, unique := range uniqueMatches {
-		results = append(results, Result{
-			ID:            id,
-			UniqueMatches: unique,
-			TotalMatches:  totalMatches[id],
-		})
+func (s *Service) rank(matches map[DocID]*DocMatch, weights FieldWeights) []Result {
+	results := make([]Result, 0, len(matches))
+
+	if !s.keepTerms() {
+		for _, match := range matches {
+			results = append(results, Result{
+				ID:            match.ID,
+				UniqueMatches: match.UniqueMatches,
+				TotalMatches:  match.TotalMatches,
+				MatchedTerms:  match.tokens,
+			})
+		}
+	} else {
+		s.mu.RLock()
+		corpus := s.corpusStatsLocked()
+		for _, match := range matches {
+			match.DocLength = s.docLengths[match.ID]
+			var score float64
+			if s.weighted() {
+				score = s.fieldScore(corpus, *match, weights)
+			} else {
+				score = s.scorer.Score(corpus, *match)
+			}
+			results = append(results, Result{
+				ID:            match.ID,
+				UniqueMatches: match.UniqueMatches,
+				TotalMatches:  match.TotalMatches,
+				Score:         score,
+				MatchedTerms:  match.tokens,
+			})
+		}
+		s.mu.RUnlock()
 	}
 
 	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
 		if results[i].UniqueMatches != results[j].UniqueMatches {
 			return results[i].UniqueMatches > results[j].UniqueMatches
 		}
@@ -143,18 +527,34 @@ func (s *Service) SearchDocuments(ctx context.Context, query string, maxResults
 		return results[i].ID < results[j].ID
 	})
 
-	totalFound := len(results)
-	if maxResults <= 0 || maxResults > totalFound {
-		maxResults = totalFound
-	}
+	return results
+}
 
-	timings["total"] = formatDuration(time.Since(start))
+// DocLength returns how many tokens docID was indexed with, summed over its
+// fields. ok is false for documents the service has not indexed, including
+// those of an index restored from a snapshot.
+func (s *Service) DocLength(docID DocID) (length int, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	return &SearchResult{
-		Results:           results[:maxResults],
-		TotalResultsCount: totalFound,
-		Timings:           timings,
-	}, nil
+	length, ok = s.docLengths[docID]
+	return length, ok
+}
+
+// CorpusStats returns the document count and average length that scorers see.
+func (s *Service) CorpusStats() CorpusStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.corpusStatsLocked()
+}
+
+func (s *Service) corpusStatsLocked() CorpusStats {
+	stats := CorpusStats{TotalDocs: len(s.docLengths)}
+	if stats.TotalDocs > 0 {
+		stats.AvgDocLength = float64(s.totalLength) / float64(stats.TotalDocs)
+	}
+	return stats
 }
 
 func (s *Service) Analyze() (Stats, bool) {
@@ -189,10 +589,3 @@ func (s *Service) BuildFilter() error {
 
 	return nil
 }
-
-func formatDuration(d time.Duration) string {
-	if d < time.Millisecond {
-		return fmt.Sprintf("%dus", d.Microseconds())
-	}
-	return fmt.Sprintf("%dms", d.Milliseconds())
-}
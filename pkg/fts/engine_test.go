@@ -1,12 +1,23 @@
 package fts
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+
+	"github.com/dariasmyr/fts-engine/pkg/fuzzy"
 )
 
 type memoryIndex struct {
+	mu      sync.Mutex
 	entries map[string][]DocRef
 	inserts []struct {
 		key string
@@ -28,10 +39,107 @@ func (m *memoryIndex) Insert(key string, id DocID) error {
 }
 
 func (m *memoryIndex) Search(key string) ([]DocRef, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.searches = append(m.searches, key)
 	return m.entries[key], nil
 }
 
+// postingIndex is a map-backed PositionalIndex that records real postings.
+type postingIndex struct {
+	postings map[string]map[DocID]*DocRef
+}
+
+func newPostingIndex() *postingIndex {
+	return &postingIndex{postings: make(map[string]map[DocID]*DocRef)}
+}
+
+func (p *postingIndex) Insert(key string, id DocID) error {
+	p.ref(key, id).Count++
+	return nil
+}
+
+func (p *postingIndex) InsertAt(key string, id DocID, pos uint32) error {
+	ref := p.ref(key, id)
+	ref.Count++
+	ref.Positions = append(ref.Positions, pos)
+	return nil
+}
+
+func (p *postingIndex) Delete(id DocID) error {
+	for _, docs := range p.postings {
+		delete(docs, id)
+	}
+	return nil
+}
+
+func (p *postingIndex) ref(key string, id DocID) *DocRef {
+	docs, ok := p.postings[key]
+	if !ok {
+		docs = make(map[DocID]*DocRef)
+		p.postings[key] = docs
+	}
+	ref, ok := docs[id]
+	if !ok {
+		ref = &DocRef{ID: id}
+		docs[id] = ref
+	}
+	return ref
+}
+
+func (p *postingIndex) Search(key string) ([]DocRef, error) {
+	docs := make([]DocRef, 0, len(p.postings[key]))
+	for _, ref := range p.postings[key] {
+		docs = append(docs, *ref)
+	}
+	return docs, nil
+}
+
+func (p *postingIndex) SearchFuzzy(_ context.Context, key string, maxDist int) ([]FuzzyMatch, error) {
+	m := fuzzy.NewMatcher(key, maxDist)
+	var matches []FuzzyMatch
+	for indexed := range p.postings {
+		if d, ok := m.Distance(indexed); ok {
+			docs, _ := p.Search(indexed)
+			matches = append(matches, FuzzyMatch{Key: indexed, Distance: d, Docs: docs})
+		}
+	}
+	return matches, nil
+}
+
+func (p *postingIndex) SearchWithDescendants(ctx context.Context, prefix string) ([]DocRef, error) {
+	merged := make(map[DocID]*DocRef)
+	for key, docs := range p.postings {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		for id, ref := range docs {
+			if merged[id] == nil {
+				merged[id] = &DocRef{ID: id}
+			}
+			merged[id].Count += ref.Count
+		}
+	}
+
+	res := make([]DocRef, 0, len(merged))
+	for _, ref := range merged {
+		res = append(res, *ref)
+	}
+	return res, nil
+}
+
+// indexDocs indexes docs into svc and returns it.
+func indexDocs(t *testing.T, svc *Service, docs map[DocID]string) *Service {
+	t.Helper()
+
+	for id, content := range docs {
+		if err := svc.IndexDocument(context.Background(), id, content); err != nil {
+			t.Fatalf("IndexDocument(%s) error = %v", id, err)
+		}
+	}
+	return svc
+}
+
 type containsOnlyFilter struct {
 	allowed map[string]bool
 }
@@ -105,6 +213,72 @@ func TestSearchDocumentsTieBreakerByID(t *testing.T) {
 	}
 }
 
+func TestSearchDocumentsNeverExceedsMaxResults(t *testing.T) {
+	ctx := context.Background()
+	svc := New(newPostingIndex(), WordKeys)
+	for i := range 25 {
+		_ = svc.IndexDocument(ctx, DocID(fmt.Sprintf("doc-%02d", i)), "hotel")
+	}
+
+	for _, maxResults := range []int{1, 5, 24, 25, 30} {
+		res, err := svc.SearchDocuments(ctx, "hotel", maxResults)
+		if err != nil {
+			t.Fatalf("SearchDocuments(max=%d) error = %v", maxResults, err)
+		}
+		if want := min(maxResults, 25); len(res.Results) != want {
+			t.Fatalf("SearchDocuments(max=%d) len(Results) = %d, want %d", maxResults, len(res.Results), want)
+		}
+		if res.TotalResultsCount != 25 {
+			t.Fatalf("SearchDocuments(max=%d) TotalResultsCount = %d, want 25", maxResults, res.TotalResultsCount)
+		}
+
+		res, err = svc.SearchFuzzy(ctx, "hotel", 1, maxResults)
+		if err != nil {
+			t.Fatalf("SearchFuzzy(max=%d) error = %v", maxResults, err)
+		}
+		if want := min(maxResults, 25); len(res.Results) != want {
+			t.Fatalf("SearchFuzzy(max=%d) len(Results) = %d, want %d", maxResults, len(res.Results), want)
+		}
+	}
+}
+
+func TestSearchPaginatesWithoutOverlap(t *testing.T) {
+	idx := newMemoryIndex()
+	idx.entries["token"] = []DocRef{
+		{ID: "e", Count: 1}, {ID: "c", Count: 1}, {ID: "a", Count: 2},
+		{ID: "d", Count: 1}, {ID: "b", Count: 1},
+	}
+
+	svc := New(idx, WordKeys)
+
+	var got []DocID
+	for offset := 0; offset < 6; offset += 2 {
+		res, err := svc.Search(context.Background(), "token", SearchOptions{Offset: offset, Limit: 2})
+		if err != nil {
+			t.Fatalf("Search(offset=%d) error = %v", offset, err)
+		}
+		if res.TotalResultsCount != 5 {
+			t.Fatalf("Search(offset=%d) TotalResultsCount = %d, want 5", offset, res.TotalResultsCount)
+		}
+		for _, r := range res.Results {
+			got = append(got, r.ID)
+		}
+	}
+
+	want := []DocID{"a", "b", "c", "d", "e"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("paged IDs = %v, want %v", got, want)
+	}
+
+	res, err := svc.Search(context.Background(), "token", SearchOptions{Offset: 10, Limit: 2})
+	if err != nil {
+		t.Fatalf("Search(offset=10) error = %v", err)
+	}
+	if len(res.Results) != 0 || res.TotalResultsCount != 5 {
+		t.Fatalf("Search(offset=10) = %d results of %d, want 0 of 5", len(res.Results), res.TotalResultsCount)
+	}
+}
+
 func TestSearchDocumentsReturnsTimings(t *testing.T) {
 	idx := newMemoryIndex()
 	idx.entries["one"] = []DocRef{{ID: "x", Count: 1}}
@@ -117,11 +291,12 @@ func TestSearchDocumentsReturnsTimings(t *testing.T) {
 	}
 
 	for _, key := range []string{"preprocess", "search_tokens", "total"} {
-		if _, ok := res.Timings[key]; !ok {
+		d, ok := res.Timings[key]
+		if !ok {
 			t.Fatalf("timings key %q missing", key)
 		}
-		if res.Timings[key] == "" {
-			t.Fatalf("timings key %q is empty", key)
+		if d < 0 || d > res.Timings["total"] {
+			t.Fatalf("timings key %q = %v, want within total %v", key, d, res.Timings["total"])
 		}
 	}
 }
@@ -171,6 +346,84 @@ func TestContextCancellation(t *testing.T) {
 	}
 }
 
+// cancelingIndex cancels a search's context once it has served after lookups.
+type cancelingIndex struct {
+	*memoryIndex
+	after  int
+	cancel context.CancelFunc
+}
+
+func (c *cancelingIndex) Search(key string) ([]DocRef, error) {
+	docs, err := c.memoryIndex.Search(key)
+	c.memoryIndex.mu.Lock()
+	if len(c.memoryIndex.searches) == c.after {
+		c.cancel()
+	}
+	c.memoryIndex.mu.Unlock()
+	return docs, err
+}
+
+func TestSearchStopsWhenContextEndsMidQuery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	idx := &cancelingIndex{memoryIndex: newMemoryIndex(), after: 1, cancel: cancel}
+	idx.entries["grand"] = []DocRef{{ID: "doc-1", Count: 1}}
+	idx.entries["hotel"] = []DocRef{{ID: "doc-1", Count: 1}}
+	svc := New(idx, WordKeys, WithSearchWorkers(1))
+
+	result, err := svc.SearchDocuments(ctx, "grand hotel river barge", 10)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	if n := len(idx.searches); n != 1 {
+		t.Fatalf("index lookups = %d (%v), want the search to stop after the first", n, idx.searches)
+	}
+	if !result.Truncated || len(result.Results) != 1 || result.Results[0].ID != "doc-1" {
+		t.Fatalf("SearchDocuments() = %+v, want doc-1 from the first lookup, truncated", result)
+	}
+	if result.Results[0].UniqueMatches != 1 {
+		t.Fatalf("unique matches = %d, want only the word read before ctx ended", result.Results[0].UniqueMatches)
+	}
+}
+
+func TestSearchFailsWhenContextEndsBeforeNegation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	idx := &cancelingIndex{memoryIndex: newMemoryIndex(), after: 1, cancel: cancel}
+	idx.entries["grand"] = []DocRef{{ID: "doc-1", Count: 1}}
+	idx.entries["hotel"] = []DocRef{{ID: "doc-1", Count: 1}}
+	svc := New(idx, WordKeys, WithSearchWorkers(1))
+
+	result, err := svc.SearchDocuments(ctx, "grand NOT hotel", 10)
+	if !errors.Is(err, context.Canceled) || result != nil {
+		t.Fatalf("SearchDocuments() = %+v, %v; want no result and context canceled", result, err)
+	}
+}
+
+func TestSearchDoesNotCacheTruncatedResults(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	idx := &cancelingIndex{memoryIndex: newMemoryIndex(), after: 1, cancel: cancel}
+	idx.entries["grand"] = []DocRef{{ID: "doc-1", Count: 1}}
+	idx.entries["hotel"] = []DocRef{{ID: "doc-2", Count: 1}}
+	svc := New(idx, WordKeys, WithSearchWorkers(1), WithResultCache(8))
+
+	if result, err := svc.SearchDocuments(ctx, "grand hotel", 10); err != nil || !result.Truncated {
+		t.Fatalf("SearchDocuments() = %+v, %v; want truncated results", result, err)
+	}
+
+	result, err := svc.SearchDocuments(context.Background(), "grand hotel", 10)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	if result.Truncated || result.TotalResultsCount != 2 {
+		t.Fatalf("SearchDocuments() = %+v, want both documents from a fresh search", result)
+	}
+}
+
 func TestSearchDocumentsSkipsIndexWhenFilterMisses(t *testing.T) {
 	idx := newMemoryIndex()
 	idx.entries["known"] = []DocRef{{ID: "doc", Count: 1}}
@@ -275,3 +528,319 @@ func TestBuildFilterSkipsNonBuildableFilter(t *testing.T) {
 		t.Fatalf("BuildFilter() error = %v", err)
 	}
 }
+
+type deletingIndex struct {
+	*memoryIndex
+	deleted []DocID
+}
+
+func (d *deletingIndex) Delete(id DocID) error {
+	d.deleted = append(d.deleted, id)
+	return nil
+}
+
+func TestDeleteDocumentDelegatesToIndex(t *testing.T) {
+	idx := &deletingIndex{memoryIndex: newMemoryIndex()}
+	svc := New(idx, WordKeys)
+
+	if err := svc.DeleteDocument(context.Background(), "doc-1"); err != nil {
+		t.Fatalf("DeleteDocument() error = %v", err)
+	}
+
+	if len(idx.deleted) != 1 || idx.deleted[0] != "doc-1" {
+		t.Fatalf("deleted = %v, want [doc-1]", idx.deleted)
+	}
+}
+
+type keyDeletingIndex struct {
+	*postingIndex
+	deletedKeys []string
+	fullDeletes int
+}
+
+func (k *keyDeletingIndex) Delete(id DocID) error {
+	k.fullDeletes++
+	return k.postingIndex.Delete(id)
+}
+
+func (k *keyDeletingIndex) DeleteKeys(id DocID, keys []string) error {
+	k.deletedKeys = append(k.deletedKeys, keys...)
+	for _, key := range keys {
+		delete(k.postings[key], id)
+	}
+	return nil
+}
+
+func TestDeleteDocumentUsesReverseIndex(t *testing.T) {
+	ctx := context.Background()
+	idx := &keyDeletingIndex{postingIndex: newPostingIndex()}
+	svc := New(idx, WordKeys, WithReverseIndex(), WithFields("title", "abstract"))
+
+	_ = svc.IndexFields(ctx, "doc-1", map[string]string{"title": "hotel", "abstract": "hotel river hotel"})
+	_ = svc.IndexFields(ctx, "doc-2", map[string]string{"title": "barge"})
+
+	if err := svc.DeleteDocument(ctx, "doc-1"); err != nil {
+		t.Fatalf("DeleteDocument() error = %v", err)
+	}
+
+	slices.Sort(idx.deletedKeys)
+	want := []string{fieldKey("abstract", "hotel"), fieldKey("abstract", "river"), fieldKey("title", "hotel")}
+	if !reflect.DeepEqual(idx.deletedKeys, want) || idx.fullDeletes != 0 {
+		t.Fatalf("deleted keys %q with %d full deletes, want %q and none", idx.deletedKeys, idx.fullDeletes, want)
+	}
+	if _, ok := svc.docKeys["doc-1"]; ok {
+		t.Fatal("reverse index still lists doc-1")
+	}
+
+	// Without a reverse index entry the whole index is walked.
+	if err := svc.DeleteDocument(ctx, "doc-3"); err != nil {
+		t.Fatalf("DeleteDocument() error = %v", err)
+	}
+	if idx.fullDeletes != 1 {
+		t.Fatalf("full deletes = %d, want 1", idx.fullDeletes)
+	}
+}
+
+func TestDeleteDocumentUnsupportedIndex(t *testing.T) {
+	svc := New(newMemoryIndex(), WordKeys)
+
+	err := svc.DeleteDocument(context.Background(), "doc-1")
+	if !errors.Is(err, ErrDeleteUnsupported) {
+		t.Fatalf("DeleteDocument() error = %v, want ErrDeleteUnsupported", err)
+	}
+}
+
+func TestIndexDocumentAgainReplacesPostings(t *testing.T) {
+	ctx := context.Background()
+	svc := New(newPostingIndex(), WordKeys, WithScorer(NewBM25()))
+
+	if err := svc.IndexDocument(ctx, "doc-1", "hotel hotel hotel river"); err != nil {
+		t.Fatalf("IndexDocument() error = %v", err)
+	}
+	if err := svc.IndexDocument(ctx, "doc-1", "hotel hotel barge"); err != nil {
+		t.Fatalf("IndexDocument() again error = %v", err)
+	}
+
+	res, err := svc.SearchDocuments(ctx, "hotel", 10)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	if len(res.Results) != 1 || res.Results[0].TotalMatches != 2 {
+		t.Fatalf("results = %+v, want one result with the latest count 2", res.Results)
+	}
+	if res, _ := svc.SearchDocuments(ctx, "river", 10); len(res.Results) != 0 {
+		t.Fatalf("old content still matches: %+v", res.Results)
+	}
+	if svc.docLengths["doc-1"] != 3 || svc.totalLength != 3 {
+		t.Fatalf("doc length %d, total %d, want 3 and 3", svc.docLengths["doc-1"], svc.totalLength)
+	}
+}
+
+func TestIndexDocumentReplacesRestoredDocument(t *testing.T) {
+	ctx := context.Background()
+	index := newPostingIndex()
+	if err := New(index, WordKeys).IndexDocument(ctx, "doc-1", "river hotel"); err != nil {
+		t.Fatalf("IndexDocument() error = %v", err)
+	}
+
+	svc := New(index, WordKeys, WithRestoredIndex())
+	if err := svc.IndexDocument(ctx, "doc-1", "grand hotel"); err != nil {
+		t.Fatalf("IndexDocument() on the restored index error = %v", err)
+	}
+	if res, _ := svc.SearchDocuments(ctx, "river", 10); len(res.Results) != 0 {
+		t.Fatalf("old content still matches: %+v", res.Results)
+	}
+	if res, _ := svc.SearchDocuments(ctx, "hotel", 10); len(res.Results) != 1 || res.Results[0].TotalMatches != 1 {
+		t.Fatalf("results = %+v, want doc-1 counted once", res.Results)
+	}
+}
+
+func TestIndexDocumentAgainNeedsDeleter(t *testing.T) {
+	ctx := context.Background()
+	svc := New(newMemoryIndex(), WordKeys)
+
+	if err := svc.IndexDocument(ctx, "doc-1", "hotel"); err != nil {
+		t.Fatalf("IndexDocument() error = %v", err)
+	}
+	if err := svc.IndexDocument(ctx, "doc-1", "hotel"); !errors.Is(err, ErrDeleteUnsupported) {
+		t.Fatalf("IndexDocument() again error = %v, want ErrDeleteUnsupported", err)
+	}
+}
+
+// brokenIndex fails every read, like an index whose storage is corrupt.
+type brokenIndex struct {
+	*postingIndex
+}
+
+var errBrokenIndex = errors.New("read postings: checksum mismatch")
+
+func (brokenIndex) Search(string) ([]DocRef, error) { return nil, errBrokenIndex }
+
+func (brokenIndex) SearchFuzzy(context.Context, string, int) ([]FuzzyMatch, error) {
+	return nil, errBrokenIndex
+}
+
+type brokenBatchIndex struct {
+	brokenIndex
+}
+
+func (brokenBatchIndex) SearchBatch([]string) (map[string][]DocRef, error) {
+	return nil, errBrokenIndex
+}
+
+func TestSearchSurfacesIndexErrors(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		name   string
+		search func(*Service) error
+		index  Index
+	}{
+		{name: "serial", index: brokenIndex{newPostingIndex()}, search: func(s *Service) error {
+			_, err := s.SearchDocuments(ctx, "hotel", 10)
+			return err
+		}},
+		{name: "batch", index: brokenBatchIndex{brokenIndex{newPostingIndex()}}, search: func(s *Service) error {
+			_, err := s.SearchDocuments(ctx, "hotel", 10)
+			return err
+		}},
+		{name: "fuzzy", index: brokenIndex{newPostingIndex()}, search: func(s *Service) error {
+			_, err := s.SearchFuzzy(ctx, "hotel", 1, 10)
+			return err
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := New(tt.index, WordKeys, WithSearchWorkers(1))
+			if err := tt.search(svc); !errors.Is(err, errBrokenIndex) {
+				t.Fatalf("error = %v, want the index error", err)
+			}
+		})
+	}
+}
+
+func TestSearchUnknownKeyIsNotAnError(t *testing.T) {
+	ctx := context.Background()
+	svc := New(newPostingIndex(), WordKeys)
+	_ = svc.IndexDocument(ctx, "doc-1", "hotel")
+
+	res, err := svc.SearchDocuments(ctx, "barge", 10)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	if res.TotalResultsCount != 0 {
+		t.Fatalf("results = %+v, want none", res.Results)
+	}
+}
+
+func TestSearchDocumentsBM25PrefersShortExactMatch(t *testing.T) {
+	ctx := context.Background()
+	long := "hotel " + strings.Repeat("river cruise ship deck cabin ", 7) + "hotel"
+
+	matchSvc := New(newPostingIndex(), WordKeys)
+	bm25Svc := New(newPostingIndex(), WordKeys, WithScorer(NewBM25()))
+
+	for _, svc := range []*Service{matchSvc, bm25Svc} {
+		if err := svc.IndexDocument(ctx, "short", "hotel barge"); err != nil {
+			t.Fatalf("IndexDocument(short) error = %v", err)
+		}
+		if err := svc.IndexDocument(ctx, "long", long); err != nil {
+			t.Fatalf("IndexDocument(long) error = %v", err)
+		}
+	}
+
+	res, err := matchSvc.SearchDocuments(ctx, "hotel", 10)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	if res.Results[0].ID != "long" {
+		t.Fatalf("match ranking results[0] = %q, want %q", res.Results[0].ID, "long")
+	}
+
+	res, err = bm25Svc.SearchDocuments(ctx, "hotel", 10)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	if res.Results[0].ID != "short" {
+		t.Fatalf("bm25 ranking results[0] = %q, want %q", res.Results[0].ID, "short")
+	}
+	if res.Results[0].Score <= res.Results[1].Score {
+		t.Fatalf("bm25 scores = %v, %v, want descending", res.Results[0].Score, res.Results[1].Score)
+	}
+}
+
+func TestDocLengthCountsTokens(t *testing.T) {
+	ctx := context.Background()
+	svc := New(newPostingIndex(), WordKeys, WithFields("title", "abstract"))
+
+	docs := map[DocID]map[string]string{
+		"barge":      {"title": "Barge", "abstract": "hotel barge"},
+		"copenhagen": {"title": "Copenhagen", "abstract": "capital of denmark"},
+	}
+	for id, fields := range docs {
+		if err := svc.IndexFields(ctx, id, fields); err != nil {
+			t.Fatalf("IndexFields(%s) error = %v", id, err)
+		}
+	}
+
+	for id, want := range map[DocID]int{"barge": 3, "copenhagen": 4} {
+		if got, ok := svc.DocLength(id); !ok || got != want {
+			t.Fatalf("DocLength(%s) = %d, %v, want %d", id, got, ok, want)
+		}
+	}
+	if got := svc.CorpusStats(); got.TotalDocs != 2 || got.AvgDocLength != 3.5 {
+		t.Fatalf("CorpusStats() = %+v, want 2 docs of 3.5 tokens", got)
+	}
+
+	if err := svc.IndexFields(ctx, "barge", map[string]string{"title": "Barge"}); err != nil {
+		t.Fatalf("IndexFields(barge) again error = %v", err)
+	}
+	if got, _ := svc.DocLength("barge"); got != 1 {
+		t.Fatalf("DocLength(barge) after reindex = %d, want 1", got)
+	}
+
+	if err := svc.DeleteDocument(ctx, "copenhagen"); err != nil {
+		t.Fatalf("DeleteDocument() error = %v", err)
+	}
+	if _, ok := svc.DocLength("copenhagen"); ok {
+		t.Fatal("DocLength(copenhagen) found after delete")
+	}
+	if got := svc.CorpusStats(); got.TotalDocs != 1 || got.AvgDocLength != 1 {
+		t.Fatalf("CorpusStats() after delete = %+v", got)
+	}
+}
+
+func TestSearchLogsAtDebugLevel(t *testing.T) {
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	svc := New(newPostingIndex(), WordKeys, WithLogger(log))
+	_ = svc.IndexDocument(ctx, "doc-1", "grand hotel")
+	_ = svc.IndexDocument(ctx, "doc-2", "hotel barge")
+
+	if _, err := svc.SearchDocuments(ctx, "hotel NOT barge", 10); err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("log output %q: %v", buf.String(), err)
+	}
+	if record["level"] != "DEBUG" || record["msg"] != "Search" || record["query"] != "hotel NOT barge" {
+		t.Fatalf("record = %v, want a debug Search record with the query", record)
+	}
+	if record["tokens"] != float64(2) || record["results"] != float64(1) {
+		t.Fatalf("record = %v, want 2 tokens and 1 result", record)
+	}
+	if _, ok := record["duration"]; !ok {
+		t.Fatalf("record = %v, want a duration", record)
+	}
+
+	buf.Reset()
+	log = slog.New(slog.NewJSONHandler(&buf, nil))
+	svc = New(newPostingIndex(), WordKeys, WithLogger(log))
+	_, _ = svc.SearchDocuments(ctx, "hotel", 10)
+	if buf.Len() != 0 {
+		t.Fatalf("info logger wrote %q, want nothing", buf.String())
+	}
+}
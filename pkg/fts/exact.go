@@ -0,0 +1,38 @@
+package fts
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrExactUnsupported = errors.New("fts: exact search requires a service built WithExactWords")
+
+// WithExactWords makes the service remember the processed tokens of every
+// document, so SearchExact can tell a whole word from a document that only
+// shares its n-grams: with trigram keys "cat" also finds "category". It costs
+// one word set per document. Documents of an index restored from a snapshot
+// have no word set and are not found by exact searches.
+func WithExactWords() Option {
+	return func(s *Service) {
+		s.docWords = make(map[DocID]fieldWords)
+	}
+}
+
+// fieldWords holds the processed tokens of a document by field.
+type fieldWords map[string]map[string]struct{}
+
+// SearchExact is SearchDocuments with every query token matching only
+// documents that contain it as a whole processed token. It returns
+// ErrExactUnsupported unless the service was built WithExactWords.
+func (s *Service) SearchExact(ctx context.Context, query string, maxResults int) (*SearchResult, error) {
+	return s.Search(ctx, query, SearchOptions{Limit: maxResults, Exact: true})
+}
+
+// hasWord reports whether field of docID was indexed with token.
+func (s *Service) hasWord(docID DocID, field, token string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.docWords[docID][field][token]
+	return ok
+}
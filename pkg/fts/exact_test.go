@@ -0,0 +1,90 @@
+package fts
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/dariasmyr/fts-engine/pkg/keygen"
+)
+
+// exactDocs hold "cat" as a word and inside longer words.
+var exactDocs = map[DocID]string{
+	"cat":      "a cat sat",
+	"category": "category list",
+	"scatter":  "scatter plot",
+}
+
+func TestSearchExactMatchesWholeWords(t *testing.T) {
+	ctx := context.Background()
+	svc := indexDocs(t, New(newPostingIndex(), keygen.Trigram, WithExactWords()), exactDocs)
+
+	tests := []struct {
+		query       string
+		wantTrigram []DocID
+		wantExact   []DocID
+	}{
+		{query: "cat", wantTrigram: []DocID{"cat", "category", "scatter"}, wantExact: []DocID{"cat"}},
+		{query: "plot NOT cat", wantTrigram: nil, wantExact: []DocID{"scatter"}},
+		{query: "categor", wantTrigram: []DocID{"cat", "category", "scatter"}, wantExact: nil},
+	}
+	for _, tt := range tests {
+		res, err := svc.SearchDocuments(ctx, tt.query, 0)
+		if err != nil {
+			t.Fatalf("SearchDocuments(%q) error = %v", tt.query, err)
+		}
+		if got := resultIDs(res); !slices.Equal(got, tt.wantTrigram) {
+			t.Fatalf("SearchDocuments(%q) = %v, want %v", tt.query, got, tt.wantTrigram)
+		}
+
+		res, err = svc.SearchExact(ctx, tt.query, 0)
+		if err != nil {
+			t.Fatalf("SearchExact(%q) error = %v", tt.query, err)
+		}
+		if got := resultIDs(res); !slices.Equal(got, tt.wantExact) {
+			t.Fatalf("SearchExact(%q) = %v, want %v", tt.query, got, tt.wantExact)
+		}
+	}
+}
+
+func TestSearchExactForgetsReplacedWords(t *testing.T) {
+	ctx := context.Background()
+	svc := indexDocs(t, New(newPostingIndex(), keygen.Trigram, WithExactWords()), exactDocs)
+
+	if err := svc.IndexDocument(ctx, "cat", "a dog sat"); err != nil {
+		t.Fatalf("IndexDocument() error = %v", err)
+	}
+	for query, want := range map[string][]DocID{"cat": nil, "dog": {"cat"}} {
+		res, err := svc.SearchExact(ctx, query, 0)
+		if err != nil {
+			t.Fatalf("SearchExact(%q) error = %v", query, err)
+		}
+		if got := resultIDs(res); !slices.Equal(got, want) {
+			t.Fatalf("SearchExact(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestSearchExactKeepsFieldScope(t *testing.T) {
+	ctx := context.Background()
+	svc := New(newPostingIndex(), keygen.Trigram, WithFields("title", "abstract"), WithExactWords())
+	_ = svc.IndexFields(ctx, "title-cat", map[string]string{"title": "cat", "abstract": "category"})
+	_ = svc.IndexFields(ctx, "abstract-cat", map[string]string{"title": "category", "abstract": "cat"})
+
+	res, err := svc.SearchExact(ctx, "title:cat", 0)
+	if err != nil {
+		t.Fatalf("SearchExact() error = %v", err)
+	}
+	if got := resultIDs(res); !slices.Equal(got, []DocID{"title-cat"}) {
+		t.Fatalf("SearchExact(title:cat) = %v, want only the document with cat in its title", got)
+	}
+}
+
+func TestSearchExactUnsupported(t *testing.T) {
+	svc := New(newPostingIndex(), keygen.Trigram)
+
+	if _, err := svc.SearchExact(context.Background(), "cat", 0); !errors.Is(err, ErrExactUnsupported) {
+		t.Fatalf("SearchExact() error = %v, want ErrExactUnsupported", err)
+	}
+}
@@ -0,0 +1,139 @@
+package fts
+
+import (
+	"context"
+	"fmt"
+)
+
+// fieldSeparator joins a field name and a key into the index key, so every
+// field has its own postings without changing the index entry format.
+const fieldSeparator = "\x1f"
+
+// WithFields names the document fields the service indexes. Keys of each
+// field are stored under their own prefix, and searches cover all fields.
+// IndexDocument writes to the first field. Without this option the service
+// has a single unnamed field whose keys are stored unprefixed.
+func WithFields(names ...string) Option {
+	return func(s *Service) {
+		if len(names) > 0 {
+			s.fields = append([]string(nil), names...)
+		}
+	}
+}
+
+// Fields returns the names of the indexed fields.
+func (s *Service) Fields() []string {
+	return append([]string(nil), s.fields...)
+}
+
+// IndexFields indexes every field of a document. Field names must have been
+// registered WithFields; empty values are skipped. Indexing a document again
+// replaces all of its fields.
+func (s *Service) IndexFields(ctx context.Context, docID DocID, fields map[string]string) error {
+	for name := range fields {
+		if !s.hasField(name) {
+			return fmt.Errorf("fts: index document: unknown field %q", name)
+		}
+	}
+
+	defer s.lockDoc(docID)()
+	defer s.invalidateResults()
+
+	if err := s.dropIndexed(ctx, docID); err != nil {
+		return err
+	}
+
+	for _, name := range s.fields {
+		content, ok := fields[name]
+		if !ok || content == "" {
+			continue
+		}
+		if err := s.indexField(ctx, docID, name, content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FieldWeights scales the score contribution of each field. A field without
+// an entry has weight 1.
+type FieldWeights map[string]float64
+
+// DefaultFieldWeights ranks title matches above abstract matches, and those
+// above matches in the extract.
+func DefaultFieldWeights() FieldWeights {
+	return FieldWeights{"title": 3.0, "abstract": 1.0, "extract": 0.5}
+}
+
+// WithFieldWeights replaces the default field weights used when
+// SearchOptions.FieldWeights is nil.
+func WithFieldWeights(weights FieldWeights) Option {
+	return func(s *Service) {
+		s.fieldWeights = weights
+	}
+}
+
+func (w FieldWeights) weight(field string) float64 {
+	if weight, ok := w[field]; ok {
+		return weight
+	}
+	return 1
+}
+
+// weighted reports whether the service indexes named fields, which are
+// ranked by their weights.
+func (s *Service) weighted() bool {
+	return s.fields[0] != ""
+}
+
+// fieldScore adds up the per-field scores of match, each scaled by the field
+// weight. Without a Scorer a field scores the number of its matched keys.
+func (s *Service) fieldScore(corpus CorpusStats, match DocMatch, weights FieldWeights) float64 {
+	byField := make(map[string][]TermMatch, len(s.fields))
+	for _, term := range match.Terms {
+		byField[term.Field] = append(byField[term.Field], term)
+	}
+
+	var score float64
+	for _, field := range s.fields {
+		terms := byField[field]
+		if len(terms) == 0 {
+			continue
+		}
+		if s.scorer == nil {
+			score += weights.weight(field) * float64(len(terms))
+			continue
+		}
+		fieldMatch := match
+		fieldMatch.Terms = terms
+		score += weights.weight(field) * s.scorer.Score(corpus, fieldMatch)
+	}
+	return score
+}
+
+func (s *Service) hasField(name string) bool {
+	for _, field := range s.fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldKey(field, key string) string {
+	if field == "" {
+		return key
+	}
+	return field + fieldSeparator + key
+}
+
+// fieldLookup resolves keys inside one field.
+func fieldLookup(lookup postingLookup, field string) postingLookup {
+	if field == "" {
+		return lookup
+	}
+	return func(key string) ([]DocRef, error) {
+		return lookup(fieldKey(field, key))
+	}
+}
@@ -0,0 +1,182 @@
+package fts
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestIndexFieldsSearchesEveryField(t *testing.T) {
+	ctx := context.Background()
+	svc := New(newPostingIndex(), WordKeys, WithFields("title", "abstract"))
+
+	err := svc.IndexFields(ctx, "doc-1", map[string]string{
+		"title":    "Copenhagen",
+		"abstract": "capital of denmark",
+	})
+	if err != nil {
+		t.Fatalf("IndexFields() error = %v", err)
+	}
+
+	res, err := svc.SearchDocuments(ctx, "copenhagen denmark", 10)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	if res.TotalResultsCount != 1 || res.Results[0].UniqueMatches != 2 {
+		t.Fatalf("results = %+v, want doc-1 with 2 unique matches", res.Results)
+	}
+}
+
+func TestIndexFieldsFindsTitleOnlyWord(t *testing.T) {
+	ctx := context.Background()
+	svc := New(newPostingIndex(), WordKeys, WithFields("title", "abstract", "extract"))
+	_ = svc.IndexFields(ctx, "doc-1", map[string]string{"title": "Anarchism", "abstract": "political philosophy"})
+	_ = svc.IndexFields(ctx, "doc-2", map[string]string{"abstract": "river barge", "extract": "canal"})
+
+	res, err := svc.SearchDocuments(ctx, "anarchism", 10)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	if res.TotalResultsCount != 1 || res.Results[0].ID != "doc-1" {
+		t.Fatalf("results = %+v, want only doc-1", res.Results)
+	}
+}
+
+func TestIndexFieldsCountsKeyOncePerDocument(t *testing.T) {
+	ctx := context.Background()
+	svc := New(newPostingIndex(), WordKeys, WithFields("title", "abstract"))
+	_ = svc.IndexFields(ctx, "doc-1", map[string]string{"title": "hotel", "abstract": "hotel rooms"})
+
+	res, err := svc.SearchDocuments(ctx, "hotel", 10)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	if got := res.Results[0]; got.UniqueMatches != 1 || got.TotalMatches != 2 {
+		t.Fatalf("result = %+v, want 1 unique and 2 total matches", got)
+	}
+}
+
+func TestIndexFieldsRejectsUnknownField(t *testing.T) {
+	svc := New(newPostingIndex(), WordKeys, WithFields("title"))
+
+	err := svc.IndexFields(context.Background(), "doc-1", map[string]string{"body": "text"})
+	if err == nil {
+		t.Fatal("IndexFields() error = nil, want unknown field error")
+	}
+}
+
+func TestPhraseDoesNotSpanFields(t *testing.T) {
+	ctx := context.Background()
+	svc := New(newPostingIndex(), WordKeys, WithFields("title", "abstract"), WithPositions())
+	_ = svc.IndexFields(ctx, "doc-1", map[string]string{"title": "grand hotel", "abstract": "copenhagen"})
+
+	res, err := svc.SearchDocuments(ctx, `"hotel copenhagen"`, 10)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	if res.TotalResultsCount != 0 {
+		t.Fatalf("results = %+v, want none", res.Results)
+	}
+
+	res, err = svc.SearchDocuments(ctx, `"grand hotel"`, 10)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	if res.TotalResultsCount != 1 {
+		t.Fatalf("results = %+v, want doc-1", res.Results)
+	}
+}
+
+func TestSearchFuzzyAcrossFields(t *testing.T) {
+	ctx := context.Background()
+	svc := New(newPostingIndex(), WordKeys, WithFields("title", "abstract"))
+	_ = svc.IndexFields(ctx, "doc-1", map[string]string{"abstract": "hotel"})
+
+	res, err := svc.SearchFuzzy(ctx, "hatel", 1, 10)
+	if err != nil {
+		t.Fatalf("SearchFuzzy() error = %v", err)
+	}
+	if res.TotalResultsCount != 1 {
+		t.Fatalf("results = %+v, want doc-1", res.Results)
+	}
+	if terms := res.Results[0].FuzzyTerms; len(terms) != 1 || terms[0].Term != "hotel" {
+		t.Fatalf("FuzzyTerms = %+v, want hotel without field prefix", terms)
+	}
+}
+
+func TestFieldWeightsRankTitleMatchFirst(t *testing.T) {
+	ctx := context.Background()
+	for _, scorer := range []Scorer{nil, NewBM25()} {
+		svc := New(newPostingIndex(), WordKeys, WithFields("title", "abstract", "extract"), WithScorer(scorer))
+		_ = svc.IndexFields(ctx, "doc-a", map[string]string{"title": "river", "abstract": "hotel"})
+		_ = svc.IndexFields(ctx, "doc-b", map[string]string{"title": "hotel", "abstract": "river"})
+
+		res, err := svc.SearchDocuments(ctx, "hotel", 10)
+		if err != nil {
+			t.Fatalf("SearchDocuments() error = %v", err)
+		}
+		if len(res.Results) != 2 || res.Results[0].ID != "doc-b" {
+			t.Fatalf("scorer %T: results = %+v, want title match doc-b first", scorer, res.Results)
+		}
+		if res.Results[0].Score <= res.Results[1].Score {
+			t.Fatalf("scorer %T: scores = %v, %v, want title match higher", scorer, res.Results[0].Score, res.Results[1].Score)
+		}
+	}
+}
+
+func TestSearchOptionsFieldWeightsOverrideDefaults(t *testing.T) {
+	ctx := context.Background()
+	svc := New(newPostingIndex(), WordKeys, WithFields("title", "abstract"))
+	_ = svc.IndexFields(ctx, "doc-a", map[string]string{"abstract": "hotel"})
+	_ = svc.IndexFields(ctx, "doc-b", map[string]string{"title": "hotel"})
+
+	res, err := svc.Search(ctx, "hotel", SearchOptions{FieldWeights: FieldWeights{"title": 0.5, "abstract": 2}})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if res.Results[0].ID != "doc-a" || res.Results[0].Score != 2 {
+		t.Fatalf("results = %+v, want abstract match doc-a first with score 2", res.Results)
+	}
+}
+
+func TestSearchScopedToField(t *testing.T) {
+	ctx := context.Background()
+	svc := New(newPostingIndex(), WordKeys, WithFields("title", "abstract"))
+	_ = svc.IndexFields(ctx, "doc-a", map[string]string{"title": "Grand Hotel", "abstract": "river barge"})
+	_ = svc.IndexFields(ctx, "doc-b", map[string]string{"title": "Barge", "abstract": "a floating hotel"})
+
+	tests := []struct {
+		query string
+		want  []DocID
+	}{
+		{query: "title:hotel", want: []DocID{"doc-a"}},
+		{query: "abstract:hotel", want: []DocID{"doc-b"}},
+		{query: "title:river", want: nil},
+		{query: "hotel", want: []DocID{"doc-a", "doc-b"}},
+		{query: "title:barge AND abstract:hotel", want: []DocID{"doc-b"}},
+		{query: "hotel NOT title:hotel", want: []DocID{"doc-b"}},
+	}
+	for _, tt := range tests {
+		res, err := svc.SearchDocuments(ctx, tt.query, 10)
+		if err != nil {
+			t.Fatalf("SearchDocuments(%q) error = %v", tt.query, err)
+		}
+		if got := resultIDs(res); !slices.Equal(got, tt.want) {
+			t.Fatalf("SearchDocuments(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchRejectsUnknownField(t *testing.T) {
+	ctx := context.Background()
+	for _, svc := range []*Service{
+		New(newPostingIndex(), WordKeys, WithFields("title", "abstract")),
+		New(newPostingIndex(), WordKeys),
+	} {
+		_, err := svc.SearchDocuments(ctx, "url:wikipedia", 10)
+		if !errors.Is(err, ErrUnknownField) {
+			t.Fatalf("fields %q: error = %v, want ErrUnknownField", svc.Fields(), err)
+		}
+	}
+}
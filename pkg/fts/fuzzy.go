@@ -0,0 +1,232 @@
+package fts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const MaxFuzzyDistance = 2
+
+var (
+	ErrFuzzyUnsupported = errors.New("fts: index does not support fuzzy search")
+	ErrFuzzyDistance    = fmt.Errorf("fts: fuzzy distance must be between 0 and %d", MaxFuzzyDistance)
+)
+
+// SearchFuzzy is SearchDocuments for misspelled queries: every query token
+// matches indexed terms within maxDist edits and the results union their
+// documents. Query operators and phrases are not interpreted.
+//
+// With word keys the index must implement FuzzySearcher and each result
+// reports the matched terms in FuzzyTerms. With n-gram keys a document is a
+// candidate when it shares enough of the token's n-grams to be within maxDist
+// edits; the original words are not indexed, so FuzzyTerms stays empty.
+func (s *Service) SearchFuzzy(ctx context.Context, query string, maxDist, maxResults int) (*SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if maxDist < 0 || maxDist > MaxFuzzyDistance {
+		return nil, ErrFuzzyDistance
+	}
+
+	start := time.Now()
+	timings := make(map[string]time.Duration, 3)
+
+	preStart := time.Now()
+	tokens := s.pipeline.Process(query)
+	timings["preprocess"] = time.Since(preStart)
+
+	searchStart := time.Now()
+	matches := make(map[DocID]*DocMatch)
+	terms := make(map[DocID][]FuzzyTerm)
+
+	seen := make(map[string]struct{}, len(tokens))
+	for _, token := range tokens {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// A repeated word would count its documents twice.
+		if _, ok := seen[token]; ok {
+			continue
+		}
+		seen[token] = struct{}{}
+
+		keys, err := s.keyGen(token)
+		if err != nil {
+			return nil, fmt.Errorf("fts: fuzzy search: keygen: %w", err)
+		}
+
+		if len(keys) == 1 && keys[0] == token {
+			err = s.fuzzyTerm(ctx, token, maxDist, matches, terms)
+		} else {
+			err = s.fuzzyGrams(keys, maxDist, matches)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("fts: fuzzy search: %w", err)
+		}
+	}
+
+	timings["search_tokens"] = time.Since(searchStart)
+
+	results := s.rank(matches, s.fieldWeights)
+	for i := range results {
+		results[i].FuzzyTerms = terms[results[i].ID]
+	}
+
+	timings["total"] = time.Since(start)
+	s.log.DebugContext(ctx, "Fuzzy search",
+		"query", query,
+		"tokens", len(tokens),
+		"max_distance", maxDist,
+		"results", len(results),
+		"duration", timings["total"],
+	)
+
+	return &SearchResult{
+		Results:           paginate(results, 0, maxResults),
+		TotalResultsCount: len(results),
+		Timings:           timings,
+	}, nil
+}
+
+// fuzzyTerm matches token against indexed terms. A document reached through
+// several terms counts once, with the closest term; of equally close terms the
+// smallest key wins, whatever order the index returns them in.
+func (s *Service) fuzzyTerm(ctx context.Context, token string, maxDist int, matches map[DocID]*DocMatch, terms map[DocID][]FuzzyTerm) error {
+	searcher, ok := s.index.(FuzzySearcher)
+	if !ok {
+		return ErrFuzzyUnsupported
+	}
+
+	type hit struct {
+		term  FuzzyMatch
+		field string
+		doc   DocRef
+	}
+	best := make(map[DocID]hit)
+	for _, field := range s.fields {
+		// A shared field prefix does not change the edit distance. Keys of a
+		// field whose name is within maxDist of this one are skipped.
+		prefix := fieldKey(field, "")
+		found, err := searcher.SearchFuzzy(ctx, fieldKey(field, token), maxDist)
+		if err != nil {
+			return err
+		}
+
+		for _, fm := range found {
+			if field != "" && !strings.HasPrefix(fm.Key, prefix) || isPhraseKey(fm.Key) {
+				continue
+			}
+			fm.Key = strings.TrimPrefix(fm.Key, prefix)
+			for _, doc := range fm.Docs {
+				if prev, ok := best[doc.ID]; !ok || closer(fm, prev.term) {
+					best[doc.ID] = hit{term: fm, field: field, doc: doc}
+				}
+			}
+		}
+	}
+
+	for id, h := range best {
+		terms[id] = append(terms[id], FuzzyTerm{Token: token, Term: h.term.Key, Distance: h.term.Distance})
+		match := matchFor(matches, id)
+		if !match.count(h.term.Key, h.field) {
+			continue
+		}
+		match.TotalMatches += int(h.doc.Count)
+		if s.keepTerms() {
+			match.Terms = append(match.Terms, TermMatch{Key: h.term.Key, Field: h.field, TermFreq: h.doc.Count, DocFreq: len(h.term.Docs)})
+		}
+	}
+
+	return nil
+}
+
+func closer(a, b FuzzyMatch) bool {
+	if a.Distance != b.Distance {
+		return a.Distance < b.Distance
+	}
+	return a.Key < b.Key
+}
+
+// fuzzyGrams applies the q-gram lemma: a word within d edits of a token shares
+// at least len(keys)-d*n of the token's n-grams, as every edit touches at most n.
+func (s *Service) fuzzyGrams(keys []string, maxDist int, matches map[DocID]*DocMatch) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	unique := make(map[string]struct{}, len(keys))
+	gram := 0
+	for _, key := range keys {
+		unique[key] = struct{}{}
+		gram = max(gram, utf8.RuneCountInString(key))
+	}
+	threshold := max(len(unique)-maxDist*gram, 1)
+
+	shared := make(map[DocID]int)
+	counts := make(map[DocID]int)
+	for key := range unique {
+		seen := make(map[DocID]struct{})
+		for _, field := range s.fields {
+			fk := fieldKey(field, key)
+			if s.filter != nil && !s.filter.Contains([]byte(fk)) {
+				continue
+			}
+			docs, err := s.index.Search(fk)
+			if err != nil {
+				return fmt.Errorf("index search: %w", err)
+			}
+			for _, doc := range docs {
+				if _, ok := seen[doc.ID]; !ok {
+					seen[doc.ID] = struct{}{}
+					shared[doc.ID]++
+				}
+				counts[doc.ID] += int(doc.Count)
+			}
+		}
+	}
+
+	for id, n := range shared {
+		if n < threshold {
+			continue
+		}
+		match := matchFor(matches, id)
+		match.UniqueMatches++
+		match.TotalMatches += counts[id]
+	}
+
+	return nil
+}
+
+func matchFor(matches map[DocID]*DocMatch, id DocID) *DocMatch {
+	match, ok := matches[id]
+	if !ok {
+		match = &DocMatch{ID: id}
+		matches[id] = match
+	}
+	return match
+}
+
+// count records that key matched the document in field and reports whether
+// that is new. A key the query repeats, in another word or as a repeated
+// n-gram, is counted once, so UniqueMatches never exceeds the distinct keys.
+func (m *DocMatch) count(key, field string) bool {
+	if m.fieldKeys == nil {
+		m.keys = make(map[string]struct{})
+		m.fieldKeys = make(map[string]struct{})
+	}
+
+	fk := fieldKey(field, key)
+	if _, ok := m.fieldKeys[fk]; ok {
+		return false
+	}
+	m.fieldKeys[fk] = struct{}{}
+	if _, ok := m.keys[key]; !ok {
+		m.keys[key] = struct{}{}
+		m.UniqueMatches++
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+package fts
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dariasmyr/fts-engine/pkg/keygen"
+)
+
+func TestSearchFuzzyReportsMatchedTerm(t *testing.T) {
+	ctx := context.Background()
+	svc := New(newPostingIndex(), WordKeys)
+	_ = svc.IndexDocument(ctx, "doc-1", "hotel copenhagen")
+	_ = svc.IndexDocument(ctx, "doc-2", "river barge")
+
+	res, err := svc.SearchFuzzy(ctx, "hatel", 1, 10)
+	if err != nil {
+		t.Fatalf("SearchFuzzy() error = %v", err)
+	}
+	if res.TotalResultsCount != 1 || res.Results[0].ID != "doc-1" {
+		t.Fatalf("results = %+v, want only doc-1", res.Results)
+	}
+
+	want := FuzzyTerm{Token: "hatel", Term: "hotel", Distance: 1}
+	if terms := res.Results[0].FuzzyTerms; len(terms) != 1 || terms[0] != want {
+		t.Fatalf("FuzzyTerms = %+v, want [%+v]", terms, want)
+	}
+}
+
+func TestSearchFuzzyCountsClosestTermOnce(t *testing.T) {
+	ctx := context.Background()
+	svc := New(newPostingIndex(), WordKeys)
+	_ = svc.IndexDocument(ctx, "doc-1", "hotel hotels")
+
+	res, err := svc.SearchFuzzy(ctx, "hotel", 1, 10)
+	if err != nil {
+		t.Fatalf("SearchFuzzy() error = %v", err)
+	}
+	if res.Results[0].UniqueMatches != 1 {
+		t.Fatalf("UniqueMatches = %d, want 1", res.Results[0].UniqueMatches)
+	}
+	if terms := res.Results[0].FuzzyTerms; len(terms) != 1 || terms[0].Term != "hotel" {
+		t.Fatalf("FuzzyTerms = %+v, want exact term hotel", terms)
+	}
+}
+
+func TestSearchFuzzyRepeatedWordsCountOnce(t *testing.T) {
+	ctx := context.Background()
+	svc := New(newPostingIndex(), WordKeys)
+	_ = svc.IndexDocument(ctx, "doc-1", "hotel")
+
+	res, err := svc.SearchFuzzy(ctx, "hotel hotel hotell", 1, 10)
+	if err != nil {
+		t.Fatalf("SearchFuzzy() error = %v", err)
+	}
+	if got := res.Results[0]; got.UniqueMatches != 1 || got.TotalMatches != 1 || len(got.FuzzyTerms) != 2 {
+		t.Fatalf("result = %+v, want hotel counted once and explained per distinct word", got)
+	}
+}
+
+func TestSearchFuzzyEquallyCloseTermsBreakTiesByKey(t *testing.T) {
+	ctx := context.Background()
+	svc := New(newPostingIndex(), WordKeys)
+	_ = svc.IndexDocument(ctx, "doc-1", "hovel hotel")
+
+	// The test index returns terms in map order, so repeat to catch a tie
+	// that follows it.
+	for range 20 {
+		res, err := svc.SearchFuzzy(ctx, "hozel", 1, 10)
+		if err != nil {
+			t.Fatalf("SearchFuzzy() error = %v", err)
+		}
+		if terms := res.Results[0].FuzzyTerms; len(terms) != 1 || terms[0].Term != "hotel" {
+			t.Fatalf("FuzzyTerms = %+v, want hotel", terms)
+		}
+	}
+}
+
+func TestSearchFuzzyNGramKeysUseOverlap(t *testing.T) {
+	ctx := context.Background()
+
+	svc := New(newPostingIndex(), keygen.Trigram)
+	_ = svc.IndexDocument(ctx, "doc-1", "copenhagen")
+	_ = svc.IndexDocument(ctx, "doc-2", "openness")
+
+	res, err := svc.SearchFuzzy(ctx, "copenhagan", 1, 10)
+	if err != nil {
+		t.Fatalf("SearchFuzzy() error = %v", err)
+	}
+	if res.TotalResultsCount != 1 || res.Results[0].ID != "doc-1" {
+		t.Fatalf("results = %+v, want only doc-1", res.Results)
+	}
+}
+
+func TestSearchFuzzyRejectsDistance(t *testing.T) {
+	svc := New(newPostingIndex(), WordKeys)
+
+	_, err := svc.SearchFuzzy(context.Background(), "hotel", 3, 10)
+	if !errors.Is(err, ErrFuzzyDistance) {
+		t.Fatalf("SearchFuzzy() error = %v, want ErrFuzzyDistance", err)
+	}
+}
+
+func TestSearchFuzzyUnsupportedIndex(t *testing.T) {
+	svc := New(newMemoryIndex(), WordKeys)
+
+	_, err := svc.SearchFuzzy(context.Background(), "hotel", 1, 10)
+	if !errors.Is(err, ErrFuzzyUnsupported) {
+		t.Fatalf("SearchFuzzy() error = %v, want ErrFuzzyUnsupported", err)
+	}
+}
@@ -1,5 +1,7 @@
 package fts
 
+import "log/slog"
+
 type Option func(*Service)
 
 func WithPipeline(p Pipeline) Option {
@@ -15,3 +17,38 @@ func WithFilter(f Filter) Option {
 		s.filter = f
 	}
 }
+
+func WithScorer(scorer Scorer) Option {
+	return func(s *Service) {
+		s.scorer = scorer
+	}
+}
+
+// WithLogger makes the service log every search at debug level with the
+// query, its token count, the result count and the duration. Without it the
+// service logs nothing.
+func WithLogger(log *slog.Logger) Option {
+	return func(s *Service) {
+		if log != nil {
+			s.log = log
+		}
+	}
+}
+
+// WithPositions makes the service record token positions when the index
+// implements PositionalIndex, enabling quoted phrase queries.
+func WithPositions() Option {
+	return func(s *Service) {
+		s.positions = true
+	}
+}
+
+// WithReverseIndex makes the service remember which keys every document was
+// indexed under. Deleting or re-indexing a document then only touches those
+// keys when the index implements KeyDeleter, instead of walking the whole
+// index. It costs one key list per document.
+func WithReverseIndex() Option {
+	return func(s *Service) {
+		s.docKeys = make(map[DocID][]string)
+	}
+}
@@ -0,0 +1,159 @@
+package fts
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrPhraseUnsupported = errors.New("fts: phrase search requires an index built with positions")
+
+// phraseMarker starts the keys of the phrase postings indexed
+// WithPhrasePipeline, so they never mix with the postings of words.
+const phraseMarker = "\x1e"
+
+// WithPhrasePipeline processes quoted phrases with p instead of the service
+// pipeline. Every field is also indexed with p, under keys of its own, so the
+// phrase words the service pipeline drops or stems are found. With
+// p = pipeline.Without(textproc.StageStopWords | textproc.StageStem),
+// "to be or not to be" only matches those six words in a row. It needs
+// WithPositions, and adds a second set of postings for every document.
+// Exact searches do not narrow phrase words, which the service does not
+// remember as document words.
+func WithPhrasePipeline(p Pipeline) Option {
+	return func(s *Service) {
+		s.phrasePipeline = p
+	}
+}
+
+// phraseTokens processes the text of a phrase.
+func (s *Service) phraseTokens(text string) []string {
+	if s.phrasePipeline != nil {
+		return s.phrasePipeline.Process(text)
+	}
+	return s.pipeline.Process(text)
+}
+
+// phraseLookup returns lookup reading the postings of phrase keys.
+func (s *Service) phraseLookup(lookup postingLookup) postingLookup {
+	if s.phrasePipeline == nil {
+		return lookup
+	}
+	return func(key string) ([]DocRef, error) {
+		return lookup(phraseMarker + key)
+	}
+}
+
+// isPhraseKey reports whether an index key is a phrase key. Only unnamed
+// field keys need the check; keys of named fields start with the field.
+func isPhraseKey(key string) bool {
+	return strings.HasPrefix(key, phraseMarker)
+}
+
+type postingLookup func(key string) ([]DocRef, error)
+
+// phraseDocs returns the documents in which tokens occur at consecutive
+// positions, in order, within one of fields. Positions are offsets in the
+// processed token stream, so words dropped by the pipeline (stop words) do not
+// break adjacency.
+func (s *Service) phraseDocs(tokens []string, fields []string, lookup postingLookup) (map[DocID]struct{}, error) {
+	docs := make(map[DocID]struct{})
+	for _, field := range fields {
+		found, err := s.fieldPhraseDocs(tokens, fieldLookup(lookup, field))
+		if err != nil {
+			return nil, err
+		}
+		for id := range found {
+			docs[id] = struct{}{}
+		}
+	}
+	return docs, nil
+}
+
+func (s *Service) fieldPhraseDocs(tokens []string, lookup postingLookup) (map[DocID]struct{}, error) {
+	var starts map[DocID]map[uint32]struct{}
+
+	for i, token := range tokens {
+		positions, err := s.tokenPositions(token, lookup)
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 {
+			starts = make(map[DocID]map[uint32]struct{}, len(positions))
+			for id, set := range positions {
+				starts[id] = set
+			}
+			continue
+		}
+
+		for id, set := range starts {
+			at := positions[id]
+			for start := range set {
+				if _, ok := at[start+uint32(i)]; !ok {
+					delete(set, start)
+				}
+			}
+			if len(set) == 0 {
+				delete(starts, id)
+			}
+		}
+
+		if len(starts) == 0 {
+			break
+		}
+	}
+
+	docs := make(map[DocID]struct{}, len(starts))
+	for id := range starts {
+		docs[id] = struct{}{}
+	}
+	return docs, nil
+}
+
+// tokenPositions returns, per document, the positions at which every key
+// generated for token occurs.
+func (s *Service) tokenPositions(token string, lookup postingLookup) (map[DocID]map[uint32]struct{}, error) {
+	keys, err := s.keyGen(token)
+	if err != nil {
+		return nil, err
+	}
+	return s.keyPositions(keys, lookup)
+}
+
+// keyPositions returns, per document, the positions at which all of keys occur.
+func (s *Service) keyPositions(keys []string, lookup postingLookup) (map[DocID]map[uint32]struct{}, error) {
+	var positions map[DocID]map[uint32]struct{}
+	for i, key := range keys {
+		docs, err := lookup(key)
+		if err != nil {
+			return nil, err
+		}
+
+		next := make(map[DocID]map[uint32]struct{}, len(docs))
+		for _, doc := range docs {
+			var prev map[uint32]struct{}
+			if i > 0 {
+				var ok bool
+				if prev, ok = positions[doc.ID]; !ok {
+					continue
+				}
+			}
+
+			set := make(map[uint32]struct{}, len(doc.Positions))
+			for _, pos := range doc.Positions {
+				if prev != nil {
+					if _, ok := prev[pos]; !ok {
+						continue
+					}
+				}
+				set[pos] = struct{}{}
+			}
+			if len(set) > 0 {
+				next[doc.ID] = set
+			}
+		}
+		positions = next
+	}
+
+	return positions, nil
+}
@@ -0,0 +1,199 @@
+package fts
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/dariasmyr/fts-engine/pkg/textproc"
+)
+
+// phraseDocs hold the words of a phrase next to each other, reversed and apart.
+var phraseDocs = map[DocID]string{
+	"adjacent": "the hotel barge on the river",
+	"reversed": "a barge near the hotel",
+	"apart":    "hotel with a view of a barge",
+}
+
+func TestSearchDocumentsPhraseRequiresAdjacency(t *testing.T) {
+	svc := indexDocs(t, New(newPostingIndex(), WordKeys, WithPositions()), phraseDocs)
+
+	res, err := svc.SearchDocuments(context.Background(), `"hotel barge"`, 10)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	if res.TotalResultsCount != 1 || res.Results[0].ID != "adjacent" {
+		t.Fatalf("results = %+v, want only %q", res.Results, "adjacent")
+	}
+}
+
+func TestSearchDocumentsUnquotedQueryKeepsOrSemantics(t *testing.T) {
+	svc := indexDocs(t, New(newPostingIndex(), WordKeys, WithPositions()), phraseDocs)
+
+	res, err := svc.SearchDocuments(context.Background(), "hotel barge", 10)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	if res.TotalResultsCount != 3 {
+		t.Fatalf("TotalResultsCount = %d, want 3", res.TotalResultsCount)
+	}
+}
+
+func TestSearchDocumentsPhraseWithExtraTerms(t *testing.T) {
+	svc := indexDocs(t, New(newPostingIndex(), WordKeys, WithPositions()), phraseDocs)
+
+	res, err := svc.SearchDocuments(context.Background(), `"hotel barge" river`, 10)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	if res.TotalResultsCount != 1 || res.Results[0].ID != "adjacent" {
+		t.Fatalf("results = %+v, want only %q", res.Results, "adjacent")
+	}
+	if res.Results[0].UniqueMatches != 3 {
+		t.Fatalf("UniqueMatches = %d, want 3", res.Results[0].UniqueMatches)
+	}
+}
+
+func TestSearchDocumentsPhraseWithoutPositions(t *testing.T) {
+	svc := New(newPostingIndex(), WordKeys)
+
+	_, err := svc.SearchDocuments(context.Background(), `"hotel barge"`, 10)
+	if !errors.Is(err, ErrPhraseUnsupported) {
+		t.Fatalf("SearchDocuments() error = %v, want ErrPhraseUnsupported", err)
+	}
+}
+
+func TestSearchDocumentsGroupedPhrases(t *testing.T) {
+	svc := New(newPostingIndex(), WordKeys, WithPositions())
+	for id, content := range map[DocID]string{
+		"barge-france":  "a hotel barge trip in france",
+		"cruise-france": "a river cruise through france",
+		"cruise-spain":  "a river cruise through spain",
+		"apart-france":  "a hotel by the barge in france",
+	} {
+		if err := svc.IndexDocument(context.Background(), id, content); err != nil {
+			t.Fatalf("IndexDocument(%s) error = %v", id, err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []DocID
+	}{
+		{query: `("hotel barge" OR "river cruise") AND france`, want: []DocID{"barge-france", "cruise-france"}},
+		{query: `"hotel barge" OR "river cruise"`, want: []DocID{"barge-france", "cruise-france", "cruise-spain"}},
+		{query: `("hotel barge" OR "river cruise") AND NOT "through spain"`, want: []DocID{"barge-france", "cruise-france"}},
+		{query: `(("river cruise" AND spain) OR "hotel barge") france`, want: []DocID{"apart-france", "barge-france", "cruise-france", "cruise-spain"}},
+		{query: `"river cruise" france`, want: []DocID{"cruise-france"}},
+	}
+
+	for _, tt := range tests {
+		res, err := svc.SearchDocuments(context.Background(), tt.query, 10)
+		if err != nil {
+			t.Fatalf("SearchDocuments(%q) error = %v", tt.query, err)
+		}
+		var got []DocID
+		for _, result := range res.Results {
+			got = append(got, result.ID)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Fatalf("SearchDocuments(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchDocumentsPhraseAlternativeRanksOnItsWords(t *testing.T) {
+	svc := indexDocs(t, New(newPostingIndex(), WordKeys, WithPositions()), phraseDocs)
+
+	// "apart" has both words, but not as the phrase, so they do not add to
+	// its score for the view it matched on.
+	res, err := svc.SearchDocuments(context.Background(), `"hotel barge" OR view`, 10)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	for _, result := range res.Results {
+		if result.ID == "apart" && result.UniqueMatches != 1 {
+			t.Fatalf("apart: UniqueMatches = %d, want only view", result.UniqueMatches)
+		}
+	}
+	if res.TotalResultsCount != 2 {
+		t.Fatalf("results = %+v, want adjacent and apart", res.Results)
+	}
+}
+
+// verbatimDocs hold phrases made of stop words and of stemmed forms.
+var verbatimDocs = map[DocID]string{
+	"hamlet":    "To be, or not to be, that is the question",
+	"reordered": "Not to be or to be",
+	"apart":     "To be fair, or not",
+	"hotels":    "The grand hotels of the town",
+	"hotel":     "The grand hotel of the town",
+}
+
+func searchIDs(t *testing.T, svc *Service, query string) []DocID {
+	t.Helper()
+
+	res, err := svc.SearchDocuments(context.Background(), query, 0)
+	if err != nil {
+		t.Fatalf("SearchDocuments(%q) error = %v", query, err)
+	}
+	var ids []DocID
+	for _, result := range res.Results {
+		ids = append(ids, result.ID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestPhrasePipelineKeepsStopWords(t *testing.T) {
+	verbatim := textproc.DefaultEnglishPipeline().Without(textproc.StageStopWords | textproc.StageStem)
+	svc := New(newPostingIndex(), WordKeys,
+		WithPipeline(textproc.DefaultEnglishPipeline()), WithPositions(), WithPhrasePipeline(verbatim))
+	indexDocs(t, svc, verbatimDocs)
+
+	tests := []struct {
+		query string
+		want  []DocID
+	}{
+		{query: `"to be or not to be"`, want: []DocID{"hamlet"}},
+		{query: `"not to be"`, want: []DocID{"hamlet", "reordered"}},
+		{query: `"the grand hotels"`, want: []DocID{"hotels"}},
+		// Words outside phrases still go through the service pipeline.
+		{query: `grand hotels`, want: []DocID{"hotel", "hotels"}},
+		{query: `"to be" AND question`, want: []DocID{"hamlet"}},
+	}
+	for _, tt := range tests {
+		if got := searchIDs(t, svc, tt.query); !slices.Equal(got, tt.want) {
+			t.Fatalf("SearchDocuments(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestPhrasePipelineDefaultDropsStopWords(t *testing.T) {
+	svc := New(newPostingIndex(), WordKeys, WithPipeline(textproc.DefaultEnglishPipeline()), WithPositions())
+	indexDocs(t, svc, verbatimDocs)
+
+	// Every word of the phrase is a stop word, so nothing is left to search.
+	if got := searchIDs(t, svc, `"to be or not to be"`); len(got) != 0 {
+		t.Fatalf("SearchDocuments() = %v, want no results", got)
+	}
+	if got := searchIDs(t, svc, `"the grand hotels"`); !slices.Equal(got, []DocID{"hotel", "hotels"}) {
+		t.Fatalf("SearchDocuments() = %v, want both stemmed forms", got)
+	}
+}
+
+func TestPhrasePipelineDelete(t *testing.T) {
+	verbatim := textproc.DefaultEnglishPipeline().Without(textproc.StageStopWords)
+	svc := New(newPostingIndex(), WordKeys,
+		WithPipeline(textproc.DefaultEnglishPipeline()), WithPositions(), WithPhrasePipeline(verbatim))
+	indexDocs(t, svc, verbatimDocs)
+
+	if err := svc.DeleteDocument(context.Background(), "hamlet"); err != nil {
+		t.Fatalf("DeleteDocument() error = %v", err)
+	}
+	if got := searchIDs(t, svc, `"to be or not to be"`); len(got) != 0 {
+		t.Fatalf("SearchDocuments() = %v, want the deleted document gone", got)
+	}
+}
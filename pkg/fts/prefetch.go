@@ -0,0 +1,269 @@
+package fts
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/dariasmyr/fts-engine/pkg/query"
+)
+
+// WithSearchWorkers bounds how many index lookups one search runs at once.
+// n <= 1 looks keys up one at a time. The default is GOMAXPROCS.
+func WithSearchWorkers(n int) Option {
+	return func(s *Service) {
+		s.workers = max(n, 1)
+	}
+}
+
+func defaultSearchWorkers() int {
+	return runtime.GOMAXPROCS(0)
+}
+
+// queryKeys returns the distinct index keys that evaluating root reads, in
+// query order. With phrasesOnly the keys of terms outside phrases are left
+// out.
+func (s *Service) queryKeys(root query.Node, phrasesOnly bool) ([]string, error) {
+	var keys []string
+	seen := make(map[string]struct{})
+	addToken := func(token string, fields []string, prefix string) error {
+		tokenKeys, err := s.keyGen(token)
+		if err != nil {
+			return err
+		}
+		for _, key := range tokenKeys {
+			for _, field := range fields {
+				fk := prefix + fieldKey(field, key)
+				if _, ok := seen[fk]; ok {
+					continue
+				}
+				seen[fk] = struct{}{}
+				keys = append(keys, fk)
+			}
+		}
+		return nil
+	}
+
+	addText := func(field string, tokens []string, prefix string) error {
+		// An unknown field is reported when the query is evaluated.
+		fields, err := s.termFields(field)
+		if err != nil {
+			return nil
+		}
+		for _, token := range tokens {
+			if err := addToken(token, fields, prefix); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var walk func(node query.Node) error
+	walk = func(node query.Node) error {
+		switch n := node.(type) {
+		case query.Term:
+			if phrasesOnly {
+				return nil
+			}
+			return addText(n.Field, s.pipeline.Process(n.Text), "")
+		case query.Phrase:
+			prefix := ""
+			if s.phrasePipeline != nil {
+				prefix = phraseMarker
+			}
+			return addText(n.Field, s.phraseTokens(n.Text), prefix)
+		case query.Not:
+			return walk(n.Operand)
+		case query.And:
+			if err := walk(n.Left); err != nil {
+				return err
+			}
+			return walk(n.Right)
+		case query.Or:
+			if err := walk(n.Left); err != nil {
+				return err
+			}
+			return walk(n.Right)
+		}
+		return nil
+	}
+
+	if root != nil {
+		if err := walk(root); err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, nil
+}
+
+// postingCache holds the postings one search has read, so every key is
+// looked up once however often the query uses it. It is safe for concurrent use.
+type postingCache struct {
+	s   *Service
+	ctx context.Context
+
+	mu       sync.Mutex
+	postings map[string][]DocRef
+}
+
+func (s *Service) newPostingCache(ctx context.Context) *postingCache {
+	return &postingCache{s: s, ctx: ctx, postings: make(map[string][]DocRef)}
+}
+
+func (c *postingCache) get(key string) ([]DocRef, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	docs, ok := c.postings[key]
+	return docs, ok
+}
+
+func (c *postingCache) put(key string, docs []DocRef) {
+	c.mu.Lock()
+	c.postings[key] = docs
+	c.mu.Unlock()
+}
+
+// lookup returns the postings of key, reading the index on a cache miss.
+// Keys the filter rules out are not looked up.
+func (c *postingCache) lookup(key string) ([]DocRef, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	if docs, ok := c.get(key); ok {
+		return docs, nil
+	}
+
+	var docs []DocRef
+	if c.s.filter == nil || c.s.filter.Contains([]byte(key)) {
+		var err error
+		if docs, err = c.s.index.Search(key); err != nil {
+			return nil, err
+		}
+	}
+	c.put(key, docs)
+	return docs, nil
+}
+
+// read returns the postings of key read so far, and none for a key not read
+// yet. It never reads the index.
+func (c *postingCache) read(key string) ([]DocRef, error) {
+	docs, _ := c.get(key)
+	return docs, nil
+}
+
+// fill caches the postings of keys with one BatchSearcher call. Keys the
+// batch has no postings for are cached as empty.
+func (c *postingCache) fill(batcher BatchSearcher, keys []string) error {
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+
+	wanted := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if c.s.filter == nil || c.s.filter.Contains([]byte(key)) {
+			wanted = append(wanted, key)
+		}
+	}
+
+	found, err := batcher.SearchBatch(wanted)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		c.put(key, found[key])
+	}
+	return nil
+}
+
+// prefetch fills cache with the postings of keys, so the serial query
+// evaluation afterwards does not wait on the index. A BatchSearcher index gets
+// the keys in one batch per worker; with one worker the whole query is read
+// from a single state of the index. Other indexes are prefetched key by key
+// when there is more than one worker, and read lazily otherwise.
+func (s *Service) prefetch(ctx context.Context, keys []string, cache *postingCache) error {
+	if batcher, ok := s.index.(BatchSearcher); ok {
+		chunks := chunkKeys(keys, s.workers)
+		return s.runBounded(ctx, len(chunks), func(i int) error {
+			return cache.fill(batcher, chunks[i])
+		})
+	}
+
+	if s.workers <= 1 {
+		return nil
+	}
+	return s.runBounded(ctx, len(keys), func(i int) error {
+		_, err := cache.lookup(keys[i])
+		return err
+	})
+}
+
+// chunkKeys splits keys into at most n chunks of about equal size.
+func chunkKeys(keys []string, n int) [][]string {
+	if len(keys) == 0 {
+		return nil
+	}
+	n = min(max(n, 1), len(keys))
+	size := (len(keys) + n - 1) / n
+
+	chunks := make([][]string, 0, n)
+	for start := 0; start < len(keys); start += size {
+		chunks = append(chunks, keys[start:min(start+size, len(keys))])
+	}
+	return chunks
+}
+
+// runBounded calls task for 0..n-1 on up to s.workers goroutines. The first
+// error stops the remaining tasks.
+func (s *Service) runBounded(ctx context.Context, n int, task func(i int) error) error {
+	workers := min(s.workers, n)
+	if workers <= 1 {
+		for i := range n {
+			if err := task(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	next := make(chan int)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if err := task(i); err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := range n {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
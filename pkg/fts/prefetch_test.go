@@ -0,0 +1,186 @@
+package fts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dariasmyr/fts-engine/pkg/query"
+)
+
+func TestSearchWorkersMatchSerialResults(t *testing.T) {
+	ctx := context.Background()
+	build := func(workers int) *Service {
+		svc := New(newPostingIndex(), WordKeys, WithPositions(), WithScorer(NewBM25()), WithSearchWorkers(workers))
+		for i := range 50 {
+			_ = svc.IndexDocument(ctx, DocID(fmt.Sprintf("doc-%02d", i)), fmt.Sprintf("word%d word%d hotel river %d", i%7, i%11, i))
+		}
+		return svc
+	}
+	serial, parallel := build(1), build(8)
+
+	for _, q := range []string{
+		"word1 word2 word3 word4 word5 word6",
+		"(word1 OR word3) AND NOT word5",
+		`hotel "hotel river" word2`,
+	} {
+		want, err := serial.SearchDocuments(ctx, q, 0)
+		if err != nil {
+			t.Fatalf("serial SearchDocuments(%q) error = %v", q, err)
+		}
+		got, err := parallel.SearchDocuments(ctx, q, 0)
+		if err != nil {
+			t.Fatalf("parallel SearchDocuments(%q) error = %v", q, err)
+		}
+		if !reflect.DeepEqual(got.Results, want.Results) {
+			t.Fatalf("query %q: parallel results differ from serial\n got %+v\nwant %+v", q, got.Results, want.Results)
+		}
+	}
+}
+
+func TestSearchWorkersLongQuery(t *testing.T) {
+	ctx := context.Background()
+	build := func(workers int) *Service {
+		svc := New(newPostingIndex(), WordKeys, WithScorer(NewBM25()), WithSearchWorkers(workers))
+		for i := range 200 {
+			_ = svc.IndexDocument(ctx, DocID(fmt.Sprintf("doc-%03d", i)), fmt.Sprintf("word%d word%d word%d", i, i%13, i%29))
+		}
+		return svc
+	}
+	serial, parallel := build(1), build(4)
+
+	words := make([]string, 500)
+	for i := range words {
+		words[i] = fmt.Sprintf("word%d", i*3)
+	}
+	q := strings.Join(words, " ")
+
+	want, err := serial.SearchDocuments(ctx, q, 0)
+	if err != nil {
+		t.Fatalf("serial SearchDocuments() error = %v", err)
+	}
+	got, err := parallel.SearchDocuments(ctx, q, 0)
+	if err != nil {
+		t.Fatalf("parallel SearchDocuments() error = %v", err)
+	}
+	if got.TotalResultsCount == 0 || !reflect.DeepEqual(got.Results, want.Results) {
+		t.Fatalf("parallel results differ from serial: got %d results, want %d", got.TotalResultsCount, want.TotalResultsCount)
+	}
+}
+
+type batchIndex struct {
+	*postingIndex
+	batches  [][]string
+	searches int
+}
+
+func (b *batchIndex) Search(key string) ([]DocRef, error) {
+	b.searches++
+	return b.postingIndex.Search(key)
+}
+
+func (b *batchIndex) SearchBatch(keys []string) (map[string][]DocRef, error) {
+	b.batches = append(b.batches, keys)
+	out := make(map[string][]DocRef)
+	for _, key := range keys {
+		if docs, _ := b.postingIndex.Search(key); len(docs) > 0 {
+			out[key] = docs
+		}
+	}
+	return out, nil
+}
+
+func TestSearchUsesOneBatchPerQuery(t *testing.T) {
+	ctx := context.Background()
+	idx := &batchIndex{postingIndex: newPostingIndex()}
+	svc := New(idx, WordKeys, WithPositions(), WithSearchWorkers(1))
+	_ = svc.IndexDocument(ctx, "doc-1", "grand hotel river barge")
+
+	res, err := svc.SearchDocuments(ctx, `hotel missing "river barge"`, 10)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	if len(res.Results) != 1 {
+		t.Fatalf("results = %+v, want doc-1", res.Results)
+	}
+	if len(idx.batches) != 1 || len(idx.batches[0]) != 4 || idx.searches != 0 {
+		t.Fatalf("batches = %q, searches = %d, want one batch of 4 keys and no single lookups", idx.batches, idx.searches)
+	}
+}
+
+func TestChunkKeys(t *testing.T) {
+	keys := []string{"a", "b", "c", "d", "e"}
+
+	for _, tt := range []struct {
+		n    int
+		want [][]string
+	}{
+		{n: 1, want: [][]string{keys}},
+		{n: 2, want: [][]string{{"a", "b", "c"}, {"d", "e"}}},
+		{n: 8, want: [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}},
+	} {
+		if got := chunkKeys(keys, tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("chunkKeys(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+	if got := chunkKeys(nil, 4); got != nil {
+		t.Fatalf("chunkKeys(nil) = %q, want nil", got)
+	}
+}
+
+type failingIndex struct {
+	*memoryIndex
+	failKey string
+}
+
+func (f failingIndex) Search(key string) ([]DocRef, error) {
+	if key == f.failKey {
+		return nil, errors.New("disk error")
+	}
+	return f.memoryIndex.Search(key)
+}
+
+func TestPrefetchReturnsLookupError(t *testing.T) {
+	idx := failingIndex{memoryIndex: newMemoryIndex(), failKey: "barge"}
+	svc := New(idx, WordKeys, WithSearchWorkers(4))
+
+	_, err := svc.SearchDocuments(context.Background(), "hotel barge river", 10)
+	if err == nil || !strings.Contains(err.Error(), "disk error") {
+		t.Fatalf("SearchDocuments() error = %v, want disk error", err)
+	}
+}
+
+func TestQueryKeysAreDistinctAndOrdered(t *testing.T) {
+	svc := New(newMemoryIndex(), WordKeys, WithFields("title", "abstract"))
+	root, err := query.Parse(`hotel OR (river AND NOT hotel) "barge river"`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	keys, err := svc.queryKeys(root, false)
+	if err != nil {
+		t.Fatalf("queryKeys() error = %v", err)
+	}
+	want := []string{
+		fieldKey("title", "hotel"), fieldKey("abstract", "hotel"),
+		fieldKey("title", "river"), fieldKey("abstract", "river"),
+		fieldKey("title", "barge"), fieldKey("abstract", "barge"),
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("queryKeys() = %q, want %q", keys, want)
+	}
+
+	if keys, err = svc.queryKeys(root, true); err != nil {
+		t.Fatalf("queryKeys() error = %v", err)
+	}
+	want = []string{
+		fieldKey("title", "barge"), fieldKey("abstract", "barge"),
+		fieldKey("title", "river"), fieldKey("abstract", "river"),
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("queryKeys(phrasesOnly) = %q, want %q", keys, want)
+	}
+}
@@ -0,0 +1,374 @@
+package fts
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/dariasmyr/fts-engine/pkg/query"
+)
+
+var (
+	ErrNegatedQuery = errors.New("fts: query has no term outside NOT")
+	ErrUnknownField = errors.New("fts: query names an unknown field")
+)
+
+// docSet is the result of a query node. A complement set stands for every
+// document except docs; a neutral set comes from terms the pipeline drops
+// entirely (stop words) and leaves the other operand unchanged.
+type docSet struct {
+	docs       map[DocID]struct{}
+	complement bool
+	neutral    bool
+}
+
+func parseQuery(text string) (query.Node, error) {
+	return query.Parse(text)
+}
+
+// queryTokens returns the processed words of root, phrase words included, in
+// query order. Negated words are included when negated is set.
+func (s *Service) queryTokens(root query.Node, negated bool) []string {
+	var tokens []string
+	var walk func(node query.Node)
+	walk = func(node query.Node) {
+		switch n := node.(type) {
+		case query.Term:
+			tokens = append(tokens, s.pipeline.Process(n.Text)...)
+		case query.Phrase:
+			tokens = append(tokens, s.pipeline.Process(n.Text)...)
+		case query.Not:
+			if negated {
+				walk(n.Operand)
+			}
+		case query.And:
+			walk(n.Left)
+			walk(n.Right)
+		case query.Or:
+			walk(n.Left)
+			walk(n.Right)
+		}
+	}
+	if root != nil {
+		walk(root)
+	}
+	return tokens
+}
+
+// hasNegation reports whether root has a NOT node.
+func hasNegation(root query.Node) bool {
+	switch n := root.(type) {
+	case query.Not:
+		return true
+	case query.And:
+		return hasNegation(n.Left) || hasNegation(n.Right)
+	case query.Or:
+		return hasNegation(n.Left) || hasNegation(n.Right)
+	}
+	return false
+}
+
+// matchMode narrows which documents a query token matches.
+type matchMode struct {
+	allKeys bool // the document has every key of the token, see docsWithAllKeys
+	exact   bool // the document has the whole token, see WithExactWords
+	// within, when set, holds the only documents that may match, such as
+	// those containing the phrase the token belongs to.
+	within map[DocID]struct{}
+}
+
+// queryLookup is how evalQuery reads postings. Terms go through terms, which
+// may read the postings of every key under a prefix. Phrases need the
+// positions of whole keys, so they always go through phrases.
+type queryLookup struct {
+	terms, phrases postingLookup
+}
+
+// evalQuery resolves node to a document set. Postings of terms that are not
+// negated are recorded in matches so they take part in ranking.
+func (s *Service) evalQuery(node query.Node, lookup queryLookup, matches map[DocID]*DocMatch, negated bool, mode matchMode) (docSet, error) {
+	switch n := node.(type) {
+	case query.Term:
+		fields, err := s.termFields(n.Field)
+		if err != nil {
+			return docSet{}, err
+		}
+		tokens := s.pipeline.Process(n.Text)
+		if len(tokens) == 0 {
+			return docSet{neutral: true}, nil
+		}
+
+		docs := make(map[DocID]struct{})
+		for i, token := range tokens {
+			// A repeated token finds the same documents and is not counted
+			// again, so there is no need to match it twice.
+			if slices.Contains(tokens[:i], token) {
+				continue
+			}
+			found, err := s.matchToken(token, fields, lookup.terms, matches, !negated, mode)
+			if err != nil {
+				return docSet{}, err
+			}
+			for id := range found {
+				docs[id] = struct{}{}
+			}
+		}
+		return docSet{docs: docs}, nil
+
+	case query.Phrase:
+		fields, err := s.termFields(n.Field)
+		if err != nil {
+			return docSet{}, err
+		}
+		tokens := s.phraseTokens(n.Text)
+		if len(tokens) == 0 {
+			return docSet{neutral: true}, nil
+		}
+		if !s.hasPositions() {
+			return docSet{}, ErrPhraseUnsupported
+		}
+
+		phraseLookup := s.phraseLookup(lookup.phrases)
+		docs, err := s.phraseDocs(tokens, fields, phraseLookup)
+		if err != nil {
+			return docSet{}, fmt.Errorf("phrase: %w", err)
+		}
+		if negated || len(docs) == 0 {
+			return docSet{docs: docs}, nil
+		}
+		// Only the documents holding the phrase rank on its words.
+		mode.within = docs
+		if s.phrasePipeline != nil {
+			mode.exact = false
+		}
+		for i, token := range tokens {
+			if slices.Contains(tokens[:i], token) {
+				continue
+			}
+			if _, err := s.matchToken(token, fields, phraseLookup, matches, true, mode); err != nil {
+				return docSet{}, err
+			}
+		}
+		return docSet{docs: docs}, nil
+
+	case query.Not:
+		operand, err := s.evalQuery(n.Operand, lookup, matches, !negated, mode)
+		if err != nil {
+			return docSet{}, err
+		}
+		if !operand.neutral {
+			operand.complement = !operand.complement
+		}
+		return operand, nil
+
+	case query.And:
+		left, right, err := s.evalOperands(n.Left, n.Right, lookup, matches, negated, mode)
+		if err != nil {
+			return docSet{}, err
+		}
+		return intersect(left, right), nil
+
+	case query.Or:
+		left, right, err := s.evalOperands(n.Left, n.Right, lookup, matches, negated, mode)
+		if err != nil {
+			return docSet{}, err
+		}
+		return union(left, right), nil
+
+	default:
+		return docSet{}, fmt.Errorf("fts: unsupported query node %T", node)
+	}
+}
+
+func (s *Service) evalOperands(left, right query.Node, lookup queryLookup, matches map[DocID]*DocMatch, negated bool, mode matchMode) (docSet, docSet, error) {
+	l, err := s.evalQuery(left, lookup, matches, negated, mode)
+	if err != nil {
+		return docSet{}, docSet{}, err
+	}
+	r, err := s.evalQuery(right, lookup, matches, negated, mode)
+	if err != nil {
+		return docSet{}, docSet{}, err
+	}
+	return l, r, nil
+}
+
+// termFields returns the fields a term scoped to field is matched in: all
+// fields for an unscoped term, and otherwise field alone.
+func (s *Service) termFields(field string) ([]string, error) {
+	if field == "" {
+		return s.fields, nil
+	}
+	if !s.weighted() || !s.hasField(field) {
+		return nil, fmt.Errorf("%w %q", ErrUnknownField, field)
+	}
+	return []string{field}, nil
+}
+
+// matchToken returns the documents containing any key of token in any of
+// fields and, when record is set, adds their postings to matches. A key found
+// in several fields of a document counts as one unique match. mode can narrow
+// the matching documents further.
+func (s *Service) matchToken(token string, fields []string, lookup postingLookup, matches map[DocID]*DocMatch, record bool, mode matchMode) (map[DocID]struct{}, error) {
+	keys, err := s.keyGen(token)
+	if err != nil {
+		return nil, fmt.Errorf("keygen: %w", err)
+	}
+
+	var confirmed map[DocID]struct{}
+	if mode.allKeys && len(keys) > 1 {
+		if confirmed, err = s.docsWithAllKeys(keys, fields, lookup); err != nil {
+			return nil, fmt.Errorf("index search: %w", err)
+		}
+	}
+
+	found := make(map[DocID]struct{})
+	for _, key := range keys {
+		for _, field := range fields {
+			docs, err := lookup(fieldKey(field, key))
+			if err != nil {
+				return nil, fmt.Errorf("index search: %w", err)
+			}
+
+			for _, doc := range docs {
+				if mode.within != nil {
+					if _, ok := mode.within[doc.ID]; !ok {
+						continue
+					}
+				}
+				if confirmed != nil {
+					if _, ok := confirmed[doc.ID]; !ok {
+						continue
+					}
+				}
+				if mode.exact && !s.hasWord(doc.ID, field, token) {
+					continue
+				}
+				found[doc.ID] = struct{}{}
+				if !record {
+					continue
+				}
+				match := matchFor(matches, doc.ID)
+				match.matched(token)
+				if !match.count(key, field) {
+					continue
+				}
+				match.TotalMatches += int(doc.Count)
+				if s.keepTerms() {
+					match.Terms = append(match.Terms, TermMatch{Key: key, Field: field, TermFreq: doc.Count, DocFreq: len(docs)})
+				}
+			}
+		}
+	}
+
+	return found, nil
+}
+
+// matched records that token was found in the document.
+func (m *DocMatch) matched(token string) {
+	if !slices.Contains(m.tokens, token) {
+		m.tokens = append(m.tokens, token)
+	}
+}
+
+// docsWithAllKeys returns the documents that have every one of keys in one
+// of fields. When positions are stored, the keys must also share a position,
+// so n-grams of one query word are not matched across different words.
+func (s *Service) docsWithAllKeys(keys []string, fields []string, lookup postingLookup) (map[DocID]struct{}, error) {
+	docs := make(map[DocID]struct{})
+	for _, field := range fields {
+		inField := fieldLookup(lookup, field)
+
+		if s.hasPositions() {
+			positions, err := s.keyPositions(keys, inField)
+			if err != nil {
+				return nil, err
+			}
+			for id := range positions {
+				docs[id] = struct{}{}
+			}
+			continue
+		}
+
+		var common map[DocID]struct{}
+		for i, key := range keys {
+			postings, err := inField(key)
+			if err != nil {
+				return nil, err
+			}
+			next := make(map[DocID]struct{}, len(postings))
+			for _, doc := range postings {
+				if _, ok := common[doc.ID]; ok || i == 0 {
+					next[doc.ID] = struct{}{}
+				}
+			}
+			if common = next; len(common) == 0 {
+				break
+			}
+		}
+		for id := range common {
+			docs[id] = struct{}{}
+		}
+	}
+	return docs, nil
+}
+
+func intersect(a, b docSet) docSet {
+	switch {
+	case a.neutral:
+		return b
+	case b.neutral:
+		return a
+	case a.complement && b.complement:
+		return docSet{docs: merge(a.docs, b.docs), complement: true}
+	case a.complement:
+		return docSet{docs: subtract(b.docs, a.docs)}
+	case b.complement:
+		return docSet{docs: subtract(a.docs, b.docs)}
+	}
+
+	out := make(map[DocID]struct{})
+	for id := range a.docs {
+		if _, ok := b.docs[id]; ok {
+			out[id] = struct{}{}
+		}
+	}
+	return docSet{docs: out}
+}
+
+func union(a, b docSet) docSet {
+	switch {
+	case a.neutral:
+		return b
+	case b.neutral:
+		return a
+	case a.complement && b.complement:
+		return docSet{docs: intersect(docSet{docs: a.docs}, docSet{docs: b.docs}).docs, complement: true}
+	case a.complement:
+		return docSet{docs: subtract(a.docs, b.docs), complement: true}
+	case b.complement:
+		return docSet{docs: subtract(b.docs, a.docs), complement: true}
+	}
+
+	return docSet{docs: merge(a.docs, b.docs)}
+}
+
+func merge(a, b map[DocID]struct{}) map[DocID]struct{} {
+	out := make(map[DocID]struct{}, len(a)+len(b))
+	for id := range a {
+		out[id] = struct{}{}
+	}
+	for id := range b {
+		out[id] = struct{}{}
+	}
+	return out
+}
+
+func subtract(a, b map[DocID]struct{}) map[DocID]struct{} {
+	out := make(map[DocID]struct{}, len(a))
+	for id := range a {
+		if _, ok := b[id]; !ok {
+			out[id] = struct{}{}
+		}
+	}
+	return out
+}
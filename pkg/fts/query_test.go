@@ -0,0 +1,220 @@
+package fts
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/dariasmyr/fts-engine/pkg/keygen"
+	"github.com/dariasmyr/fts-engine/pkg/query"
+)
+
+// booleanDocs are the documents the boolean query tests search.
+var booleanDocs = map[DocID]string{
+	"hotel":        "hotel in copenhagen",
+	"danish-hotel": "danish hotel",
+	"barge":        "barge on the river",
+}
+
+func resultIDs(res *SearchResult) []DocID {
+	ids := make([]DocID, 0, len(res.Results))
+	for _, r := range res.Results {
+		ids = append(ids, r.ID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestSearchDocumentsBooleanOperators(t *testing.T) {
+	svc := indexDocs(t, New(newPostingIndex(), WordKeys), booleanDocs)
+
+	tests := []struct {
+		query string
+		want  []DocID
+	}{
+		{query: "hotel AND NOT danish", want: []DocID{"hotel"}},
+		{query: "hotel AND danish", want: []DocID{"danish-hotel"}},
+		{query: "hotel barge", want: []DocID{"barge", "danish-hotel", "hotel"}},
+		{query: "(hotel OR barge) NOT danish", want: []DocID{"barge", "hotel"}},
+		{query: "the AND barge", want: []DocID{"barge"}},
+	}
+
+	for _, tt := range tests {
+		res, err := svc.SearchDocuments(context.Background(), tt.query, 10)
+		if err != nil {
+			t.Fatalf("SearchDocuments(%q) error = %v", tt.query, err)
+		}
+		if got := resultIDs(res); !slices.Equal(got, tt.want) {
+			t.Fatalf("SearchDocuments(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchDocumentsNegatedTermsAreNotCounted(t *testing.T) {
+	svc := indexDocs(t, New(newPostingIndex(), WordKeys), booleanDocs)
+
+	res, err := svc.SearchDocuments(context.Background(), "hotel NOT barge", 10)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	for _, r := range res.Results {
+		if r.UniqueMatches != 1 {
+			t.Fatalf("result %q UniqueMatches = %d, want 1", r.ID, r.UniqueMatches)
+		}
+	}
+}
+
+func TestSearchUniqueMatchesCountDistinctKeys(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		svc    *Service
+		query  string
+		id     DocID
+		unique int
+	}{
+		{name: "repeated word", svc: indexDocs(t, New(newPostingIndex(), WordKeys), booleanDocs), query: "hotel hotel", id: "hotel", unique: 1},
+		{name: "repeated operand", svc: indexDocs(t, New(newPostingIndex(), WordKeys), booleanDocs), query: "hotel OR (hotel AND copenhagen)", id: "hotel", unique: 2},
+		// "aaaa" yields the trigram "aaa" twice.
+		{name: "repeated n-gram", svc: indexDocs(t, New(newPostingIndex(), keygen.Trigram), trigramDocs), query: "aaaa", id: "aaa", unique: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = tt.svc.IndexDocument(ctx, "aaa", "aaa")
+			res, err := tt.svc.Search(ctx, tt.query, SearchOptions{})
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			for _, r := range res.Results {
+				if r.ID == tt.id && (r.UniqueMatches != tt.unique || r.TotalMatches != tt.unique) {
+					t.Fatalf("%s: UniqueMatches %d TotalMatches %d, want %d", r.ID, r.UniqueMatches, r.TotalMatches, tt.unique)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchDocumentsMalformedQuery(t *testing.T) {
+	svc := indexDocs(t, New(newPostingIndex(), WordKeys), booleanDocs)
+
+	_, err := svc.SearchDocuments(context.Background(), "hotel AND", 10)
+	if !errors.Is(err, query.ErrSyntax) {
+		t.Fatalf("SearchDocuments() error = %v, want query.ErrSyntax", err)
+	}
+}
+
+func TestSearchDocumentsOnlyNegatedTerms(t *testing.T) {
+	svc := indexDocs(t, New(newPostingIndex(), WordKeys), booleanDocs)
+
+	_, err := svc.SearchDocuments(context.Background(), "NOT danish", 10)
+	if !errors.Is(err, ErrNegatedQuery) {
+		t.Fatalf("SearchDocuments() error = %v, want ErrNegatedQuery", err)
+	}
+}
+
+// trigramDocs share trigrams of "hotel" without all holding the word.
+var trigramDocs = map[DocID]string{
+	"hotel":    "grand hotel",
+	"hot-dog":  "hot dog stand",
+	"scramble": "hotter otel",
+}
+
+func TestSearchRequireAllKeys(t *testing.T) {
+	ctx := context.Background()
+	svc := indexDocs(t, New(newPostingIndex(), keygen.Trigram), trigramDocs)
+
+	res, err := svc.Search(ctx, "hotel", SearchOptions{})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if got := resultIDs(res); !slices.Equal(got, []DocID{"hot-dog", "hotel", "scramble"}) {
+		t.Fatalf("any key: results = %v", got)
+	}
+
+	// "hot dog" shares only the "hot" trigram with "hotel".
+	res, err = svc.Search(ctx, "hotel", SearchOptions{RequireAllKeys: true})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if got := resultIDs(res); !slices.Equal(got, []DocID{"hotel", "scramble"}) {
+		t.Fatalf("all keys: results = %v", got)
+	}
+	for _, r := range res.Results {
+		if r.ID == "hotel" && r.UniqueMatches != 3 {
+			t.Fatalf("hotel UniqueMatches = %d, want 3", r.UniqueMatches)
+		}
+	}
+}
+
+func TestSearchRequireAllKeysInOneWord(t *testing.T) {
+	svc := indexDocs(t, New(newPostingIndex(), keygen.Trigram, WithPositions()), trigramDocs)
+
+	// With positions the trigrams must come from one word, which rules out
+	// "hotter otel" as well.
+	res, err := svc.Search(context.Background(), "hotel", SearchOptions{RequireAllKeys: true})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if got := resultIDs(res); !slices.Equal(got, []DocID{"hotel"}) {
+		t.Fatalf("results = %v, want [hotel]", got)
+	}
+}
+
+func TestSearchRequireAllKeysWordKeys(t *testing.T) {
+	svc := indexDocs(t, New(newPostingIndex(), WordKeys), booleanDocs)
+
+	res, err := svc.Search(context.Background(), "hotel OR barge", SearchOptions{RequireAllKeys: true})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if got := resultIDs(res); !slices.Equal(got, []DocID{"barge", "danish-hotel", "hotel"}) {
+		t.Fatalf("results = %v", got)
+	}
+}
+
+func TestSearchReportsMatchedTerms(t *testing.T) {
+	tests := []struct {
+		name  string
+		svc   *Service
+		query string
+		want  map[DocID][]string
+	}{
+		{
+			name:  "words",
+			svc:   indexDocs(t, New(newPostingIndex(), WordKeys), booleanDocs),
+			query: "danish hotel NOT river",
+			want: map[DocID][]string{
+				"hotel":        {"hotel"},
+				"danish-hotel": {"danish", "hotel"},
+			},
+		},
+		{
+			name:  "trigrams",
+			svc:   indexDocs(t, New(newPostingIndex(), keygen.Trigram), trigramDocs),
+			query: "hotel dog",
+			want: map[DocID][]string{
+				"hotel":    {"hotel"},
+				"hot-dog":  {"hotel", "dog"},
+				"scramble": {"hotel"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		res, err := tt.svc.SearchDocuments(context.Background(), tt.query, 0)
+		if err != nil {
+			t.Fatalf("%s: SearchDocuments() error = %v", tt.name, err)
+		}
+		got := make(map[DocID][]string, len(res.Results))
+		for _, r := range res.Results {
+			got[r.ID] = r.MatchedTerms
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%s: matched terms = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
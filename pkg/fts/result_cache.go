@@ -0,0 +1,178 @@
+package fts
+
+import (
+	"container/list"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// WithResultCache makes Search remember the results of the last capacity
+// distinct searches, evicting the least recently used one. A repeated search
+// then skips the index. Only the page of results is kept, not documents.
+// Indexing or deleting any document empties the cache. capacity <= 0 turns
+// it off, which is the default.
+func WithResultCache(capacity int) Option {
+	return func(s *Service) {
+		s.results = nil
+		if capacity > 0 {
+			s.results = newResultCache(capacity)
+		}
+	}
+}
+
+// ResultCacheStats reports how the result cache has been used.
+type ResultCacheStats struct {
+	Capacity  int
+	Entries   int
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// ResultCacheStats returns the result cache counters. ok is false for a
+// service built without WithResultCache.
+func (s *Service) ResultCacheStats() (stats ResultCacheStats, ok bool) {
+	if s.results == nil {
+		return ResultCacheStats{}, false
+	}
+	return s.results.stats(), true
+}
+
+// resultKey identifies a search. Queries differing only in white space
+// between words share a key.
+type resultKey struct {
+	query          string
+	offset         int
+	limit          int
+	weights        string
+	requireAllKeys bool
+	descendants    bool
+	exact          bool
+	suggestBelow   int
+}
+
+func newResultKey(query string, opts SearchOptions) resultKey {
+	key := resultKey{
+		query:          strings.Join(strings.Fields(query), " "),
+		offset:         opts.Offset,
+		limit:          opts.Limit,
+		requireAllKeys: opts.RequireAllKeys,
+		descendants:    opts.Descendants,
+		exact:          opts.Exact,
+		suggestBelow:   opts.SuggestBelow,
+	}
+	if opts.FieldWeights != nil {
+		// fmt prints maps in key order.
+		key.weights = fmt.Sprint(map[string]float64(opts.FieldWeights))
+	}
+	return key
+}
+
+type resultEntry struct {
+	key    resultKey
+	result SearchResult
+}
+
+// resultCache is an LRU of search results. gen counts the changes to the
+// index, so a search that ran while one was made is not stored.
+type resultCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[resultKey]*list.Element
+	// order holds *resultEntry values, most recently used first.
+	order *list.List
+	gen   uint64
+
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+func newResultCache(capacity int) *resultCache {
+	return &resultCache{
+		capacity: capacity,
+		entries:  make(map[resultKey]*list.Element, capacity),
+		order:    list.New(),
+	}
+}
+
+// get returns a copy of the result cached for key. On a miss it returns the
+// generation to pass to put.
+func (c *resultCache) get(key resultKey) (result *SearchResult, gen uint64, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, c.gen, false
+	}
+	c.hits++
+	c.order.MoveToFront(elem)
+
+	cached := elem.Value.(*resultEntry).result
+	cached.Results = slices.Clone(cached.Results)
+	return &cached, c.gen, true
+}
+
+// put stores result under key unless the index has changed since get
+// returned gen.
+func (c *resultCache) put(key resultKey, gen uint64, result *SearchResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+	entry := &resultEntry{key: key, result: *result}
+	entry.result.Results = slices.Clone(result.Results)
+	entry.result.Timings = nil
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*resultEntry).key)
+		c.evictions++
+	}
+}
+
+// invalidate drops every entry and fails the puts of searches in flight.
+func (c *resultCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	if c.order.Len() > 0 {
+		clear(c.entries)
+		c.order.Init()
+	}
+}
+
+func (c *resultCache) stats() ResultCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return ResultCacheStats{
+		Capacity:  c.capacity,
+		Entries:   c.order.Len(),
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+	}
+}
+
+// invalidateResults empties the result cache, if any. Every change to the
+// index calls it once the change is done.
+func (s *Service) invalidateResults() {
+	if s.results != nil {
+		s.results.invalidate()
+	}
+}
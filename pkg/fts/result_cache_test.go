@@ -0,0 +1,132 @@
+package fts
+
+import (
+	"context"
+	"testing"
+)
+
+// cachedDocs are the documents the result cache tests search.
+var cachedDocs = map[DocID]string{
+	"doc-1": "grand hotel by the river",
+	"doc-2": "hotel with a barge",
+}
+
+func cacheStats(t *testing.T, svc *Service) ResultCacheStats {
+	t.Helper()
+
+	stats, ok := svc.ResultCacheStats()
+	if !ok {
+		t.Fatal("ResultCacheStats() ok = false, want a cache")
+	}
+	return stats
+}
+
+func TestResultCacheHit(t *testing.T) {
+	svc := indexDocs(t, New(newPostingIndex(), WordKeys, WithResultCache(8)), cachedDocs)
+	ctx := context.Background()
+
+	first, err := svc.SearchDocuments(ctx, "hotel", 10)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	first.Results[0].ID = "changed by the caller"
+
+	second, err := svc.SearchDocuments(ctx, "  hotel ", 10)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	if len(second.Results) != 2 || second.Results[0].ID != "doc-1" || second.TotalResultsCount != 2 {
+		t.Fatalf("cached results = %+v, want both documents as first ranked", second.Results)
+	}
+	if _, ok := second.Timings["total"]; !ok {
+		t.Fatalf("Timings = %v, want a total", second.Timings)
+	}
+
+	if stats := cacheStats(t, svc); stats.Hits != 1 || stats.Misses != 1 || stats.Entries != 1 {
+		t.Fatalf("stats = %+v, want 1 hit, 1 miss and 1 entry", stats)
+	}
+}
+
+func TestResultCacheKeysOnOptions(t *testing.T) {
+	svc := indexDocs(t, New(newPostingIndex(), WordKeys, WithResultCache(8)), cachedDocs)
+	ctx := context.Background()
+
+	for _, opts := range []SearchOptions{{Limit: 1}, {Limit: 1, Offset: 1}, {Limit: 2}} {
+		if _, err := svc.Search(ctx, "hotel", opts); err != nil {
+			t.Fatalf("Search(%+v) error = %v", opts, err)
+		}
+	}
+	if stats := cacheStats(t, svc); stats.Hits != 0 || stats.Entries != 3 {
+		t.Fatalf("stats = %+v, want every page cached apart", stats)
+	}
+}
+
+func TestResultCacheMissAfterChange(t *testing.T) {
+	svc := indexDocs(t, New(newPostingIndex(), WordKeys, WithResultCache(8)), cachedDocs)
+	ctx := context.Background()
+
+	if _, err := svc.SearchDocuments(ctx, "hotel", 10); err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	if err := svc.IndexDocument(ctx, "doc-3", "small hotel"); err != nil {
+		t.Fatalf("IndexDocument() error = %v", err)
+	}
+	result, err := svc.SearchDocuments(ctx, "hotel", 10)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	if len(result.Results) != 3 {
+		t.Fatalf("results = %+v, want the new document too", result.Results)
+	}
+
+	if err := svc.DeleteDocument(ctx, "doc-1"); err != nil {
+		t.Fatalf("DeleteDocument() error = %v", err)
+	}
+	if result, err = svc.SearchDocuments(ctx, "hotel", 10); err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	if len(result.Results) != 2 {
+		t.Fatalf("results = %+v, want the deleted document gone", result.Results)
+	}
+
+	if stats := cacheStats(t, svc); stats.Hits != 0 || stats.Misses != 3 {
+		t.Fatalf("stats = %+v, want every search to miss", stats)
+	}
+}
+
+func TestResultCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	svc := indexDocs(t, New(newPostingIndex(), WordKeys, WithResultCache(2)), cachedDocs)
+	ctx := context.Background()
+
+	for _, query := range []string{"hotel", "river", "hotel", "barge", "hotel", "river"} {
+		if _, err := svc.SearchDocuments(ctx, query, 10); err != nil {
+			t.Fatalf("SearchDocuments(%q) error = %v", query, err)
+		}
+	}
+
+	// "river" is evicted by "barge" as the least recently used, so only the
+	// repeated "hotel" searches hit.
+	if stats := cacheStats(t, svc); stats.Hits != 2 || stats.Misses != 4 || stats.Evictions != 2 || stats.Entries != 2 {
+		t.Fatalf("stats = %+v, want 2 hits, 4 misses, 2 evictions and 2 entries", stats)
+	}
+}
+
+func TestResultCacheDropsStaleResult(t *testing.T) {
+	c := newResultCache(4)
+	key := newResultKey("hotel", SearchOptions{})
+
+	_, gen, _ := c.get(key)
+	c.invalidate()
+	c.put(key, gen, &SearchResult{Results: []Result{{ID: "doc-1"}}})
+
+	if _, _, ok := c.get(key); ok {
+		t.Fatal("get() ok = true, want the result of a search that overlapped a change dropped")
+	}
+}
+
+func TestResultCacheDisabled(t *testing.T) {
+	svc := New(newMemoryIndex(), WordKeys, WithResultCache(0))
+	if _, ok := svc.ResultCacheStats(); ok {
+		t.Fatal("ResultCacheStats() ok = true, want no cache")
+	}
+}
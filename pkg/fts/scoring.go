@@ -0,0 +1,106 @@
+package fts
+
+import "math"
+
+// Scorer ranks a matched document. Results are ordered by descending score;
+// a Service without a Scorer keeps the UniqueMatches/TotalMatches ordering.
+type Scorer interface {
+	Score(corpus CorpusStats, doc DocMatch) float64
+}
+
+// CorpusStats describes the documents indexed through the Service.
+// Indexes restored from a snapshot start with empty stats.
+type CorpusStats struct {
+	TotalDocs    int
+	AvgDocLength float64
+}
+
+type DocMatch struct {
+	ID            DocID
+	UniqueMatches int
+	TotalMatches  int
+	DocLength     int
+	Terms         []TermMatch
+
+	// keys and fieldKeys are what count has recorded.
+	keys      map[string]struct{}
+	fieldKeys map[string]struct{}
+	// tokens are the distinct query tokens that matched, in query order.
+	tokens []string
+}
+
+type TermMatch struct {
+	Key string
+	// Field is the document field the key matched in; empty for unnamed fields.
+	Field    string
+	TermFreq uint32
+	DocFreq  int
+}
+
+const (
+	DefaultBM25K1 = 1.2
+	DefaultBM25B  = 0.75
+)
+
+// BM25 is the Okapi BM25 scorer. K1 controls term frequency saturation and
+// B the strength of document length normalization.
+type BM25 struct {
+	K1 float64
+	B  float64
+}
+
+func NewBM25() BM25 {
+	return BM25{K1: DefaultBM25K1, B: DefaultBM25B}
+}
+
+func (s BM25) Score(corpus CorpusStats, doc DocMatch) float64 {
+	norm := 1.0
+	if corpus.AvgDocLength > 0 && doc.DocLength > 0 {
+		norm = 1 - s.B + s.B*float64(doc.DocLength)/corpus.AvgDocLength
+	}
+
+	var score float64
+	for _, term := range doc.Terms {
+		docFreq := float64(term.DocFreq)
+		totalDocs := math.Max(float64(corpus.TotalDocs), docFreq)
+		idf := math.Log(1 + (totalDocs-docFreq+0.5)/(docFreq+0.5))
+
+		tf := float64(term.TermFreq)
+		score += idf * tf * (s.K1 + 1) / (tf + s.K1*norm)
+	}
+
+	return score
+}
+
+// DefaultUniqueWeight makes one more unique match outweigh any realistic
+// difference in total matches, which reproduces the default ordering.
+const DefaultUniqueWeight = 1e6
+
+// MatchWeights scores a document as Unique*unique matches + Total*total
+// matches. It is a lighter alternative to BM25 for when raw term frequency
+// should count for more, or less, than the default ordering gives it.
+type MatchWeights struct {
+	Unique float64
+	Total  float64
+}
+
+func NewMatchWeights() MatchWeights {
+	return MatchWeights{Unique: DefaultUniqueWeight, Total: 1}
+}
+
+// Score counts the matches in doc.Terms when there are any, so each field of
+// a fielded service scores only its own matches. Matches without term
+// details, as from n-gram fuzzy search, use the document counts.
+func (w MatchWeights) Score(_ CorpusStats, doc DocMatch) float64 {
+	unique, total := doc.UniqueMatches, doc.TotalMatches
+	if len(doc.Terms) > 0 {
+		keys := make(map[string]struct{}, len(doc.Terms))
+		total = 0
+		for _, term := range doc.Terms {
+			keys[term.Key] = struct{}{}
+			total += int(term.TermFreq)
+		}
+		unique = len(keys)
+	}
+	return w.Unique*float64(unique) + w.Total*float64(total)
+}
@@ -0,0 +1,140 @@
+package fts
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestBM25RareTermScoresHigher(t *testing.T) {
+	scorer := NewBM25()
+	corpus := CorpusStats{TotalDocs: 100, AvgDocLength: 10}
+
+	rare := scorer.Score(corpus, DocMatch{DocLength: 10, Terms: []TermMatch{{Key: "barge", TermFreq: 1, DocFreq: 2}}})
+	common := scorer.Score(corpus, DocMatch{DocLength: 10, Terms: []TermMatch{{Key: "hotel", TermFreq: 1, DocFreq: 80}}})
+
+	if rare <= common {
+		t.Fatalf("rare score = %v, common score = %v, want rare > common", rare, common)
+	}
+}
+
+func TestBM25WithoutCorpusStats(t *testing.T) {
+	scorer := NewBM25()
+
+	score := scorer.Score(CorpusStats{}, DocMatch{Terms: []TermMatch{{Key: "hotel", TermFreq: 2, DocFreq: 3}}})
+	if score <= 0 {
+		t.Fatalf("Score() = %v, want > 0", score)
+	}
+}
+
+func TestRepeatedQueryWordsDoNotRaiseScore(t *testing.T) {
+	ctx := context.Background()
+
+	for name, scorer := range map[string]Scorer{"default": nil, "bm25": NewBM25()} {
+		t.Run(name, func(t *testing.T) {
+			var opts []Option
+			if scorer != nil {
+				opts = append(opts, WithScorer(scorer))
+			}
+			svc := New(newPostingIndex(), WordKeys, opts...)
+			_ = svc.IndexDocument(ctx, "doc-1", "grand hotel by the river")
+			_ = svc.IndexDocument(ctx, "doc-2", "hotel")
+
+			once, err := svc.SearchDocuments(ctx, "hotel river", 10)
+			if err != nil {
+				t.Fatalf("SearchDocuments() error = %v", err)
+			}
+			for _, query := range []string{"hotel hotel hotel river", "hotel river hotel", "(hotel OR hotel) river"} {
+				repeated, err := svc.SearchDocuments(ctx, query, 10)
+				if err != nil {
+					t.Fatalf("SearchDocuments(%q) error = %v", query, err)
+				}
+				if len(repeated.Results) != len(once.Results) {
+					t.Fatalf("%q: %d results, want %d", query, len(repeated.Results), len(once.Results))
+				}
+				for i, want := range once.Results {
+					got := repeated.Results[i]
+					if got.ID != want.ID || got.UniqueMatches != want.UniqueMatches || got.TotalMatches != want.TotalMatches || got.Score != want.Score {
+						t.Fatalf("%q: result %d = %+v, want %+v", query, i, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMatchWeightsOrdering(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		weights MatchWeights
+		want    []DocID
+	}{
+		{name: "default", weights: NewMatchWeights(), want: []DocID{"varied", "repeated"}},
+		{name: "frequency", weights: MatchWeights{Unique: 1, Total: 1}, want: []DocID{"repeated", "varied"}},
+		{name: "unique only", weights: MatchWeights{Unique: 1}, want: []DocID{"varied", "repeated"}},
+	}
+	for _, tt := range tests {
+		svc := New(newPostingIndex(), WordKeys, WithScorer(tt.weights))
+		_ = svc.IndexDocument(ctx, "repeated", "hotel hotel hotel hotel")
+		_ = svc.IndexDocument(ctx, "varied", "hotel barge")
+
+		res, err := svc.SearchDocuments(ctx, "hotel barge", 10)
+		if err != nil {
+			t.Fatalf("%s: SearchDocuments() error = %v", tt.name, err)
+		}
+		var got []DocID
+		for _, r := range res.Results {
+			got = append(got, r.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Fatalf("%s: order = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMatchWeightsMatchesDefaultOrdering(t *testing.T) {
+	ctx := context.Background()
+	content := map[DocID]string{
+		"doc-1": "grand hotel by the river",
+		"doc-2": "hotel hotel",
+		"doc-3": "river barge hotel",
+		"doc-4": "barge",
+	}
+
+	var orders [][]DocID
+	for _, opts := range [][]Option{nil, {WithScorer(NewMatchWeights())}} {
+		svc := New(newPostingIndex(), WordKeys, opts...)
+		for id, text := range content {
+			_ = svc.IndexDocument(ctx, id, text)
+		}
+		res, err := svc.SearchDocuments(ctx, "hotel river barge", 10)
+		if err != nil {
+			t.Fatalf("SearchDocuments() error = %v", err)
+		}
+		var order []DocID
+		for _, r := range res.Results {
+			order = append(order, r.ID)
+		}
+		orders = append(orders, order)
+	}
+	if !slices.Equal(orders[0], orders[1]) {
+		t.Fatalf("weighted order = %v, want the default %v", orders[1], orders[0])
+	}
+}
+
+func TestMatchWeightsScoresFieldTerms(t *testing.T) {
+	doc := DocMatch{
+		UniqueMatches: 3,
+		TotalMatches:  9,
+		Terms:         []TermMatch{{Key: "hotel", TermFreq: 2}, {Key: "barge", TermFreq: 1}},
+	}
+	if got := (MatchWeights{Unique: 10, Total: 1}).Score(CorpusStats{}, doc); got != 23 {
+		t.Fatalf("Score() = %v, want 23 from the terms", got)
+	}
+	doc.Terms = nil
+	if got := (MatchWeights{Unique: 10, Total: 1}).Score(CorpusStats{}, doc); got != 39 {
+		t.Fatalf("Score() = %v, want 39 from the counts", got)
+	}
+}
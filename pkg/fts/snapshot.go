@@ -1,8 +1,11 @@
 package fts
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"encoding/gob"
+	"errors"
 	"fmt"
 	"io"
 	"sync"
@@ -10,6 +13,11 @@ import (
 
 const snapshotVersion uint16 = 1
 
+// gzipMagic starts a gzip stream. A snapshot's gob stream starts with a type
+// definition, whose length is followed by a negative type id encoded with a
+// leading 0xff, so an uncompressed snapshot never begins with these bytes.
+var gzipMagic = []byte{0x1f, 0x8b}
+
 type IndexSnapshotSaver func(index Index, w io.Writer) error
 type IndexSnapshotLoader func(r io.Reader) (Index, error)
 
@@ -137,6 +145,11 @@ func LoadIndexSnapshot(r io.Reader) (*LoadedIndexSnapshot, error) {
 		return nil, fmt.Errorf("fts: load index snapshot: nil reader")
 	}
 
+	r, err := snapshotReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("fts: load index snapshot: %w", err)
+	}
+
 	var envelope indexEnvelope
 	if err := gob.NewDecoder(r).Decode(&envelope); err != nil {
 		return nil, fmt.Errorf("fts: load index snapshot: decode envelope: %w", err)
@@ -207,6 +220,11 @@ func LoadFilterSnapshot(r io.Reader) (*LoadedFilterSnapshot, error) {
 		return nil, fmt.Errorf("fts: load filter snapshot: nil reader")
 	}
 
+	r, err := snapshotReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("fts: load filter snapshot: %w", err)
+	}
+
 	var envelope filterEnvelope
 	if err := gob.NewDecoder(r).Decode(&envelope); err != nil {
 		return nil, fmt.Errorf("fts: load filter snapshot: decode envelope: %w", err)
@@ -232,6 +250,25 @@ func LoadFilterSnapshot(r io.Reader) (*LoadedFilterSnapshot, error) {
 	return &LoadedFilterSnapshot{FilterName: envelope.FilterName, Filter: filter}, nil
 }
 
+// snapshotReader returns r, decompressing it when it holds a gzip stream, so
+// snapshots written through a gzip.Writer load like plain ones.
+func snapshotReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(len(gzipMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if !bytes.Equal(head, gzipMagic) {
+		return br, nil
+	}
+
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, fmt.Errorf("gzip: %w", err)
+	}
+	return zr, nil
+}
+
 func indexCodecByName(name string) (indexSnapshotCodec, bool) {
 	snapshotRegistryMu.RLock()
 	codec, ok := indexSnapshotCodecs[name]
@@ -2,6 +2,7 @@ package fts
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/gob"
 	"fmt"
@@ -138,6 +139,62 @@ func TestSaveLoadSplitSnapshotsRoundTrip(t *testing.T) {
 	}
 }
 
+func TestLoadCompressedSnapshots(t *testing.T) {
+	indexCodecName := fmt.Sprintf("test-index-%s", t.Name())
+	if err := RegisterIndexSnapshotCodec(indexCodecName,
+		func(index Index, w io.Writer) error { return index.(Serializable).Serialize(w) },
+		loadSnapshotIndex,
+	); err != nil {
+		t.Fatalf("RegisterIndexSnapshotCodec() error = %v", err)
+	}
+	filterCodecName := fmt.Sprintf("test-filter-%s", t.Name())
+	if err := RegisterFilterSnapshotCodec(filterCodecName,
+		func(filter Filter, w io.Writer) error { return filter.(Serializable).Serialize(w) },
+		loadSnapshotFilter,
+	); err != nil {
+		t.Fatalf("RegisterFilterSnapshotCodec() error = %v", err)
+	}
+
+	svc := New(newSnapshotIndex(), WordKeys, WithFilter(newSnapshotFilter()))
+	if err := svc.IndexDocument(context.Background(), "doc-1", "alpha beta"); err != nil {
+		t.Fatalf("IndexDocument() error = %v", err)
+	}
+	index, searchFilter := svc.SnapshotComponents()
+
+	compress := func(save func(w io.Writer) error) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if err := save(zw); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("gzip close: %v", err)
+		}
+		return buf.Bytes()
+	}
+	indexSnap := compress(func(w io.Writer) error { return SaveIndexSnapshot(w, indexCodecName, index) })
+	filterSnap := compress(func(w io.Writer) error { return SaveFilterSnapshot(w, filterCodecName, searchFilter) })
+
+	loadedIndex, err := LoadIndexSnapshot(bytes.NewReader(indexSnap))
+	if err != nil {
+		t.Fatalf("LoadIndexSnapshot() error = %v", err)
+	}
+	loadedFilter, err := LoadFilterSnapshot(bytes.NewReader(filterSnap))
+	if err != nil {
+		t.Fatalf("LoadFilterSnapshot() error = %v", err)
+	}
+
+	reloaded := New(loadedIndex.Index, WordKeys, WithFilter(loadedFilter.Filter))
+	res, err := reloaded.SearchDocuments(context.Background(), "alpha", 10)
+	if err != nil || res.TotalResultsCount != 1 {
+		t.Fatalf("SearchDocuments() = %+v, %v; want one result", res, err)
+	}
+
+	if _, err := LoadIndexSnapshot(bytes.NewReader(indexSnap[:len(indexSnap)/2])); err == nil {
+		t.Fatal("LoadIndexSnapshot(truncated) error = nil, want non-nil")
+	}
+}
+
 func TestSaveIndexSnapshotUnknownCodec(t *testing.T) {
 	var snap bytes.Buffer
 	err := SaveIndexSnapshot(&snap, "unknown", newSnapshotIndex())
@@ -0,0 +1,209 @@
+package fts
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dariasmyr/fts-engine/pkg/textproc"
+)
+
+// DefaultSnippetWindow is the snippet length, in characters, used when
+// Snippet is called with a window <= 0.
+const DefaultSnippetWindow = 160
+
+const snippetEllipsis = "…"
+
+// MatchSpan is the byte range [Start, End) of a matched word in a snippet.
+type MatchSpan struct {
+	Start int
+	End   int
+}
+
+type Snippet struct {
+	Text  string
+	Spans []MatchSpan
+}
+
+// Snippet cuts at most window characters of text around the first word that
+// matches query and reports where the matching words are. The cut starts at
+// the beginning of the matching sentence and ends after a whole sentence
+// where the window allows, and on word boundaries otherwise. A word matches
+// when the service pipeline reduces it to the same token as a query word, so
+// other forms of a stemmed word are highlighted too. A cut is marked with an
+// ellipsis; span offsets are bytes of Snippet.Text. Text without a match
+// yields its first window characters.
+func (s *Service) Snippet(query, text string, window int) Snippet {
+	if window <= 0 {
+		window = DefaultSnippetWindow
+	}
+
+	words := s.matchingWords(s.queryTerms(query), text)
+
+	first := 0
+	if len(words) > 0 {
+		first = words[0].Start
+	}
+	start, end := snippetBounds(text, first, window)
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString(snippetEllipsis)
+	}
+	shift := b.Len() - start
+	b.WriteString(text[start:end])
+	if end < len(text) {
+		b.WriteString(snippetEllipsis)
+	}
+
+	snippet := Snippet{Text: b.String()}
+	for _, word := range words {
+		if word.Start < start || word.End > end {
+			continue
+		}
+		snippet.Spans = append(snippet.Spans, MatchSpan{Start: word.Start + shift, End: word.End + shift})
+	}
+	return snippet
+}
+
+// Highlight reports where the words of text that match query are, as Snippet
+// does, but over the whole text. It suits short fields such as a title.
+func (s *Service) Highlight(query, text string) []MatchSpan {
+	return s.matchingWords(s.queryTerms(query), text)
+}
+
+// matchingWords returns the byte ranges of the words of text that the
+// pipeline reduces to one of terms.
+func (s *Service) matchingWords(terms map[string]struct{}, text string) []MatchSpan {
+	var words []MatchSpan
+	for _, word := range wordSpans(text) {
+		for _, token := range s.pipeline.Process(text[word.Start:word.End]) {
+			if _, ok := terms[token]; ok {
+				words = append(words, word)
+				break
+			}
+		}
+	}
+	return words
+}
+
+// queryTerms returns the processed tokens of the terms and phrases of query,
+// without field names and negated words. A query that does not parse has
+// none.
+func (s *Service) queryTerms(q string) map[string]struct{} {
+	terms := make(map[string]struct{})
+	root, err := parseQuery(q)
+	if err != nil {
+		return terms
+	}
+	for _, token := range s.queryTokens(root, false) {
+		terms[token] = struct{}{}
+	}
+	return terms
+}
+
+// wordSpans returns the byte ranges of the words of text.
+func wordSpans(text string) []MatchSpan {
+	var spans []MatchSpan
+	start := -1
+	for i, r := range text {
+		if textproc.IsWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			spans = append(spans, MatchSpan{Start: start, End: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, MatchSpan{Start: start, End: len(text)})
+	}
+	return spans
+}
+
+// snippetBounds returns the byte range of a window of at most window runes
+// that shows the byte offset at. The window starts at the beginning of the
+// sentence holding at when that leaves a quarter of the window after it, and
+// otherwise a quarter of the way before at. It ends after the last sentence
+// that fits, when that fills at least half of it. Cuts that fall inside a
+// sentence are moved inwards to word boundaries.
+func snippetBounds(text string, at, window int) (int, int) {
+	runes := utf8.RuneCountInString(text)
+	if runes <= window {
+		return 0, len(text)
+	}
+
+	offsets := make([]int, 0, runes+1)
+	for i := range text {
+		offsets = append(offsets, i)
+	}
+	offsets = append(offsets, len(text))
+
+	ends := sentenceEnds(text)
+	first := utf8.RuneCountInString(text[:at])
+	from := max(first-window/4, 0)
+	if sentence := sentenceStart(text, ends, at); first-utf8.RuneCountInString(text[:sentence]) <= window*3/4 {
+		from = utf8.RuneCountInString(text[:sentence])
+	}
+	from = min(from, runes-window)
+	start, end := offsets[from], offsets[from+window]
+
+	if start > 0 {
+		if r, _ := utf8.DecodeLastRuneInString(text[:start]); textproc.IsWordRune(r) {
+			if cut := strings.IndexFunc(text[start:], unicode.IsSpace); cut >= 0 && start+cut < at {
+				start += cut
+			}
+		}
+		start += len(text[start:]) - len(strings.TrimLeftFunc(text[start:], unicode.IsSpace))
+	}
+	if end < len(text) {
+		sentence := -1
+		for _, e := range ends {
+			if e > at && e <= end {
+				sentence = e
+			}
+		}
+		if sentence >= 0 && utf8.RuneCountInString(text[start:sentence]) >= window/2 {
+			end = sentence
+		} else if r, _ := utf8.DecodeRuneInString(text[end:]); textproc.IsWordRune(r) {
+			if cut := strings.LastIndexFunc(text[start:end], unicode.IsSpace); cut >= 0 && start+cut > at {
+				end = start + cut
+			}
+		}
+		end = start + len(strings.TrimRightFunc(text[start:end], unicode.IsSpace))
+	}
+
+	return start, end
+}
+
+// sentenceEnds returns the byte offsets just past each '.', '!' or '?' of
+// text that is followed by white space or ends it.
+func sentenceEnds(text string) []int {
+	var ends []int
+	for i, r := range text {
+		if r != '.' && r != '!' && r != '?' {
+			continue
+		}
+		next, _ := utf8.DecodeRuneInString(text[i+1:])
+		if i+1 == len(text) || unicode.IsSpace(next) {
+			ends = append(ends, i+1)
+		}
+	}
+	return ends
+}
+
+// sentenceStart returns the byte offset of the first word of the sentence
+// that holds the byte offset at.
+func sentenceStart(text string, ends []int, at int) int {
+	start := 0
+	for _, e := range ends {
+		if e > at {
+			break
+		}
+		start = e
+	}
+	return start + len(text[start:]) - len(strings.TrimLeftFunc(text[start:], unicode.IsSpace))
+}
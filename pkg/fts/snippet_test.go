@@ -0,0 +1,172 @@
+package fts
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func spanTexts(snippet Snippet) []string {
+	texts := make([]string, 0, len(snippet.Spans))
+	for _, span := range snippet.Spans {
+		texts = append(texts, snippet.Text[span.Start:span.End])
+	}
+	return texts
+}
+
+func TestSnippetMarksEveryMatch(t *testing.T) {
+	svc := New(newMemoryIndex(), WordKeys)
+
+	got := svc.Snippet("hotel AND (barge OR NOT river)", "The Hotel sits by a barge on the river.", 80)
+	if got.Text != "The Hotel sits by a barge on the river." {
+		t.Fatalf("Text = %q, want the whole short text", got.Text)
+	}
+	want := []string{"Hotel", "barge"}
+	if texts := spanTexts(got); strings.Join(texts, ",") != strings.Join(want, ",") {
+		t.Fatalf("spans = %q, want %q without the negated word", texts, want)
+	}
+}
+
+func TestSnippetSkipsFieldNames(t *testing.T) {
+	svc := New(newMemoryIndex(), WordKeys, WithFields("title", "abstract"))
+
+	got := svc.Snippet(`title:hotel abstract:"river barge"`, "The title of the hotel on the river barge.", 80)
+	want := []string{"hotel", "river", "barge"}
+	if texts := spanTexts(got); strings.Join(texts, ",") != strings.Join(want, ",") {
+		t.Fatalf("spans = %q, want %q", texts, want)
+	}
+}
+
+func TestSnippetCutsWindowAroundFirstMatch(t *testing.T) {
+	svc := New(newMemoryIndex(), WordKeys)
+	text := strings.Repeat("filler words ", 20) + "the grand hotel of copenhagen " + strings.Repeat("more text ", 20)
+
+	got := svc.Snippet("hotel", text, 40)
+	if !strings.HasPrefix(got.Text, snippetEllipsis) || !strings.HasSuffix(got.Text, snippetEllipsis) {
+		t.Fatalf("Text = %q, want ellipses on both ends", got.Text)
+	}
+	body := strings.TrimSuffix(strings.TrimPrefix(got.Text, snippetEllipsis), snippetEllipsis)
+	if n := utf8.RuneCountInString(body); n > 40 {
+		t.Fatalf("snippet has %d characters, want at most 40", n)
+	}
+	if body != strings.TrimSpace(body) || strings.HasPrefix(body, "ords") {
+		t.Fatalf("Text = %q, want cut on word boundaries", got.Text)
+	}
+	if texts := spanTexts(got); len(texts) != 1 || texts[0] != "hotel" {
+		t.Fatalf("spans = %q, want [hotel]", texts)
+	}
+}
+
+func TestSnippetStartsAtSentence(t *testing.T) {
+	svc := New(newMemoryIndex(), WordKeys)
+	text := "The Hotel d'Angleterre is a luxury hotel in Copenhagen, Denmark. " +
+		"It opened in 1755 on Kongens Nytorv. " +
+		"Its restaurant Marchal holds a Michelin star. " +
+		"The building was rebuilt after a fire in 2013 and reopened with new suites."
+
+	got := svc.Snippet("michelin", text, 80)
+	want := snippetEllipsis + "Its restaurant Marchal holds a Michelin star." + snippetEllipsis
+	if got.Text != want {
+		t.Fatalf("Text = %q, want %q", got.Text, want)
+	}
+	if texts := spanTexts(got); len(texts) != 1 || texts[0] != "Michelin" {
+		t.Fatalf("spans = %q, want [Michelin]", texts)
+	}
+}
+
+func TestSnippetEndsAfterSentence(t *testing.T) {
+	svc := New(newMemoryIndex(), WordKeys)
+	text := "Hotel Adlon is a hotel in Berlin, Germany. " +
+		"It stands on Unter den Linden, at the corner of Pariser Platz, opposite the Brandenburg Gate, since 1907."
+
+	got := svc.Snippet("berlin", text, 60)
+	if want := "Hotel Adlon is a hotel in Berlin, Germany." + snippetEllipsis; got.Text != want {
+		t.Fatalf("Text = %q, want %q", got.Text, want)
+	}
+}
+
+func TestSnippetLongSentenceCutsOnWords(t *testing.T) {
+	svc := New(newMemoryIndex(), WordKeys)
+	text := "The Ritz is a hotel " + strings.Repeat("with many grand rooms ", 10) + "overlooking Green Park in London " +
+		strings.Repeat("and close to Piccadilly ", 10) + "since 1906."
+
+	got := svc.Snippet("london", text, 40)
+	body := strings.TrimSuffix(strings.TrimPrefix(got.Text, snippetEllipsis), snippetEllipsis)
+	if n := utf8.RuneCountInString(body); n > 40 {
+		t.Fatalf("snippet has %d characters, want at most 40", n)
+	}
+	if !strings.HasPrefix(got.Text, snippetEllipsis) || !strings.HasSuffix(got.Text, snippetEllipsis) {
+		t.Fatalf("Text = %q, want ellipses on both ends", got.Text)
+	}
+	if body != strings.TrimSpace(body) || strings.HasPrefix(body, "ooms") || strings.HasPrefix(body, "ooking") {
+		t.Fatalf("Text = %q, want cut on word boundaries", got.Text)
+	}
+	if texts := spanTexts(got); len(texts) != 1 || texts[0] != "London" {
+		t.Fatalf("spans = %q, want [London]", texts)
+	}
+}
+
+func TestSnippetSentenceMultiByte(t *testing.T) {
+	svc := New(newMemoryIndex(), WordKeys)
+	text := "Hôtel Ritz Paris est un palace parisien. Il se trouve place Vendôme, à Paris. Coco Chanel y a vécu pendant plus de trente ans."
+
+	got := svc.Snippet("vendôme", text, 50)
+	if want := snippetEllipsis + "Il se trouve place Vendôme, à Paris." + snippetEllipsis; got.Text != want {
+		t.Fatalf("Text = %q, want %q", got.Text, want)
+	}
+	if texts := spanTexts(got); len(texts) != 1 || texts[0] != "Vendôme" {
+		t.Fatalf("spans = %q, want [Vendôme]", texts)
+	}
+}
+
+func TestSnippetMultiByteOffsets(t *testing.T) {
+	svc := New(newMemoryIndex(), WordKeys)
+	text := strings.Repeat("Ärger über Öl ", 10) + "Kjøbenhavn ved Øresund " + strings.Repeat("Æblegrød ", 10)
+
+	got := svc.Snippet("øresund", text, 30)
+	if !utf8.ValidString(got.Text) {
+		t.Fatalf("Text = %q, not valid UTF-8", got.Text)
+	}
+	if texts := spanTexts(got); len(texts) != 1 || texts[0] != "Øresund" {
+		t.Fatalf("spans = %q, want [Øresund]", texts)
+	}
+}
+
+func TestSnippetUsesPipeline(t *testing.T) {
+	svc := New(newMemoryIndex(), WordKeys, WithPipeline(stemPipeline{}))
+
+	got := svc.Snippet("hotels", "two hotel rooms", 0)
+	if texts := spanTexts(got); len(texts) != 1 || texts[0] != "hotel" {
+		t.Fatalf("spans = %q, want [hotel]", texts)
+	}
+}
+
+func TestSnippetWithoutMatch(t *testing.T) {
+	svc := New(newMemoryIndex(), WordKeys)
+
+	got := svc.Snippet("barge", "grand hotel", 0)
+	if got.Text != "grand hotel" || len(got.Spans) != 0 {
+		t.Fatalf("snippet = %+v, want the text without spans", got)
+	}
+}
+
+// stemPipeline lowercases and drops a trailing "s".
+type stemPipeline struct{}
+
+func (stemPipeline) Process(text string) []string {
+	tokens := defaultPipeline{}.Process(text)
+	for i, token := range tokens {
+		tokens[i] = strings.TrimSuffix(token, "s")
+	}
+	return tokens
+}
+
+func TestHighlightCoversWholeText(t *testing.T) {
+	svc := New(newMemoryIndex(), WordKeys, WithPipeline(stemPipeline{}))
+	title := "Hotel " + strings.Repeat("and more ", 40) + "hotels"
+
+	spans := svc.Highlight("hotels", title)
+	if len(spans) != 2 || title[spans[0].Start:spans[0].End] != "Hotel" || title[spans[1].Start:spans[1].End] != "hotels" {
+		t.Fatalf("spans = %+v, want both forms of hotel", spans)
+	}
+}
@@ -0,0 +1,182 @@
+package fts
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dariasmyr/fts-engine/pkg/fuzzy"
+	"github.com/dariasmyr/fts-engine/pkg/query"
+)
+
+// suggestion is an indexed word that could replace a query word.
+type suggestion struct {
+	word     string
+	distance int
+	docs     int
+}
+
+// better reports whether a is a better replacement than b: fewer edits first,
+// then the word found in more documents, then the smaller word.
+func (a suggestion) better(b suggestion) bool {
+	if a.distance != b.distance {
+		return a.distance < b.distance
+	}
+	if a.docs != b.docs {
+		return a.docs > b.docs
+	}
+	return a.word < b.word
+}
+
+// suggest returns the processed words of the query, negated ones left out,
+// with every word no document contains replaced by the closest indexed word
+// within MaxFuzzyDistance edits. It returns "" when no word was replaced.
+func (s *Service) suggest(ctx context.Context, root query.Node, lookup postingLookup) (string, error) {
+	tokens := s.queryTokens(root, false)
+
+	replaced := false
+	words := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		best, ok, err := s.closestWord(ctx, token, lookup)
+		if err != nil {
+			return "", err
+		}
+		if ok && best.word != token {
+			token, replaced = best.word, true
+		}
+		words = append(words, token)
+	}
+	if !replaced {
+		return "", nil
+	}
+	return strings.Join(words, " "), nil
+}
+
+// closestWord returns the indexed word closest to token, which is token
+// itself when it is indexed. With word keys the index must implement
+// FuzzySearcher. With n-gram keys the service must be built WithExactWords,
+// as only the document words it remembers can be compared. ok is false when
+// there is no candidate or no way to look for one.
+func (s *Service) closestWord(ctx context.Context, token string, lookup postingLookup) (suggestion, bool, error) {
+	keys, err := s.keyGen(token)
+	if err != nil {
+		return suggestion{}, false, fmt.Errorf("keygen: %w", err)
+	}
+	if len(keys) == 1 && keys[0] == token {
+		return s.closestKey(ctx, token)
+	}
+	return s.closestGramWord(token, keys, lookup)
+}
+
+// closestKey picks among the indexed keys within MaxFuzzyDistance of token.
+// A key indexed in several fields counts the documents of all of them.
+func (s *Service) closestKey(ctx context.Context, token string) (suggestion, bool, error) {
+	searcher, ok := s.index.(FuzzySearcher)
+	if !ok {
+		return suggestion{}, false, nil
+	}
+
+	candidates := make(map[string]suggestion)
+	for _, field := range s.fields {
+		prefix := fieldKey(field, "")
+		found, err := searcher.SearchFuzzy(ctx, fieldKey(field, token), MaxFuzzyDistance)
+		if err != nil {
+			return suggestion{}, false, fmt.Errorf("index search: %w", err)
+		}
+		for _, fm := range found {
+			if field != "" && !strings.HasPrefix(fm.Key, prefix) || isPhraseKey(fm.Key) {
+				continue
+			}
+			word := strings.TrimPrefix(fm.Key, prefix)
+			c := candidates[word]
+			c.word, c.distance = word, fm.Distance
+			c.docs += len(fm.Docs)
+			candidates[word] = c
+		}
+	}
+
+	return bestSuggestion(candidates)
+}
+
+// closestGramWord compares token with the words of the documents that share
+// enough of its n-grams to be within MaxFuzzyDistance edits, the same bound
+// fuzzyGrams uses.
+func (s *Service) closestGramWord(token string, keys []string, lookup postingLookup) (suggestion, bool, error) {
+	if s.docWords == nil || len(keys) == 0 {
+		return suggestion{}, false, nil
+	}
+
+	unique := make(map[string]struct{}, len(keys))
+	gram := 0
+	for _, key := range keys {
+		unique[key] = struct{}{}
+		gram = max(gram, utf8.RuneCountInString(key))
+	}
+	threshold := max(len(unique)-MaxFuzzyDistance*gram, 1)
+
+	shared := make(map[DocID]int)
+	for key := range unique {
+		seen := make(map[DocID]struct{})
+		for _, field := range s.fields {
+			docs, err := lookup(fieldKey(field, key))
+			if err != nil {
+				return suggestion{}, false, fmt.Errorf("index search: %w", err)
+			}
+			for _, doc := range docs {
+				if _, ok := seen[doc.ID]; !ok {
+					seen[doc.ID] = struct{}{}
+					shared[doc.ID]++
+				}
+			}
+		}
+	}
+
+	matcher := fuzzy.NewMatcher(token, MaxFuzzyDistance)
+	candidates := make(map[string]suggestion)
+	rejected := make(map[string]struct{})
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for id, n := range shared {
+		if n < threshold {
+			continue
+		}
+		// A word in several fields of a document counts once.
+		words := make(map[string]struct{})
+		for _, set := range s.docWords[id] {
+			maps.Copy(words, set)
+		}
+		for word := range words {
+			if _, ok := rejected[word]; ok {
+				continue
+			}
+			c, ok := candidates[word]
+			if !ok {
+				distance, within := matcher.Distance(word)
+				if !within {
+					rejected[word] = struct{}{}
+					continue
+				}
+				c = suggestion{word: word, distance: distance}
+			}
+			c.docs++
+			candidates[word] = c
+		}
+	}
+
+	return bestSuggestion(candidates)
+}
+
+func bestSuggestion(candidates map[string]suggestion) (suggestion, bool, error) {
+	var best suggestion
+	found := false
+	for _, c := range candidates {
+		if !found || c.better(best) {
+			best, found = c, true
+		}
+	}
+	return best, found, nil
+}
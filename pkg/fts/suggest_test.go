@@ -0,0 +1,71 @@
+package fts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dariasmyr/fts-engine/pkg/keygen"
+)
+
+func TestSearchSuggestsMisspelledWords(t *testing.T) {
+	ctx := context.Background()
+	svc := indexDocs(t, New(newPostingIndex(), WordKeys), booleanDocs)
+
+	tests := []struct {
+		query        string
+		suggestBelow int
+		want         string
+	}{
+		{query: "hotle", suggestBelow: 1, want: "hotel"},
+		{query: "danish hotle", suggestBelow: 3, want: "danish hotel"},
+		{query: "danish hotle", suggestBelow: 1, want: ""},
+		{query: "hotle NOT barge", suggestBelow: 1, want: "hotel"},
+		{query: "hotel", suggestBelow: 5, want: ""},
+		{query: "xyzzy", suggestBelow: 1, want: ""},
+		{query: "hotle", suggestBelow: 0, want: ""},
+	}
+	for _, tt := range tests {
+		res, err := svc.Search(ctx, tt.query, SearchOptions{SuggestBelow: tt.suggestBelow})
+		if err != nil {
+			t.Fatalf("Search(%q) error = %v", tt.query, err)
+		}
+		if res.Suggestion != tt.want {
+			t.Fatalf("Search(%q, SuggestBelow %d) suggestion = %q, want %q", tt.query, tt.suggestBelow, res.Suggestion, tt.want)
+		}
+	}
+}
+
+func TestSearchSuggestsFromTrigrams(t *testing.T) {
+	ctx := context.Background()
+
+	svc := indexDocs(t, New(newPostingIndex(), keygen.Trigram, WithExactWords()), trigramDocs)
+	res, err := svc.Search(ctx, "hotek", SearchOptions{SuggestBelow: 10})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if res.Suggestion != "hotel" {
+		t.Fatalf("suggestion = %q, want %q", res.Suggestion, "hotel")
+	}
+
+	// Without the document words there is nothing to compare against.
+	svc = indexDocs(t, New(newPostingIndex(), keygen.Trigram), trigramDocs)
+	if res, _ := svc.Search(ctx, "hotek", SearchOptions{SuggestBelow: 10}); res.Suggestion != "" {
+		t.Fatalf("suggestion = %q, want none", res.Suggestion)
+	}
+}
+
+func TestSuggestionPrefersCommonWords(t *testing.T) {
+	ctx := context.Background()
+	svc := New(newPostingIndex(), WordKeys)
+	_ = svc.IndexDocument(ctx, "doc-1", "barge")
+	_ = svc.IndexDocument(ctx, "doc-2", "large")
+	_ = svc.IndexDocument(ctx, "doc-3", "large")
+
+	res, err := svc.Search(ctx, "harge", SearchOptions{SuggestBelow: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if res.Suggestion != "large" {
+		t.Fatalf("suggestion = %q, want the more common %q", res.Suggestion, "large")
+	}
+}
@@ -3,6 +3,7 @@ package fts
 import (
 	"context"
 	"io"
+	"time"
 )
 
 type DocID string
@@ -10,29 +11,144 @@ type DocID string
 type DocRef struct {
 	ID    DocID
 	Count uint32
+	// Positions holds the token offsets of the key inside the document. Only
+	// a PositionalIndex fed by a service built WithPositions fills it.
+	Positions []uint32
 }
 
 type Result struct {
 	ID            DocID
 	UniqueMatches int
 	TotalMatches  int
+	Score         float64
+	// FuzzyTerms lists, for SearchFuzzy, which indexed term each query token matched.
+	FuzzyTerms []FuzzyTerm
+	// MatchedTerms lists, for Search, the distinct processed query tokens
+	// found in the document, in query order. With n-gram keys a token counts
+	// when any of its grams matched.
+	MatchedTerms []string
+}
+
+type FuzzyTerm struct {
+	Token    string
+	Term     string
+	Distance int
+}
+
+// SearchOptions selects the window [Offset, Offset+Limit) of the ranked
+// results. A Limit <= 0 returns everything after Offset. FieldWeights
+// overrides the service's field weights for this search.
+//
+// RequireAllKeys matters for key generators that split a token into several
+// keys, such as n-grams. By default a document matches a token when it has
+// any of its keys, so "hot dog" matches a search for "hotel" through "hot".
+// With RequireAllKeys it must have every key in one field, and when positions
+// are stored, all of them at the same token position, i.e. in one word.
+//
+// Descendants lets a query key also match every indexed key it is a prefix
+// of, with their counts merged; the index must be a DescendantSearcher.
+// Phrases still match exact keys.
+//
+// SuggestBelow asks for a SearchResult.Suggestion when the search finds fewer
+// results than this, so good queries do not pay for it. Zero never suggests.
+type SearchOptions struct {
+	Offset         int
+	Limit          int
+	FieldWeights   FieldWeights
+	RequireAllKeys bool
+	Descendants    bool
+	// Exact keeps only documents that contain every query word as a whole
+	// token. It requires a service built WithExactWords; Search returns
+	// ErrExactUnsupported otherwise.
+	Exact        bool
+	SuggestBelow int
 }
 
 type SearchResult struct {
 	Results           []Result
 	TotalResultsCount int
-	Timings           map[string]string
+	Timings           map[string]time.Duration
+	// Suggestion is a corrected query ("did you mean"), set when
+	// SearchOptions.SuggestBelow asked for one and some query word is not
+	// indexed but an indexed word is close to it.
+	Suggestion string
+	// Truncated is set when the search context ended before the search did.
+	// Results then hold the documents matched by the postings read so far,
+	// and Suggestion may be missing.
+	Truncated bool
 }
 
+// Index maps keys to document postings. Implementations must be safe for
+// concurrent use: searches may run while documents are inserted, so the
+// postings returned by Search must not be changed by later writes.
+//
+// A key that was never inserted is not an error: Search returns nil postings
+// and a nil error. An error means the index could not be read, and the
+// search that asked fails with it instead of treating the key as absent.
 type Index interface {
 	Insert(key string, id DocID) error
 	Search(key string) ([]DocRef, error)
 }
 
+// PositionalIndex is implemented by indexes that can keep token positions next
+// to the per-document counts. Phrase queries need them.
+type PositionalIndex interface {
+	InsertAt(key string, id DocID, pos uint32) error
+}
+
+// FuzzyMatch is an indexed key within the requested edit distance of a query key.
+type FuzzyMatch struct {
+	Key      string
+	Distance int
+	Docs     []DocRef
+}
+
+// FuzzySearcher is implemented by indexes that can enumerate keys within a
+// Levenshtein distance of a query key. The walk may visit most of the index,
+// so implementations check ctx as they go and return its error once it is done.
+type FuzzySearcher interface {
+	SearchFuzzy(ctx context.Context, key string, maxDist int) ([]FuzzyMatch, error)
+}
+
+// BatchSearcher is implemented by indexes that can look up several keys under
+// one read lock, so the postings of a query come from one state of the index.
+// Keys without postings are missing from the result.
+type BatchSearcher interface {
+	SearchBatch(keys []string) (map[string][]DocRef, error)
+}
+
+// DescendantSearcher is implemented by indexes that can return the postings of
+// every key starting with a prefix, merged per document, so a search can match
+// longer forms of a word ("hotel" also finding "hotelier"). A short prefix
+// can cover most of the index, so implementations return ctx.Err() when ctx
+// is done during the walk.
+type DescendantSearcher interface {
+	SearchWithDescendants(ctx context.Context, prefix string) ([]DocRef, error)
+}
+
 type Analyzer interface {
 	Analyze() Stats
 }
 
+// Deleter is implemented by indexes that can drop a document from every key.
+// Implementations walk the whole structure, so no original text is needed.
+type Deleter interface {
+	Delete(id DocID) error
+}
+
+// KeyDeleter is implemented by indexes that can drop a document from the keys
+// it was indexed under, without walking the whole structure.
+type KeyDeleter interface {
+	DeleteKeys(id DocID, keys []string) error
+}
+
+// Resetter is implemented by in-memory indexes that can drop every key at
+// once, so a replaced index stops holding its memory while references to it
+// remain. Reset must not run while the index is still being searched.
+type Resetter interface {
+	Reset()
+}
+
 type Serializable interface {
 	Serialize(w io.Writer) error
 }
@@ -3,6 +3,8 @@ package ftsbuiltin
 import (
 	"fmt"
 	"io"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/dariasmyr/fts-engine/pkg/filter"
@@ -85,19 +87,31 @@ func RegisterFilters() error {
 	return nil
 }
 
+// indexes maps the index names accepted by BuildIndex to their constructors.
+var indexes = map[string]func() fts.Index{
+	"radix":         func() fts.Index { return radix.New() },
+	"slicedradix":   func() fts.Index { return slicedradix.New() },
+	"hamt":          func() fts.Index { return hamt.New() },
+	"hamtpointered": func() fts.Index { return hamtpointered.New() },
+}
+
+// IndexNames returns the names accepted by BuildIndex, sorted.
+func IndexNames() []string {
+	names := make([]string, 0, len(indexes))
+	for name := range indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func BuildIndex(name string) (fts.Index, error) {
-	switch name {
-	case "radix":
-		return radix.New(), nil
-	case "slicedradix":
-		return slicedradix.New(), nil
-	case "hamt":
-		return hamt.New(), nil
-	case "hamtpointered":
-		return hamtpointered.New(), nil
-	default:
-		return nil, fmt.Errorf("unknown index %q", name)
+	build, ok := indexes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown index %q (want one of %s)", name, strings.Join(IndexNames(), ", "))
 	}
+
+	return build(), nil
 }
 
 func BuildFilter(name string, opts FilterOptions) (fts.Filter, error) {
@@ -0,0 +1,551 @@
+package ftsbuiltin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dariasmyr/fts-engine/pkg/fts"
+	"github.com/dariasmyr/fts-engine/pkg/keygen"
+	"github.com/dariasmyr/fts-engine/pkg/textproc"
+)
+
+func TestBuildIndexKnowsEveryName(t *testing.T) {
+	for _, name := range IndexNames() {
+		index, err := BuildIndex(name)
+		if err != nil || index == nil {
+			t.Fatalf("BuildIndex(%q) = %v, %v", name, index, err)
+		}
+	}
+}
+
+func TestBuildIndexUnknownListsNames(t *testing.T) {
+	_, err := BuildIndex("kv")
+	if err == nil {
+		t.Fatal("BuildIndex(kv) error = nil")
+	}
+	if !strings.Contains(err.Error(), "slicedradix") {
+		t.Fatalf("error = %q, want the valid index names", err)
+	}
+}
+
+func TestIndexesReindexReplacesCounts(t *testing.T) {
+	ctx := context.Background()
+	for _, name := range IndexNames() {
+		t.Run(name, func(t *testing.T) {
+			index, err := BuildIndex(name)
+			if err != nil {
+				t.Fatalf("BuildIndex() error = %v", err)
+			}
+			svc := fts.New(index, keygen.Word, fts.WithPositions())
+
+			_ = svc.IndexDocument(ctx, "doc-1", "hotel hotel hotel")
+			if err := svc.IndexDocument(ctx, "doc-1", "hotel hotel hotel hotel hotel"); err != nil {
+				t.Fatalf("IndexDocument() again error = %v", err)
+			}
+
+			res, err := svc.SearchDocuments(ctx, "hotel", 0)
+			if err != nil {
+				t.Fatalf("SearchDocuments() error = %v", err)
+			}
+			if len(res.Results) != 1 || res.Results[0].TotalMatches != 5 {
+				t.Fatalf("results = %+v, want a single entry with count 5", res.Results)
+			}
+		})
+	}
+}
+
+func TestIndexesTrigramIgnoresCase(t *testing.T) {
+	ctx := context.Background()
+	// No lowercase filter: the key generator alone must fold case.
+	pipeline := textproc.NewPipeline(textproc.AlnumTokenizer{})
+	for _, name := range IndexNames() {
+		t.Run(name, func(t *testing.T) {
+			index, err := BuildIndex(name)
+			if err != nil {
+				t.Fatalf("BuildIndex() error = %v", err)
+			}
+			svc := fts.New(index, keygen.Trigram, fts.WithPipeline(pipeline))
+
+			if err := svc.IndexDocument(ctx, "doc-1", "NASA launches a probe"); err != nil {
+				t.Fatalf("IndexDocument() error = %v", err)
+			}
+			for _, query := range []string{"nasa", "NASA", "Nasa"} {
+				res, err := svc.SearchDocuments(ctx, query, 0)
+				if err != nil {
+					t.Fatalf("SearchDocuments(%q) error = %v", query, err)
+				}
+				if res.TotalResultsCount != 1 {
+					t.Fatalf("SearchDocuments(%q) = %d results, want 1", query, res.TotalResultsCount)
+				}
+			}
+		})
+	}
+}
+
+func TestIndexesSnapshotReindexReplacesCounts(t *testing.T) {
+	ctx := context.Background()
+	if err := RegisterSnapshotCodecs(); err != nil {
+		t.Fatalf("RegisterSnapshotCodecs() error = %v", err)
+	}
+	for _, name := range IndexNames() {
+		t.Run(name, func(t *testing.T) {
+			index, err := BuildIndex(name)
+			if err != nil {
+				t.Fatalf("BuildIndex() error = %v", err)
+			}
+			_ = fts.New(index, keygen.Word).IndexDocument(ctx, "doc-1", "hotel hotel hotel")
+
+			var snapshot bytes.Buffer
+			if err := fts.SaveIndexSnapshot(&snapshot, name, index); err != nil {
+				t.Fatalf("SaveIndexSnapshot() error = %v", err)
+			}
+			svc, err := fts.NewFromReader(&snapshot, func(r io.Reader) (fts.Index, error) {
+				loaded, err := fts.LoadIndexSnapshot(r)
+				if err != nil {
+					return nil, err
+				}
+				return loaded.Index, nil
+			}, keygen.Word)
+			if err != nil {
+				t.Fatalf("NewFromReader() error = %v", err)
+			}
+
+			if err := svc.IndexDocument(ctx, "doc-1", "hotel hotel"); err != nil {
+				t.Fatalf("IndexDocument() after load error = %v", err)
+			}
+			res, err := svc.SearchDocuments(ctx, "hotel", 0)
+			if err != nil {
+				t.Fatalf("SearchDocuments() error = %v", err)
+			}
+			if len(res.Results) != 1 || res.Results[0].TotalMatches != 2 {
+				t.Fatalf("results = %+v, want a single entry with count 2", res.Results)
+			}
+		})
+	}
+}
+
+func TestIndexesSearchBatchMatchesSearch(t *testing.T) {
+	ctx := context.Background()
+	for _, name := range IndexNames() {
+		t.Run(name, func(t *testing.T) {
+			index, err := BuildIndex(name)
+			if err != nil {
+				t.Fatalf("BuildIndex() error = %v", err)
+			}
+			svc := fts.New(index, keygen.Word, fts.WithPositions())
+			_ = svc.IndexDocument(ctx, "doc-1", "grand hotel river")
+			_ = svc.IndexDocument(ctx, "doc-2", "hotel barge")
+
+			batcher, ok := index.(fts.BatchSearcher)
+			if !ok {
+				t.Fatalf("%T does not implement fts.BatchSearcher", index)
+			}
+			keys := []string{"hotel", "barge", "missing"}
+			got, err := batcher.SearchBatch(keys)
+			if err != nil {
+				t.Fatalf("SearchBatch() error = %v", err)
+			}
+			if _, ok := got["missing"]; ok || len(got) != 2 {
+				t.Fatalf("SearchBatch() keys = %d, want hotel and barge only", len(got))
+			}
+			for _, key := range keys[:2] {
+				want, _ := index.Search(key)
+				if len(got[key]) != len(want) {
+					t.Fatalf("SearchBatch()[%q] = %+v, Search() = %+v", key, got[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestIndexesDeleteKeysMatchesDelete(t *testing.T) {
+	ctx := context.Background()
+	for _, name := range IndexNames() {
+		t.Run(name, func(t *testing.T) {
+			var services []*fts.Service
+			for _, opts := range [][]fts.Option{
+				{fts.WithPositions()},
+				{fts.WithPositions(), fts.WithReverseIndex()},
+			} {
+				index, err := BuildIndex(name)
+				if err != nil {
+					t.Fatalf("BuildIndex() error = %v", err)
+				}
+				svc := fts.New(index, keygen.Word, opts...)
+				for i := range 20 {
+					_ = svc.IndexDocument(ctx, fts.DocID(fmt.Sprintf("doc-%d", i)), fmt.Sprintf("grand hotel river word%d", i%3))
+				}
+				for _, id := range []fts.DocID{"doc-0", "doc-3", "doc-19"} {
+					if err := svc.DeleteDocument(ctx, id); err != nil {
+						t.Fatalf("DeleteDocument() error = %v", err)
+					}
+				}
+				services = append(services, svc)
+			}
+
+			for _, q := range []string{"hotel", "word0", `"grand hotel"`} {
+				scan, _ := services[0].SearchDocuments(ctx, q, 0)
+				reverse, err := services[1].SearchDocuments(ctx, q, 0)
+				if err != nil {
+					t.Fatalf("SearchDocuments(%q) error = %v", q, err)
+				}
+				if reverse.TotalResultsCount != scan.TotalResultsCount || reverse.TotalResultsCount == 0 {
+					t.Fatalf("query %q: %d results with the reverse index, %d with a full delete", q, reverse.TotalResultsCount, scan.TotalResultsCount)
+				}
+			}
+			fuzzy, err := services[1].SearchFuzzy(ctx, "word0", 0, 0)
+			if err != nil {
+				t.Fatalf("SearchFuzzy() error = %v", err)
+			}
+			for _, r := range fuzzy.Results {
+				if r.ID == "doc-0" || r.ID == "doc-3" {
+					t.Fatalf("fuzzy search returned deleted %s", r.ID)
+				}
+			}
+		})
+	}
+}
+
+// postingsOf renders postings in a form that does not depend on the order an
+// index returns them in.
+func postingsOf(refs []fts.DocRef) string {
+	out := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		positions := slices.Clone(ref.Positions)
+		slices.Sort(positions)
+		out = append(out, fmt.Sprintf("%s:%d%v", ref.ID, ref.Count, positions))
+	}
+	slices.Sort(out)
+	return strings.Join(out, " ")
+}
+
+func TestIndexesAgree(t *testing.T) {
+	// Keys that are prefixes of one another split and merge edges at every
+	// point: at the first and last byte, inside a multi-byte rune and at
+	// the end of an existing key.
+	keys := []string{"a", "ab", "abc", "abd", "b", "hotel", "hotels", "hot", "ho", "h", "hôtel", "hôte", "hõ", "title:hotel", "title:h", "title:", "x1", "x12", "x123"}
+	probes := append(slices.Clone(keys), "", "abcd", "hote", "hotelsx", "title", "x", "ô", "zzz")
+
+	build := func(name string) fts.Index {
+		index, err := BuildIndex(name)
+		if err != nil {
+			t.Fatalf("BuildIndex(%q) error = %v", name, err)
+		}
+		positional := index.(fts.PositionalIndex)
+		for d := range 4 {
+			doc := fts.DocID(fmt.Sprintf("doc-%d", d))
+			for i, key := range keys {
+				if (i+d)%3 == 0 {
+					continue
+				}
+				if err := positional.InsertAt(key, doc, uint32(i)); err != nil {
+					t.Fatalf("%s: InsertAt(%q) error = %v", name, key, err)
+				}
+			}
+		}
+		if err := index.(fts.Deleter).Delete("doc-2"); err != nil {
+			t.Fatalf("%s: Delete() error = %v", name, err)
+		}
+		if err := index.(fts.KeyDeleter).DeleteKeys("doc-1", []string{"ab", "hotel", "title:"}); err != nil {
+			t.Fatalf("%s: DeleteKeys() error = %v", name, err)
+		}
+		return index
+	}
+
+	names := IndexNames()
+	reference := build(names[0])
+	for _, name := range names[1:] {
+		index := build(name)
+		for _, probe := range probes {
+			want, _ := reference.Search(probe)
+			got, err := index.Search(probe)
+			if err != nil {
+				t.Fatalf("%s: Search(%q) error = %v", name, probe, err)
+			}
+			if postingsOf(got) != postingsOf(want) {
+				t.Fatalf("%s: Search(%q) = %s, %s has %s", name, probe, postingsOf(got), names[0], postingsOf(want))
+			}
+
+			wantFuzzy, _ := reference.(fts.FuzzySearcher).SearchFuzzy(context.Background(), probe, 1)
+			gotFuzzy, err := index.(fts.FuzzySearcher).SearchFuzzy(context.Background(), probe, 1)
+			if err != nil {
+				t.Fatalf("%s: SearchFuzzy(%q) error = %v", name, probe, err)
+			}
+			if fuzzyKeys(gotFuzzy) != fuzzyKeys(wantFuzzy) {
+				t.Fatalf("%s: SearchFuzzy(%q) = %s, %s has %s", name, probe, fuzzyKeys(gotFuzzy), names[0], fuzzyKeys(wantFuzzy))
+			}
+		}
+	}
+}
+
+func TestIndexesUnicodeAndDigitKeys(t *testing.T) {
+	keys := []string{"mp3", "19c", "ови", "éa", "èb", "caf", "afé"}
+	for _, name := range IndexNames() {
+		t.Run(name, func(t *testing.T) {
+			index, err := BuildIndex(name)
+			if err != nil {
+				t.Fatalf("BuildIndex() error = %v", err)
+			}
+			for _, key := range keys {
+				if err := index.Insert(key, "doc-1"); err != nil {
+					t.Fatalf("Insert(%q) error = %v", key, err)
+				}
+			}
+
+			for _, key := range keys {
+				docs, err := index.Search(key)
+				if err != nil {
+					t.Fatalf("Search(%q) error = %v", key, err)
+				}
+				if len(docs) != 1 || docs[0].ID != "doc-1" {
+					t.Fatalf("Search(%q) = %+v, want doc-1", key, docs)
+				}
+			}
+		})
+	}
+}
+
+func fuzzyKeys(matches []fts.FuzzyMatch) string {
+	out := make([]string, 0, len(matches))
+	for _, m := range matches {
+		out = append(out, fmt.Sprintf("%s/%d[%s]", m.Key, m.Distance, postingsOf(m.Docs)))
+	}
+	slices.Sort(out)
+	return strings.Join(out, " ")
+}
+
+// BenchmarkReplaceDocument re-indexes one document of a large index, which
+// deletes its old postings either by walking the index or through the
+// reverse index.
+func BenchmarkReplaceDocument(b *testing.B) {
+	ctx := context.Background()
+	for _, tt := range []struct {
+		name string
+		opts []fts.Option
+	}{
+		{name: "scan"},
+		{name: "reverse", opts: []fts.Option{fts.WithReverseIndex()}},
+	} {
+		b.Run(tt.name, func(b *testing.B) {
+			index, err := BuildIndex("slicedradix")
+			if err != nil {
+				b.Fatalf("BuildIndex() error = %v", err)
+			}
+			svc := fts.New(index, keygen.Word, tt.opts...)
+			for i := range 5000 {
+				_ = svc.IndexDocument(ctx, fts.DocID(fmt.Sprintf("doc-%d", i)), fmt.Sprintf("grand hotel word%d term%d", i, i%97))
+			}
+
+			b.ResetTimer()
+			for i := range b.N {
+				if err := svc.IndexDocument(ctx, "doc-1", fmt.Sprintf("river barge word%d", i)); err != nil {
+					b.Fatalf("IndexDocument() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+// TestIndexesConcurrentIndexers is meant for -race: documents sharing the
+// same keys are indexed from several goroutines, and no posting may be lost.
+func TestIndexesConcurrentIndexers(t *testing.T) {
+	const (
+		workers = 8
+		docs    = 400
+	)
+	ctx := context.Background()
+
+	for _, name := range IndexNames() {
+		t.Run(name, func(t *testing.T) {
+			index, err := BuildIndex(name)
+			if err != nil {
+				t.Fatalf("BuildIndex() error = %v", err)
+			}
+			svc := fts.New(index, keygen.Word, fts.WithPositions())
+
+			var wg sync.WaitGroup
+			for w := range workers {
+				wg.Go(func() {
+					for i := w; i < docs; i += workers {
+						content := fmt.Sprintf("grand hotel river hotel word%d", i)
+						if err := svc.IndexDocument(ctx, fts.DocID(fmt.Sprintf("doc-%d", i)), content); err != nil {
+							t.Errorf("IndexDocument() error = %v", err)
+							return
+						}
+					}
+				})
+			}
+			wg.Wait()
+
+			res, err := svc.SearchDocuments(ctx, "hotel", 0)
+			if err != nil {
+				t.Fatalf("SearchDocuments() error = %v", err)
+			}
+			if res.TotalResultsCount != docs {
+				t.Fatalf("TotalResultsCount = %d, want %d", res.TotalResultsCount, docs)
+			}
+			for _, r := range res.Results {
+				if r.TotalMatches != 2 {
+					t.Fatalf("result %+v, want both hotel occurrences", r)
+				}
+			}
+			if res, _ := svc.SearchDocuments(ctx, `"grand hotel river"`, 0); res.TotalResultsCount != docs {
+				t.Fatalf("phrase TotalResultsCount = %d, want %d", res.TotalResultsCount, docs)
+			}
+		})
+	}
+}
+
+// TestIndexesConcurrentReindex re-indexes the same documents from several
+// goroutines; each must end up with the postings of exactly one version.
+func TestIndexesConcurrentReindex(t *testing.T) {
+	const workers = 8
+	ctx := context.Background()
+
+	for _, name := range IndexNames() {
+		t.Run(name, func(t *testing.T) {
+			index, err := BuildIndex(name)
+			if err != nil {
+				t.Fatalf("BuildIndex() error = %v", err)
+			}
+			svc := fts.New(index, keygen.Word)
+
+			var wg sync.WaitGroup
+			for range workers {
+				wg.Go(func() {
+					for i := range 50 {
+						if err := svc.IndexDocument(ctx, fts.DocID(fmt.Sprintf("doc-%d", i%5)), "hotel hotel"); err != nil {
+							t.Errorf("IndexDocument() error = %v", err)
+							return
+						}
+					}
+				})
+			}
+			wg.Wait()
+
+			res, err := svc.SearchDocuments(ctx, "hotel", 0)
+			if err != nil {
+				t.Fatalf("SearchDocuments() error = %v", err)
+			}
+			if res.TotalResultsCount != 5 {
+				t.Fatalf("TotalResultsCount = %d, want 5", res.TotalResultsCount)
+			}
+			for _, r := range res.Results {
+				if r.TotalMatches != 2 {
+					t.Fatalf("result %+v, want the count of one version", r)
+				}
+			}
+		})
+	}
+}
+
+// TestIndexesSearchDuringIndexing is meant for -race: searches read postings
+// while documents sharing the same keys are being indexed.
+func TestIndexesSearchDuringIndexing(t *testing.T) {
+	const docs = 300
+
+	for _, name := range IndexNames() {
+		t.Run(name, func(t *testing.T) {
+			index, err := BuildIndex(name)
+			if err != nil {
+				t.Fatalf("BuildIndex() error = %v", err)
+			}
+			svc := fts.New(index, keygen.Word, fts.WithPositions())
+			ctx := context.Background()
+
+			var wg sync.WaitGroup
+			done := make(chan struct{})
+			wg.Go(func() {
+				defer close(done)
+				for i := range docs {
+					content := fmt.Sprintf("grand hotel river barge hotel %d", i)
+					if err := svc.IndexDocument(ctx, fts.DocID(fmt.Sprintf("doc-%d", i)), content); err != nil {
+						t.Errorf("IndexDocument() error = %v", err)
+						return
+					}
+				}
+			})
+
+			for range 8 {
+				wg.Go(func() {
+					for {
+						select {
+						case <-done:
+							return
+						default:
+						}
+						res, err := svc.SearchDocuments(ctx, `hotel "river barge"`, 0)
+						if err != nil {
+							t.Errorf("SearchDocuments() error = %v", err)
+							return
+						}
+						for _, r := range res.Results {
+							if r.TotalMatches < 2 {
+								t.Errorf("result %+v, want both hotel occurrences", r)
+								return
+							}
+						}
+						if _, err := svc.SearchFuzzy(ctx, "hatel", 1, 5); err != nil {
+							t.Errorf("SearchFuzzy() error = %v", err)
+							return
+						}
+						svc.Analyze()
+					}
+				})
+			}
+			wg.Wait()
+
+			res, err := svc.SearchDocuments(ctx, "hotel", 0)
+			if err != nil {
+				t.Fatalf("SearchDocuments() error = %v", err)
+			}
+			if res.TotalResultsCount != docs {
+				t.Fatalf("TotalResultsCount = %d, want %d", res.TotalResultsCount, docs)
+			}
+		})
+	}
+}
+
+// BenchmarkLongTrigramQuery compares serial and concurrent key lookups for a
+// query that expands into many trigram keys.
+func BenchmarkLongTrigramQuery(b *testing.B) {
+	words := strings.Fields("grand hotel river barge lighthouse harbour cathedral museum " +
+		"railway station university library observatory monastery fortress bridge")
+	query := strings.Join(words, " ")
+
+	for _, tt := range []struct {
+		name    string
+		workers int
+	}{
+		{name: "serial", workers: 1},
+		{name: "parallel", workers: 8},
+	} {
+		b.Run(tt.name, func(b *testing.B) {
+			index, err := BuildIndex("slicedradix")
+			if err != nil {
+				b.Fatalf("BuildIndex() error = %v", err)
+			}
+			svc := fts.New(index, keygen.Trigram, fts.WithSearchWorkers(tt.workers))
+			ctx := context.Background()
+			for i := range 2000 {
+				content := fmt.Sprintf("%s %s %d", words[i%len(words)], words[(i*7)%len(words)], i)
+				if err := svc.IndexDocument(ctx, fts.DocID(fmt.Sprintf("doc-%d", i)), content); err != nil {
+					b.Fatalf("IndexDocument() error = %v", err)
+				}
+			}
+
+			b.ResetTimer()
+			for range b.N {
+				if _, err := svc.SearchDocuments(ctx, query, 10); err != nil {
+					b.Fatalf("SearchDocuments() error = %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package fuzzy
+
+import "unicode/utf8"
+
+// Row is one row of the Levenshtein matrix against the matcher query.
+type Row []int
+
+// Matcher computes rune-level Levenshtein distances to a fixed query one rune
+// at a time, so trie walks can share the work for common prefixes and prune
+// branches that can no longer get within the maximum distance.
+type Matcher struct {
+	query []rune
+	max   int
+}
+
+func NewMatcher(query string, maxDist int) *Matcher {
+	return &Matcher{query: []rune(query), max: maxDist}
+}
+
+// Start returns the row for an empty candidate.
+func (m *Matcher) Start() Row {
+	row := make(Row, len(m.query)+1)
+	for i := range row {
+		row[i] = i
+	}
+	return row
+}
+
+// Step returns the row after appending r to the candidate described by prev.
+func (m *Matcher) Step(prev Row, r rune) Row {
+	row := make(Row, len(prev))
+	row[0] = prev[0] + 1
+	for j := 1; j < len(row); j++ {
+		cost := 1
+		if m.query[j-1] == r {
+			cost = 0
+		}
+		row[j] = min(prev[j]+1, row[j-1]+1, prev[j-1]+cost)
+	}
+	return row
+}
+
+// Viable reports whether some extension of the candidate can still be within
+// the maximum distance.
+func (m *Matcher) Viable(row Row) bool {
+	for _, d := range row {
+		if d <= m.max {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns the distance between the candidate and the query and whether
+// it is within the maximum distance.
+func (m *Matcher) Match(row Row) (int, bool) {
+	d := row[len(row)-1]
+	return d, d <= m.max
+}
+
+// Distance checks a complete candidate, stopping as soon as it cannot match.
+func (m *Matcher) Distance(candidate string) (int, bool) {
+	if diff := utf8.RuneCountInString(candidate) - len(m.query); diff > m.max || -diff > m.max {
+		return 0, false
+	}
+
+	row := m.Start()
+	for _, r := range candidate {
+		row = m.Step(row, r)
+		if !m.Viable(row) {
+			return 0, false
+		}
+	}
+	return m.Match(row)
+}
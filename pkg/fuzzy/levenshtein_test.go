@@ -0,0 +1,57 @@
+package fuzzy
+
+import "testing"
+
+func TestMatcherDistance(t *testing.T) {
+	tests := []struct {
+		query     string
+		candidate string
+		max       int
+		want      int
+		ok        bool
+	}{
+		{query: "hotel", candidate: "hotel", max: 1, want: 0, ok: true},
+		{query: "hatel", candidate: "hotel", max: 1, want: 1, ok: true},
+		{query: "hotl", candidate: "hotel", max: 1, want: 1, ok: true},
+		{query: "hoteel", candidate: "hotel", max: 1, want: 1, ok: true},
+		{query: "htoel", candidate: "hotel", max: 2, want: 2, ok: true},
+		{query: "htoel", candidate: "hotel", max: 1, ok: false},
+		{query: "hotel", candidate: "ho", max: 2, ok: false},
+		{query: "strase", candidate: "straße", max: 1, want: 1, ok: true},
+	}
+
+	for _, tt := range tests {
+		got, ok := NewMatcher(tt.query, tt.max).Distance(tt.candidate)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Fatalf("Distance(%q, %q, %d) = %d, %v, want %d, %v", tt.query, tt.candidate, tt.max, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMatcherStepSharesPrefixes(t *testing.T) {
+	m := NewMatcher("hotel", 1)
+
+	row := m.Start()
+	for _, r := range "hot" {
+		row = m.Step(row, r)
+	}
+	if !m.Viable(row) {
+		t.Fatalf("prefix %q not viable", "hot")
+	}
+
+	el := row
+	for _, r := range "el" {
+		el = m.Step(el, r)
+	}
+	if d, ok := m.Match(el); !ok || d != 0 {
+		t.Fatalf("Match(hotel) = %d, %v, want 0, true", d, ok)
+	}
+
+	xyz := row
+	for _, r := range "xyz" {
+		xyz = m.Step(xyz, r)
+	}
+	if m.Viable(xyz) {
+		t.Fatalf("prefix %q viable, want pruned", "hotxyz")
+	}
+}
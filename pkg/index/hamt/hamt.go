@@ -1,10 +1,11 @@
 package hamt
 
 import (
+	"context"
 	"encoding/gob"
 	"fmt"
 	"github.com/dariasmyr/fts-engine/pkg/fts"
-	"hash/fnv"
+	"github.com/dariasmyr/fts-engine/pkg/fuzzy"
 	"io"
 	"math/bits"
 	"slices"
@@ -16,19 +17,27 @@ const (
 	quant     = 5
 	lowerbits = uint32(1<<quant) - 1
 	depth     = 7
+
+	fnvOffset32 = 2166136261
+	fnvPrime32  = 16777619
+
+	// ctxCheckInterval is how many terminal nodes SearchFuzzy compares
+	// between cancellation checks.
+	ctxCheckInterval = 64
 )
 
 type documents []fts.DocRef
 
-func (d documents) Add(id fts.DocID) documents {
+func (d documents) Add(id fts.DocID, at []uint32) documents {
 	i := sort.Search(len(d), func(i int) bool { return d[i].ID >= id })
 	if i < len(d) && d[i].ID == id {
 		d[i].Count++
+		d[i].Positions = append(d[i].Positions, at...)
 		return d
 	}
 	d = append(d, fts.DocRef{})
 	copy(d[i+1:], d[i:])
-	d[i] = fts.DocRef{ID: id, Count: 1}
+	d[i] = fts.DocRef{ID: id, Count: 1, Positions: append([]uint32(nil), at...)}
 	return d
 }
 
@@ -43,15 +52,53 @@ type terminal struct {
 	entries []entry
 }
 
-func (t *terminal) Append(word string, id fts.DocID) {
+func (t *terminal) Append(word string, id fts.DocID, at []uint32) {
 	i := sort.Search(len(t.entries), func(i int) bool { return t.entries[i].key >= word })
 	if i < len(t.entries) && t.entries[i].key == word {
-		t.entries[i].docs = t.entries[i].docs.Add(id)
+		t.entries[i].docs = t.entries[i].docs.Add(id, at)
 		return
 	}
 	t.entries = append(t.entries, entry{})
 	copy(t.entries[i+1:], t.entries[i:])
-	t.entries[i] = entry{key: word, docs: documents{{ID: id, Count: 1}}}
+	t.entries[i] = entry{key: word, docs: documents(nil).Add(id, at)}
+}
+
+// Remove drops id from the entry of word, and the entry once it has no
+// documents left.
+func (t *terminal) Remove(word string, id fts.DocID) {
+	i := sort.Search(len(t.entries), func(i int) bool { return t.entries[i].key >= word })
+	if i == len(t.entries) || t.entries[i].key != word {
+		return
+	}
+
+	docs := t.entries[i].docs
+	j := sort.Search(len(docs), func(j int) bool { return docs[j].ID >= id })
+	if j == len(docs) || docs[j].ID != id {
+		return
+	}
+	if len(docs) == 1 {
+		t.entries = slices.Delete(t.entries, i, i+1)
+		return
+	}
+	t.entries[i].docs = slices.Delete(slices.Clone(docs), j, j+1)
+}
+
+// without returns the entries with id removed, dropping keys left without documents.
+func (t *terminal) without(id fts.DocID) []entry {
+	entries := make([]entry, 0, len(t.entries))
+	for _, e := range t.entries {
+		i := sort.Search(len(e.docs), func(i int) bool { return e.docs[i].ID >= id })
+		if i < len(e.docs) && e.docs[i].ID == id {
+			if len(e.docs) == 1 {
+				continue
+			}
+			docs := make(documents, 0, len(e.docs)-1)
+			docs = append(docs, e.docs[:i]...)
+			e.docs = append(docs, e.docs[i+1:]...)
+		}
+		entries = append(entries, e)
+	}
+	return entries
 }
 
 func (t *terminal) Find(word string) documents {
@@ -76,6 +123,8 @@ func (n node) Append(idx uint32, branch nodeptr) node {
 }
 
 type Index struct {
+	// mu guards the whole index. Insert and Delete hold it exclusively; reads
+	// share it and copy postings out before releasing it.
 	mu    sync.RWMutex
 	nodes []node
 	terms []terminal
@@ -176,31 +225,107 @@ func (t *Index) Search(key string) ([]fts.DocRef, error) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
 
+	return t.search(key), nil
+}
+
+// SearchBatch looks all keys up under one read lock.
+func (t *Index) SearchBatch(keys []string) (map[string][]fts.DocRef, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	out := make(map[string][]fts.DocRef, len(keys))
+	for _, key := range keys {
+		if docs := t.search(key); len(docs) > 0 {
+			out[key] = docs
+		}
+	}
+	return out, nil
+}
+
+// search returns the postings of key. The caller holds t.mu.
+func (t *Index) search(key string) []fts.DocRef {
+	n, ok := t.terminalOf(key)
+	if !ok {
+		return nil
+	}
+
+	docs := t.terms[n].Find(key)
+	if docs == nil {
+		return nil
+	}
+
+	return slices.Clone(docs)
+}
+
+// terminalOf returns the terminal that holds key if it exists. The caller
+// holds t.mu.
+func (t *Index) terminalOf(key string) (nodeptr, bool) {
 	n := nodeptr(0)
 	hash := strhash32(key)
 	for range depth - 1 {
 		var ok bool
 		n, ok = t.nextNode(n, hash)
 		if !ok {
-			return nil, nil
+			return 0, false
 		}
 		hash >>= quant
 	}
 
-	term := t.terms[n]
-	if term.entries == nil {
-		return nil, nil
+	if t.terms[n].entries == nil {
+		return 0, false
 	}
+	return n, true
+}
 
-	docs := term.Find(key)
-	if docs == nil {
-		return nil, nil
+// DeleteKeys drops id from the given keys only. Terminals left without
+// entries stay in place until the next Delete.
+func (t *Index) DeleteKeys(id fts.DocID, keys []string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, key := range keys {
+		if n, ok := t.terminalOf(key); ok {
+			t.terms[n].Remove(key, id)
+		}
 	}
+	return nil
+}
 
-	return docs, nil
+// SearchFuzzy compares every stored key against key. Hashing scatters similar
+// keys, so there is no structure to prune by.
+func (t *Index) SearchFuzzy(ctx context.Context, key string, maxDist int) ([]fts.FuzzyMatch, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	m := fuzzy.NewMatcher(key, maxDist)
+	var matches []fts.FuzzyMatch
+	for i := range t.terms {
+		if i%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		for _, e := range t.terms[i].entries {
+			if d, ok := m.Distance(e.key); ok {
+				matches = append(matches, fts.FuzzyMatch{Key: e.key, Distance: d, Docs: slices.Clone(e.docs)})
+			}
+		}
+	}
+
+	return matches, nil
 }
 
 func (t *Index) Insert(word string, id fts.DocID) error {
+	return t.insert(word, id, nil)
+}
+
+// InsertAt inserts word like Insert and also records pos as one of its token
+// positions inside id.
+func (t *Index) InsertAt(word string, id fts.DocID, pos uint32) error {
+	return t.insert(word, id, []uint32{pos})
+}
+
+func (t *Index) insert(word string, id fts.DocID, at []uint32) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
@@ -223,7 +348,55 @@ func (t *Index) Insert(word string, id fts.DocID) error {
 		t.nodes[n] = t.nodes[n].Append(hash&lowerbits, termPtr)
 	}
 
-	t.terms[termPtr].Append(word, id)
+	t.terms[termPtr].Append(word, id, at)
+	return nil
+}
+
+// Delete drops id from every terminal and rebuilds the node and terminal
+// arrays without the branches left empty. The root keeps index 0.
+func (t *Index) Delete(id fts.DocID) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	nodes := make([]node, 1, len(t.nodes))
+	terms := make([]terminal, 0, len(t.terms))
+
+	var compact func(ptr nodeptr, level int) node
+	compact = func(ptr nodeptr, level int) node {
+		old := t.nodes[ptr]
+		var n node
+		child := 0
+		for bit := uint32(0); bit <= lowerbits; bit++ {
+			mask := uint32(1) << bit
+			if old.bitmap&mask == 0 {
+				continue
+			}
+			ptr := old.children[child]
+			child++
+
+			if level == depth-2 {
+				entries := t.terms[ptr].without(id)
+				if len(entries) == 0 {
+					continue
+				}
+				terms = append(terms, terminal{entries: entries})
+				n = n.Append(bit, nodeptr(len(terms)-1))
+				continue
+			}
+
+			sub := compact(ptr, level+1)
+			if len(sub.children) == 0 {
+				continue
+			}
+			nodes = append(nodes, sub)
+			n = n.Append(bit, nodeptr(len(nodes)-1))
+		}
+		return n
+	}
+
+	nodes[0] = compact(0, 0)
+	t.nodes = nodes
+	t.terms = terms
 	return nil
 }
 
@@ -247,25 +420,45 @@ func (t *Index) nextNode(n nodeptr, hash uint32) (nodeptr, bool) {
 	return node.children[index], true
 }
 
+// strhash32 is 32-bit FNV-1a over the bytes of str: the same hash as
+// hash/fnv.New32a, which snapshots depend on, without going through a
+// hash.Hash on the insert and search paths.
 func strhash32(str string) uint32 {
-	h := fnv.New32a()
-	_, _ = h.Write([]byte(str))
-	return h.Sum32()
+	h := uint32(fnvOffset32)
+	for i := 0; i < len(str); i++ {
+		h ^= uint32(str[i])
+		h *= fnvPrime32
+	}
+	return h
 }
 
 func (t *Index) Analyze() fts.Stats {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
 	var s fts.Stats
 	var totalDepth int
 
 	levelChildrenSum := make(map[int]int)
 	levelNodeCount := make(map[int]int)
 
+	// Terminals count as nodes at their depth, as in the other indexes, so
+	// Leaves is a subset of Nodes and AvgDepth covers the whole trie.
+	visit := func(currentDepth int) {
+		s.Nodes++
+		totalDepth += currentDepth
+		if currentDepth > s.MaxDepth {
+			s.MaxDepth = currentDepth
+		}
+	}
+
 	var dfs func(ptr nodeptr, currentDepth int, isTerm bool)
 	dfs = func(ptr nodeptr, currentDepth int, isTerm bool) {
 		if isTerm {
 			if int(ptr) >= len(t.terms) {
 				return
 			}
+			visit(currentDepth)
 			term := t.terms[ptr]
 			s.Leaves++
 			for _, e := range term.entries {
@@ -277,12 +470,8 @@ func (t *Index) Analyze() fts.Stats {
 		if int(ptr) >= len(t.nodes) {
 			return
 		}
+		visit(currentDepth)
 		n := t.nodes[ptr]
-		s.Nodes++
-		totalDepth += currentDepth
-		if currentDepth > s.MaxDepth {
-			s.MaxDepth = currentDepth
-		}
 
 		childCount := len(n.children)
 		s.TotalChildren += childCount
@@ -303,7 +492,7 @@ func (t *Index) Analyze() fts.Stats {
 		s.AvgDepth = float64(totalDepth) / float64(s.Nodes)
 	}
 
-	for d := 1; d <= depth; d++ {
+	for d := 0; d < depth-1; d++ {
 		if levelNodeCount[d] > 0 {
 			s.AvgChildrenPerLevel = append(s.AvgChildrenPerLevel,
 				float64(levelChildrenSum[d])/float64(levelNodeCount[d]))
@@ -315,4 +504,22 @@ func (t *Index) Analyze() fts.Stats {
 	return s
 }
 
-var _ fts.Index = (*Index)(nil)
+// Reset empties the index, releasing the node and terminal arrays to the
+// garbage collector. It holds the lock exclusively; see fts.Resetter for when
+// it is safe to call.
+func (t *Index) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.nodes = make([]node, 1)
+	t.terms = nil
+}
+
+var (
+	_ fts.Index           = (*Index)(nil)
+	_ fts.PositionalIndex = (*Index)(nil)
+	_ fts.FuzzySearcher   = (*Index)(nil)
+	_ fts.BatchSearcher   = (*Index)(nil)
+	_ fts.KeyDeleter      = (*Index)(nil)
+	_ fts.Resetter        = (*Index)(nil)
+)
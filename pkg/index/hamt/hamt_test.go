@@ -1,6 +1,17 @@
 package hamt
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"testing"
+
+	"github.com/dariasmyr/fts-engine/pkg/fts"
+	"github.com/dariasmyr/fts-engine/pkg/keygen"
+)
 
 func TestIndexInsertAndSearch(t *testing.T) {
 	idx := New()
@@ -75,3 +86,360 @@ func TestIndexAnalyze(t *testing.T) {
 		t.Fatalf("stats.Nodes = %d, want > 0", stats.Nodes)
 	}
 }
+
+func TestIndexAnalyzeCountsWholePath(t *testing.T) {
+	idx := New()
+	_ = idx.Insert("hotel", "doc-1")
+
+	// One key is a chain of inner nodes at depths 0..depth-2 and a terminal
+	// at depth-1.
+	stats := idx.Analyze()
+	if stats.Nodes != depth || stats.Leaves != 1 || stats.MaxDepth != depth-1 {
+		t.Fatalf("stats = %+v, want %d nodes, 1 leaf, max depth %d", stats, depth, depth-1)
+	}
+	if want := float64(depth-1) / 2; stats.AvgDepth != want {
+		t.Fatalf("AvgDepth = %v, want %v", stats.AvgDepth, want)
+	}
+	if len(stats.AvgChildrenPerLevel) != depth-1 || stats.AvgChildrenPerLevel[0] != 1 {
+		t.Fatalf("AvgChildrenPerLevel = %v, want %d levels starting at the root", stats.AvgChildrenPerLevel, depth-1)
+	}
+}
+
+func TestIndexDelete(t *testing.T) {
+	idx := New()
+
+	docs := map[fts.DocID][]string{
+		"doc-1": {"hotel", "barge", "hotelier"},
+		"doc-2": {"hotel", "river"},
+		"doc-3": {"hotel", "barge", "harbour"},
+	}
+	for id, words := range docs {
+		for _, word := range words {
+			if err := idx.Insert(word, id); err != nil {
+				t.Fatalf("Insert(%q, %q) error = %v", word, id, err)
+			}
+		}
+	}
+
+	before := idx.Analyze().Nodes
+
+	if err := idx.Delete("doc-1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	for _, word := range []string{"hotel", "barge", "hotelier", "river", "harbour"} {
+		found, err := idx.Search(word)
+		if err != nil {
+			t.Fatalf("Search(%q) error = %v", word, err)
+		}
+		for _, doc := range found {
+			if doc.ID == "doc-1" {
+				t.Fatalf("Search(%q) still returns deleted doc-1", word)
+			}
+		}
+	}
+
+	if found, _ := idx.Search("hotelier"); len(found) != 0 {
+		t.Fatalf("Search(hotelier) = %+v, want empty", found)
+	}
+	if found, _ := idx.Search("hotel"); len(found) != 2 {
+		t.Fatalf("len(Search(hotel)) = %d, want 2", len(found))
+	}
+	if found, _ := idx.Search("barge"); len(found) != 1 || found[0].ID != "doc-3" {
+		t.Fatalf("Search(barge) = %+v, want doc-3", found)
+	}
+
+	if after := idx.Analyze().Nodes; after > before {
+		t.Fatalf("Analyze().Nodes = %d after delete, want <= %d", after, before)
+	}
+
+	if err := idx.Insert("hotelier", "doc-4"); err != nil {
+		t.Fatalf("Insert() after delete error = %v", err)
+	}
+	if found, _ := idx.Search("hotelier"); len(found) != 1 || found[0].ID != "doc-4" {
+		t.Fatalf("Search(hotelier) after reinsert = %+v, want doc-4", found)
+	}
+}
+
+// TestIndexDeleteKeepsLongerKey deletes the only document of a key that is a
+// prefix of another key.
+func TestIndexDeleteKeepsLongerKey(t *testing.T) {
+	idx := New()
+
+	_ = idx.Insert("hot", "doc-1")
+	_ = idx.Insert("hotel", "doc-2")
+
+	if err := idx.Delete("doc-1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if docs, _ := idx.Search("hot"); len(docs) != 0 {
+		t.Fatalf("Search(hot) = %+v, want empty", docs)
+	}
+	if docs, _ := idx.Search("hotel"); len(docs) != 1 || docs[0].ID != "doc-2" {
+		t.Fatalf("Search(hotel) = %+v, want doc-2", docs)
+	}
+}
+
+func TestIndexInsertAtRecordsPositions(t *testing.T) {
+	idx := New()
+
+	_ = idx.InsertAt("hotel", "doc-1", 0)
+	_ = idx.InsertAt("hotel", "doc-1", 4)
+	_ = idx.Insert("hotel", "doc-2")
+
+	var buf bytes.Buffer
+	if err := idx.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	for name, index := range map[string]fts.Index{"built": idx, "loaded": loaded} {
+		docs, err := index.Search("hotel")
+		if err != nil {
+			t.Fatalf("%s: Search() error = %v", name, err)
+		}
+		if len(docs) != 2 {
+			t.Fatalf("%s: len(docs) = %d, want 2", name, len(docs))
+		}
+		for _, doc := range docs {
+			switch doc.ID {
+			case "doc-1":
+				if doc.Count != 2 || !slices.Equal(doc.Positions, []uint32{0, 4}) {
+					t.Fatalf("%s: doc-1 = %+v, want Count 2 and Positions [0 4]", name, doc)
+				}
+			case "doc-2":
+				if doc.Count != 1 || len(doc.Positions) != 0 {
+					t.Fatalf("%s: doc-2 = %+v, want Count 1 and no positions", name, doc)
+				}
+			}
+		}
+	}
+}
+
+func TestIndexSearchFuzzy(t *testing.T) {
+	idx := New()
+
+	_ = idx.Insert("hotel", "doc-1")
+	_ = idx.Insert("hotels", "doc-2")
+	_ = idx.Insert("hostel", "doc-3")
+	_ = idx.Insert("straße", "doc-4")
+	_ = idx.Insert("barge", "doc-5")
+
+	matches, err := idx.SearchFuzzy(context.Background(), "hatel", 1)
+	if err != nil {
+		t.Fatalf("SearchFuzzy() error = %v", err)
+	}
+	if len(matches) != 1 || matches[0].Key != "hotel" || matches[0].Distance != 1 {
+		t.Fatalf("SearchFuzzy(hatel, 1) = %+v, want hotel at distance 1", matches)
+	}
+	if len(matches[0].Docs) != 1 || matches[0].Docs[0].ID != "doc-1" {
+		t.Fatalf("SearchFuzzy(hatel, 1) docs = %+v, want doc-1", matches[0].Docs)
+	}
+
+	matches, err = idx.SearchFuzzy(context.Background(), "hotel", 2)
+	if err != nil {
+		t.Fatalf("SearchFuzzy() error = %v", err)
+	}
+	keys := make([]string, 0, len(matches))
+	for _, m := range matches {
+		keys = append(keys, m.Key)
+	}
+	slices.Sort(keys)
+	if want := []string{"hostel", "hotel", "hotels"}; !slices.Equal(keys, want) {
+		t.Fatalf("SearchFuzzy(hotel, 2) keys = %v, want %v", keys, want)
+	}
+
+	matches, err = idx.SearchFuzzy(context.Background(), "strase", 1)
+	if err != nil {
+		t.Fatalf("SearchFuzzy() error = %v", err)
+	}
+	if len(matches) != 1 || matches[0].Key != "straße" {
+		t.Fatalf("SearchFuzzy(strase, 1) = %+v, want straße", matches)
+	}
+}
+
+func TestIndexSearchFuzzyCanceled(t *testing.T) {
+	idx := New()
+	for i := range 1000 {
+		_ = idx.Insert(fmt.Sprintf("hotel%d", i), fts.DocID(fmt.Sprintf("doc-%d", i)))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := idx.SearchFuzzy(ctx, "hotel", 1); !errors.Is(err, context.Canceled) {
+		t.Fatalf("SearchFuzzy() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestIndexHashCollisions(t *testing.T) {
+	// Each pair has the same 32-bit FNV-1a hash, so both keys share one
+	// terminal node.
+	pairs := [][2]string{{"costarring", "liquid"}, {"declinate", "macallums"}}
+	idx := New()
+	for _, pair := range pairs {
+		if strhash32(pair[0]) != strhash32(pair[1]) {
+			t.Fatalf("%q and %q do not collide", pair[0], pair[1])
+		}
+		_ = idx.Insert(pair[0], "doc-1")
+		_ = idx.Insert(pair[1], "doc-2")
+	}
+
+	var buf bytes.Buffer
+	if err := idx.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	for name, index := range map[string]fts.Index{"built": idx, "loaded": loaded} {
+		for _, pair := range pairs {
+			for i, key := range pair {
+				want := fts.DocID(fmt.Sprintf("doc-%d", i+1))
+				docs, err := index.Search(key)
+				if err != nil || len(docs) != 1 || docs[0].ID != want {
+					t.Fatalf("%s: Search(%q) = %+v, %v, want %s", name, key, docs, err, want)
+				}
+			}
+		}
+	}
+
+	if err := idx.DeleteKeys("doc-1", []string{"costarring"}); err != nil {
+		t.Fatalf("DeleteKeys() error = %v", err)
+	}
+	if docs, _ := idx.Search("costarring"); len(docs) != 0 {
+		t.Fatalf("Search(costarring) after DeleteKeys = %+v", docs)
+	}
+	if docs, _ := idx.Search("liquid"); len(docs) != 1 {
+		t.Fatalf("Search(liquid) lost its colliding neighbour: %+v", docs)
+	}
+}
+
+func TestIndexCollisionsDoNotDeepenTrie(t *testing.T) {
+	idx := New()
+	_ = idx.Insert("costarring", "doc-1")
+	before := idx.Analyze()
+
+	// "liquid" shares all 32 hash bits with "costarring" and must end in the
+	// same terminal instead of growing the trie.
+	_ = idx.Insert("liquid", "doc-2")
+	after := idx.Analyze()
+	if after.MaxDepth != before.MaxDepth || after.Nodes != before.Nodes {
+		t.Fatalf("after collision: depth %d, nodes %d; want %d and %d", after.MaxDepth, after.Nodes, before.MaxDepth, before.Nodes)
+	}
+}
+
+func TestStrhash32MatchesFNV(t *testing.T) {
+	// Snapshots store the trie shape, so the hash must stay FNV-1a.
+	for _, key := range []string{"", "a", "hotel", "straße", "copenhagen", "word0123"} {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(key))
+		if got, want := strhash32(key), h.Sum32(); got != want {
+			t.Fatalf("strhash32(%q) = %#x, want %#x", key, got, want)
+		}
+	}
+}
+
+var hashSink uint32
+
+func BenchmarkStrhash32(b *testing.B) {
+	b.ReportAllocs()
+	for range b.N {
+		hashSink = strhash32("copenhagen")
+	}
+}
+
+func TestIndexReset(t *testing.T) {
+	idx := New()
+	_ = idx.InsertAt("hotel", "doc-1", 0)
+	_ = idx.Insert("hotels", "doc-2")
+
+	idx.Reset()
+
+	for _, key := range []string{"hotel", "hotels"} {
+		docs, err := idx.Search(key)
+		if err != nil {
+			t.Fatalf("Search(%q) error = %v", key, err)
+		}
+		if len(docs) != 0 {
+			t.Fatalf("Search(%q) after Reset = %+v, want no postings", key, docs)
+		}
+	}
+	if stats := idx.Analyze(); stats.TotalDocs != 0 {
+		t.Fatalf("Analyze() after Reset = %+v, want no postings", stats)
+	}
+
+	if err := idx.Insert("hotel", "doc-3"); err != nil {
+		t.Fatalf("Insert() after Reset error = %v", err)
+	}
+	if docs, _ := idx.Search("hotel"); len(docs) != 1 || docs[0].ID != "doc-3" {
+		t.Fatalf("Search() = %+v, want doc-3", docs)
+	}
+}
+
+func TestDocumentsAddKeepsIDOrder(t *testing.T) {
+	var d documents
+	for _, id := range []fts.DocID{"doc-3", "doc-1", "doc-4", "doc-1", "doc-0", "doc-3", "doc-2"} {
+		d = d.Add(id, []uint32{uint32(len(d))})
+	}
+
+	var ids []fts.DocID
+	for _, ref := range d {
+		ids = append(ids, ref.ID)
+	}
+	if want := []fts.DocID{"doc-0", "doc-1", "doc-2", "doc-3", "doc-4"}; !slices.Equal(ids, want) {
+		t.Fatalf("IDs = %v, want %v", ids, want)
+	}
+	for _, ref := range d {
+		want := uint32(1)
+		if ref.ID == "doc-1" || ref.ID == "doc-3" {
+			want = 2
+		}
+		if ref.Count != want || len(ref.Positions) != int(want) {
+			t.Fatalf("%s: count %d, positions %v; want %d of each", ref.ID, ref.Count, ref.Positions, want)
+		}
+	}
+}
+
+func TestIndexSearchDocuments(t *testing.T) {
+	ctx := context.Background()
+	svc := fts.New(New(), keygen.Word, fts.WithPositions())
+
+	// Documents arrive out of ID order, so postings are inserted in the
+	// middle of the sorted list as well as at both ends.
+	docs := map[fts.DocID]string{
+		"doc-5": "grand hotel",
+		"doc-1": "hotel hotel by the river",
+		"doc-9": "river barge",
+		"doc-3": "grand hotel on the river",
+		"doc-0": "old hotel",
+	}
+	for _, id := range []fts.DocID{"doc-5", "doc-1", "doc-9", "doc-3", "doc-0"} {
+		if err := svc.IndexDocument(ctx, id, docs[id]); err != nil {
+			t.Fatalf("IndexDocument(%s) error = %v", id, err)
+		}
+	}
+
+	res, err := svc.SearchDocuments(ctx, "hotel river", 10)
+	if err != nil {
+		t.Fatalf("SearchDocuments() error = %v", err)
+	}
+	var ids []fts.DocID
+	for _, r := range res.Results {
+		ids = append(ids, r.ID)
+	}
+	// Two distinct words beat one; ties go by total matches, then by ID.
+	if want := []fts.DocID{"doc-1", "doc-3", "doc-0", "doc-5", "doc-9"}; !slices.Equal(ids, want) {
+		t.Fatalf("results = %v, want %v", ids, want)
+	}
+
+	phrase, err := svc.SearchDocuments(ctx, `"grand hotel"`, 10)
+	if err != nil || phrase.TotalResultsCount != 2 {
+		t.Fatalf(`SearchDocuments("grand hotel") = %+v, %v; want doc-3 and doc-5`, phrase, err)
+	}
+}
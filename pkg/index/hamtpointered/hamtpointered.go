@@ -1,12 +1,14 @@
 package hamtpointered
 
 import (
+	"context"
 	"encoding/gob"
 	"fmt"
 	"github.com/dariasmyr/fts-engine/pkg/fts"
-	"hash/fnv"
+	"github.com/dariasmyr/fts-engine/pkg/fuzzy"
 	"io"
 	"math/bits"
+	"slices"
 	"sync"
 )
 
@@ -14,6 +16,13 @@ const (
 	quant     = 5
 	lowerbits = uint32(1<<quant) - 1
 	depth     = 7
+
+	fnvOffset32 = 2166136261
+	fnvPrime32  = 16777619
+
+	// ctxCheckInterval is how many nodes SearchFuzzy visits between
+	// cancellation checks.
+	ctxCheckInterval = 256
 )
 
 type node struct {
@@ -32,7 +41,9 @@ type entry struct {
 
 type Index struct {
 	root *node
-	mu   sync.RWMutex
+	// mu guards the whole index: one writer or many readers. Reads hand out
+	// copies of the postings.
+	mu sync.RWMutex
 }
 
 type snapshotNode struct {
@@ -46,7 +57,12 @@ type snapshotChild struct {
 }
 
 type snapshotTerminal struct {
-	Entries []entry
+	Entries []snapshotEntry
+}
+
+type snapshotEntry struct {
+	Key  string
+	Docs []fts.DocRef
 }
 
 func newNode() *node {
@@ -93,7 +109,11 @@ func encodeNode(n *node) *snapshotNode {
 		case *node:
 			s.Node = encodeNode(v)
 		case *terminalNode:
-			s.Terminal = &snapshotTerminal{Entries: append([]entry(nil), v.entries...)}
+			entries := make([]snapshotEntry, 0, len(v.entries))
+			for _, e := range v.entries {
+				entries = append(entries, snapshotEntry{Key: e.key, Docs: append([]fts.DocRef(nil), e.docs...)})
+			}
+			s.Terminal = &snapshotTerminal{Entries: entries}
 		}
 		snap.Children = append(snap.Children, s)
 	}
@@ -114,17 +134,27 @@ func decodeNode(s *snapshotNode) *node {
 			continue
 		}
 		if child.Terminal != nil {
-			n.children = append(n.children, &terminalNode{entries: append([]entry(nil), child.Terminal.Entries...)})
+			entries := make([]entry, 0, len(child.Terminal.Entries))
+			for _, e := range child.Terminal.Entries {
+				entries = append(entries, entry{key: e.Key, docs: append([]fts.DocRef(nil), e.Docs...)})
+			}
+			n.children = append(n.children, &terminalNode{entries: entries})
 		}
 	}
 
 	return n
 }
 
+// hashKey is 32-bit FNV-1a over the bytes of key: the same hash as
+// hash/fnv.New32a, which snapshots depend on, without going through a
+// hash.Hash on the insert and search paths.
 func hashKey(key string) uint32 {
-	h := fnv.New32a()
-	_, _ = h.Write([]byte(key))
-	return h.Sum32()
+	h := uint32(fnvOffset32)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= fnvPrime32
+	}
+	return h
 }
 
 func (n *node) nextNode(hash uint32, level int) (child any, pos int, mask uint32) {
@@ -142,12 +172,13 @@ func (n *node) appendChild(newChild any, mask uint32, pos int) {
 	n.children = append(n.children[:pos], append([]any{newChild}, n.children[pos:]...)...)
 }
 
-func (n *node) insertNode(hash uint32, key string, docID fts.DocID, level int) {
+func (n *node) insertNode(hash uint32, key string, docID fts.DocID, at []uint32, level int) {
 	child, pos, mask := n.nextNode(hash, level)
 
 	if level == depth {
 		if child == nil {
-			tn := &terminalNode{entries: []entry{{key: key, docs: []fts.DocRef{{ID: docID, Count: 1}}}}}
+			tn := &terminalNode{entries: []entry{{key: key}}}
+			addDoc(&tn.entries[0].docs, docID, at)
 			n.appendChild(tn, mask, pos)
 			return
 		}
@@ -155,12 +186,13 @@ func (n *node) insertNode(hash uint32, key string, docID fts.DocID, level int) {
 		t := child.(*terminalNode)
 		for i := range t.entries {
 			if key == t.entries[i].key {
-				addDoc(&t.entries[i].docs, docID)
+				addDoc(&t.entries[i].docs, docID, at)
 				return
 			}
 		}
 
-		t.entries = append(t.entries, entry{key: key, docs: []fts.DocRef{{ID: docID, Count: 1}}})
+		t.entries = append(t.entries, entry{key: key})
+		addDoc(&t.entries[len(t.entries)-1].docs, docID, at)
 		return
 	}
 
@@ -170,56 +202,227 @@ func (n *node) insertNode(hash uint32, key string, docID fts.DocID, level int) {
 		child = newChild
 	}
 
-	child.(*node).insertNode(hash, key, docID, level+1)
+	child.(*node).insertNode(hash, key, docID, at, level+1)
 }
 
-func addDoc(docs *[]fts.DocRef, docID fts.DocID) {
+func addDoc(docs *[]fts.DocRef, docID fts.DocID, at []uint32) {
 	for i := range *docs {
 		if (*docs)[i].ID == docID {
 			(*docs)[i].Count++
+			(*docs)[i].Positions = append((*docs)[i].Positions, at...)
 			return
 		}
 	}
-	*docs = append(*docs, fts.DocRef{ID: docID, Count: 1})
+	*docs = append(*docs, fts.DocRef{ID: docID, Count: 1, Positions: append([]uint32(nil), at...)})
 }
 
 func (t *Index) Insert(word string, docID fts.DocID) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	t.root.insertNode(hashKey(word), word, docID, 0)
+	t.root.insertNode(hashKey(word), word, docID, nil, 0)
+	return nil
+}
+
+// InsertAt inserts word like Insert and also records pos as one of its token
+// positions inside docID.
+func (t *Index) InsertAt(word string, docID fts.DocID, pos uint32) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.root.insertNode(hashKey(word), word, docID, []uint32{pos}, 0)
 	return nil
 }
 
+func (t *Index) Delete(docID fts.DocID) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.root.deleteDoc(docID)
+	return nil
+}
+
+// deleteDoc drops docID below n and unlinks children left without entries.
+func (n *node) deleteDoc(docID fts.DocID) {
+	children := make([]any, 0, len(n.children))
+	var bitmap uint32
+	pos := 0
+	for bit := uint32(0); bit <= lowerbits; bit++ {
+		mask := uint32(1) << bit
+		if n.bitmap&mask == 0 {
+			continue
+		}
+		child := n.children[pos]
+		pos++
+
+		switch c := child.(type) {
+		case *node:
+			c.deleteDoc(docID)
+			if len(c.children) == 0 {
+				continue
+			}
+		case *terminalNode:
+			c.entries = entriesWithout(c.entries, docID)
+			if len(c.entries) == 0 {
+				continue
+			}
+		}
+
+		bitmap |= mask
+		children = append(children, child)
+	}
+
+	n.bitmap = bitmap
+	n.children = children
+}
+
+func entriesWithout(entries []entry, docID fts.DocID) []entry {
+	out := make([]entry, 0, len(entries))
+	for _, e := range entries {
+		for i := range e.docs {
+			if e.docs[i].ID != docID {
+				continue
+			}
+			docs := make([]fts.DocRef, 0, len(e.docs)-1)
+			docs = append(docs, e.docs[:i]...)
+			e.docs = append(docs, e.docs[i+1:]...)
+			break
+		}
+		if len(e.docs) > 0 {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
 func (t *Index) Search(word string) ([]fts.DocRef, error) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
 
+	return t.search(word), nil
+}
+
+// SearchBatch looks all keys up under one read lock.
+func (t *Index) SearchBatch(keys []string) (map[string][]fts.DocRef, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	out := make(map[string][]fts.DocRef, len(keys))
+	for _, key := range keys {
+		if docs := t.search(key); len(docs) > 0 {
+			out[key] = docs
+		}
+	}
+	return out, nil
+}
+
+// search returns the postings of word. The caller holds t.mu.
+func (t *Index) search(word string) []fts.DocRef {
+	term := t.terminalOf(word)
+	if term == nil {
+		return nil
+	}
+
+	for i := range term.entries {
+		if word == term.entries[i].key {
+			return slices.Clone(term.entries[i].docs)
+		}
+	}
+	return nil
+}
+
+// terminalOf returns the terminal node word hashes to, or nil. The caller
+// holds t.mu.
+func (t *Index) terminalOf(word string) *terminalNode {
 	hash := hashKey(word)
 	n := t.root
 
 	for level := 0; level <= depth; level++ {
 		child, _, _ := n.nextNode(hash, level)
 		if child == nil {
-			return nil, nil
+			return nil
 		}
 
 		if level == depth {
-			term := child.(*terminalNode)
-			for i := range term.entries {
-				if word == term.entries[i].key {
-					return term.entries[i].docs, nil
-				}
-			}
-			return nil, nil
+			return child.(*terminalNode)
 		}
 
 		n = child.(*node)
 	}
 
-	return nil, nil
+	return nil
+}
+
+// DeleteKeys drops docID from the given keys only. Terminal nodes left
+// without entries stay in place until the next Delete.
+func (t *Index) DeleteKeys(docID fts.DocID, keys []string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, key := range keys {
+		term := t.terminalOf(key)
+		if term == nil {
+			continue
+		}
+		for i := range term.entries {
+			if term.entries[i].key != key {
+				continue
+			}
+			docs := slices.DeleteFunc(slices.Clone(term.entries[i].docs), func(ref fts.DocRef) bool { return ref.ID == docID })
+			if len(docs) == 0 {
+				term.entries = slices.Delete(term.entries, i, i+1)
+			} else {
+				term.entries[i].docs = docs
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// SearchFuzzy compares every stored key against word. Hashing scatters similar
+// keys, so there is no structure to prune by.
+func (t *Index) SearchFuzzy(ctx context.Context, word string, maxDist int) ([]fts.FuzzyMatch, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	m := fuzzy.NewMatcher(word, maxDist)
+	var matches []fts.FuzzyMatch
+
+	visited := 0
+	var walk func(n *node) error
+	walk = func(n *node) error {
+		for _, child := range n.children {
+			if visited%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			visited++
+			switch c := child.(type) {
+			case *node:
+				if err := walk(c); err != nil {
+					return err
+				}
+			case *terminalNode:
+				for _, e := range c.entries {
+					if d, ok := m.Distance(e.key); ok {
+						matches = append(matches, fts.FuzzyMatch{Key: e.key, Distance: d, Docs: slices.Clone(e.docs)})
+					}
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(t.root); err != nil {
+		return nil, err
+	}
+
+	return matches, nil
 }
 
 func (t *Index) Analyze() fts.Stats {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
 	var s fts.Stats
 	var totalDepth int
 
@@ -267,4 +470,21 @@ func (t *Index) Analyze() fts.Stats {
 	return s
 }
 
-var _ fts.Index = (*Index)(nil)
+// Reset replaces the root with an empty node, so the old trie can be
+// collected. It holds the lock exclusively; see fts.Resetter for when it is
+// safe to call.
+func (t *Index) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.root = newNode()
+}
+
+var (
+	_ fts.Index           = (*Index)(nil)
+	_ fts.PositionalIndex = (*Index)(nil)
+	_ fts.FuzzySearcher   = (*Index)(nil)
+	_ fts.BatchSearcher   = (*Index)(nil)
+	_ fts.KeyDeleter      = (*Index)(nil)
+	_ fts.Resetter        = (*Index)(nil)
+)
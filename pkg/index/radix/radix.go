@@ -1,18 +1,61 @@
 package radix
 
 import (
+	"context"
 	"encoding/gob"
 	"fmt"
 	"github.com/dariasmyr/fts-engine/pkg/fts"
+	"github.com/dariasmyr/fts-engine/pkg/fuzzy"
 	"io"
+	"slices"
 	"sync"
+	"unicode/utf8"
 )
 
+// ctxCheckInterval is how many nodes SearchFuzzy and SearchWithDescendants
+// visit between cancellation checks.
+const ctxCheckInterval = 256
+
+// node locks itself: mu guards terminal, docs, positions and children. A
+// node's prefix belongs to the edge from its parent and is guarded by the
+// parent's mu, as splitting an edge rewrites it from there. Locks are always
+// taken from parent to child, so inserts into different subtrees only meet
+// on the nodes they share, and only while passing through them.
 type node struct {
-	terminal bool
-	prefix   string
-	children []*node
-	docs     map[fts.DocID]uint32
+	mu        sync.RWMutex
+	terminal  bool
+	prefix    string
+	children  []*node
+	docs      map[fts.DocID]uint32
+	positions map[fts.DocID][]uint32
+}
+
+// edge is a child as seen from its parent: the prefix is copied under the
+// parent's lock, so it can be read after the lock is released.
+type edge struct {
+	node   *node
+	prefix string
+}
+
+// edges returns the children of n. The caller holds n.mu.
+func (n *node) edges() []edge {
+	edges := make([]edge, len(n.children))
+	for i, child := range n.children {
+		edges[i] = edge{node: child, prefix: child.prefix}
+	}
+	return edges
+}
+
+// child returns the child of n whose edge shares a prefix with rest, and the
+// length of that prefix. Edges of siblings never start with the same byte, so
+// there is at most one. The caller holds n.mu.
+func (n *node) child(rest string) (int, *node, int) {
+	for i, child := range n.children {
+		if p := lcp(rest, child.prefix); p > 0 {
+			return i, child, p
+		}
+	}
+	return -1, nil, 0
 }
 
 func newNode(prefix string) *node {
@@ -22,9 +65,26 @@ func newNode(prefix string) *node {
 	}
 }
 
+// addDoc counts one more occurrence of docID and records its positions, if any.
+// The positions map is allocated lazily so count-only indexes do not pay for it.
+func (n *node) addDoc(docID fts.DocID, at []uint32) {
+	n.terminal = true
+	n.docs[docID]++
+	if len(at) == 0 {
+		return
+	}
+	if n.positions == nil {
+		n.positions = make(map[fts.DocID][]uint32)
+	}
+	n.positions[docID] = append(n.positions[docID], at...)
+}
+
 type Index struct {
 	root *node
-	mu   sync.RWMutex
+	// mu guards the tree as a whole. Inserts, searches and DeleteKeys share
+	// it and lock the nodes they visit; Delete, which prunes and merges
+	// nodes, and Reset take it alone.
+	mu sync.RWMutex
 }
 
 type snapshotNode struct {
@@ -46,7 +106,7 @@ func (t *Index) Serialize(w io.Writer) error {
 		return fmt.Errorf("radix: serialize: nil root")
 	}
 
-	if err := gob.NewEncoder(w).Encode(encodeNode(t.root)); err != nil {
+	if err := gob.NewEncoder(w).Encode(encodeNode(t.root, t.root.prefix)); err != nil {
 		return fmt.Errorf("radix: serialize: %w", err)
 	}
 
@@ -62,20 +122,25 @@ func Load(r io.Reader) (fts.Index, error) {
 	return &Index{root: decodeNode(snap)}, nil
 }
 
-func encodeNode(n *node) snapshotNode {
+// encodeNode copies n and its subtree. prefix is n's edge, read by the caller
+// under the parent's lock.
+func encodeNode(n *node, prefix string) snapshotNode {
 	if n == nil {
 		return snapshotNode{}
 	}
 
+	n.mu.RLock()
 	snap := snapshotNode{
 		Terminal: n.terminal,
-		Prefix:   n.prefix,
-		Docs:     collectDocs(n.docs),
-		Children: make([]snapshotNode, 0, len(n.children)),
+		Prefix:   prefix,
+		Docs:     n.collectDocs(),
 	}
+	edges := n.edges()
+	n.mu.RUnlock()
 
-	for _, child := range n.children {
-		snap.Children = append(snap.Children, encodeNode(child))
+	snap.Children = make([]snapshotNode, 0, len(edges))
+	for _, e := range edges {
+		snap.Children = append(snap.Children, encodeNode(e.node, e.prefix))
 	}
 
 	return snap
@@ -86,6 +151,12 @@ func decodeNode(s snapshotNode) *node {
 	n.terminal = s.Terminal
 	for _, doc := range s.Docs {
 		n.docs[doc.ID] = doc.Count
+		if len(doc.Positions) > 0 {
+			if n.positions == nil {
+				n.positions = make(map[fts.DocID][]uint32)
+			}
+			n.positions[doc.ID] = doc.Positions
+		}
 	}
 
 	n.children = make([]*node, 0, len(s.Children))
@@ -105,60 +176,88 @@ func lcp(a, b string) int {
 }
 
 func (t *Index) Insert(word string, docID fts.DocID) error {
-	t.mu.Lock()
-	defer t.mu.Unlock()
+	return t.insert(word, docID, nil)
+}
+
+// InsertAt inserts word like Insert and also records pos as one of its token
+// positions inside docID.
+func (t *Index) InsertAt(word string, docID fts.DocID, pos uint32) error {
+	return t.insert(word, docID, []uint32{pos})
+}
+
+// insert descends with lock coupling: the child is locked before its parent
+// is released. Nodes on the way are only read-locked, so inserts pass through
+// shared nodes together; the node that gets a new child or a split edge is
+// locked for writing, and its children are checked again once it is, as
+// another insert may have changed them in between.
+func (t *Index) insert(word string, docID fts.DocID, at []uint32) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 
 	current := t.root
+	current.mu.RLock()
+	exclusive := false
 	rest := word
 
-	var n *node
 	for {
-		for i, child := range current.children {
-			p := lcp(rest, child.prefix)
-			if p == 0 {
-				continue
-			}
-
-			if p == len(child.prefix) {
-				current = child
-				rest = rest[p:]
-				if rest == "" {
-					current.terminal = true
-					current.docs[docID]++
-					return nil
-				}
-				goto NEXT
+		i, child, p := current.child(rest)
+		if child != nil && p == len(child.prefix) {
+			rest = rest[p:]
+			last := rest == ""
+			if last {
+				child.mu.Lock()
+			} else {
+				child.mu.RLock()
 			}
+			unlock(current, exclusive)
+			current, exclusive = child, last
 
-			common := child.prefix[:p]
-			childSuffix := child.prefix[p:]
-			newSuffix := rest[p:]
-
-			middle := newNode(common)
-			child.prefix = childSuffix
-			middle.children = append(middle.children, child)
-			current.children[i] = middle
-
-			if newSuffix != "" {
-				n = newNode(newSuffix)
-				n.terminal = true
-				n.docs[docID]++
-				middle.children = append(middle.children, n)
+			if last {
+				current.addDoc(docID, at)
+				current.mu.Unlock()
 				return nil
 			}
+			continue
+		}
 
-			middle.terminal = true
-			middle.docs[docID]++
+		if !exclusive {
+			current.mu.RUnlock()
+			current.mu.Lock()
+			exclusive = true
+			continue
+		}
+
+		if child == nil {
+			n := newNode(rest)
+			n.addDoc(docID, at)
+			current.children = append(current.children, n)
+			current.mu.Unlock()
 			return nil
 		}
 
-		n = newNode(rest)
-		n.terminal = true
-		n.docs[docID]++
-		current.children = append(current.children, n)
+		// Split the edge at p. child keeps its own lock, so an insert that
+		// already went down into it carries on undisturbed.
+		middle := newNode(child.prefix[:p])
+		child.prefix = child.prefix[p:]
+		middle.children = append(middle.children, child)
+		if newSuffix := rest[p:]; newSuffix != "" {
+			n := newNode(newSuffix)
+			n.addDoc(docID, at)
+			middle.children = append(middle.children, n)
+		} else {
+			middle.addDoc(docID, at)
+		}
+		current.children[i] = middle
+		current.mu.Unlock()
 		return nil
+	}
+}
 
-	NEXT:
+func unlock(n *node, exclusive bool) {
+	if exclusive {
+		n.mu.Unlock()
+	} else {
+		n.mu.RUnlock()
 	}
 }
 
@@ -166,52 +265,284 @@ func (t *Index) Search(word string) ([]fts.DocRef, error) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
 
+	return t.search(word), nil
+}
+
+// SearchBatch looks all keys up under one read lock of the tree. Inserts go
+// on meanwhile, so a batch does not see a single state of the index.
+func (t *Index) SearchBatch(keys []string) (map[string][]fts.DocRef, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	out := make(map[string][]fts.DocRef, len(keys))
+	for _, key := range keys {
+		if docs := t.search(key); len(docs) > 0 {
+			out[key] = docs
+		}
+	}
+	return out, nil
+}
+
+// search returns the postings of word. The caller holds t.mu.
+func (t *Index) search(word string) []fts.DocRef {
+	n := t.find(word, false)
+	if n == nil {
+		return nil
+	}
+	defer n.mu.RUnlock()
+
+	return n.collectDocs()
+}
+
+// SearchWithDescendants returns the postings of every key that starts with
+// prefix, merged per document: counts add up and positions are joined in order.
+func (t *Index) SearchWithDescendants(ctx context.Context, prefix string) ([]fts.DocRef, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	n := t.findPrefix(prefix)
+	if n == nil {
+		return nil, nil
+	}
+
+	merged := make(map[fts.DocID]*fts.DocRef)
+	visited := 0
+	var walk func(n *node) error
+	walk = func(n *node) error {
+		if visited%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		visited++
+
+		n.mu.RLock()
+		for id, count := range n.docs {
+			ref, ok := merged[id]
+			if !ok {
+				ref = &fts.DocRef{ID: id}
+				merged[id] = ref
+			}
+			ref.Count += count
+			ref.Positions = append(ref.Positions, n.positions[id]...)
+		}
+		children := slices.Clone(n.children)
+		n.mu.RUnlock()
+
+		for _, child := range children {
+			if err := walk(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(n); err != nil {
+		return nil, err
+	}
+
+	res := make([]fts.DocRef, 0, len(merged))
+	for _, ref := range merged {
+		slices.Sort(ref.Positions)
+		res = append(res, *ref)
+	}
+	return res, nil
+}
+
+// findPrefix returns the highest node whose key starts with prefix, or nil.
+// prefix may end inside the node's edge. The node is returned unlocked: the
+// caller holds t.mu, so it stays in the tree, but new keys may reach it.
+func (t *Index) findPrefix(prefix string) *node {
+	current, rest := t.root, prefix
+	current.mu.RLock()
+	for rest != "" {
+		_, child, p := current.child(rest)
+		if child == nil || (p < len(rest) && p < len(child.prefix)) {
+			current.mu.RUnlock()
+			return nil
+		}
+		child.mu.RLock()
+		current.mu.RUnlock()
+		current, rest = child, rest[p:]
+		if p < len(child.prefix) {
+			break
+		}
+	}
+	current.mu.RUnlock()
+	return current
+}
+
+// find returns the terminal node of word, or nil. The node is returned
+// locked, for writing when exclusive and for reading otherwise, and the
+// caller unlocks it. The caller holds t.mu.
+func (t *Index) find(word string, exclusive bool) *node {
 	current := t.root
+	current.mu.RLock()
 	rest := word
 
 	for {
-		nextNode, nextRest, matched, exact := t.next(current, rest)
-		if !matched {
-			return nil, nil
+		_, child, p := current.child(rest)
+		if child == nil || p < len(child.prefix) {
+			current.mu.RUnlock()
+			return nil
 		}
-		if exact {
-			return collectDocs(nextNode.docs), nil
+
+		rest = rest[p:]
+		last := rest == ""
+		if last && exclusive {
+			child.mu.Lock()
+		} else {
+			child.mu.RLock()
 		}
-		current = nextNode
-		rest = nextRest
-	}
-}
+		current.mu.RUnlock()
 
-func collectDocs(docs map[fts.DocID]uint32) []fts.DocRef {
-	res := make([]fts.DocRef, 0, len(docs))
-	for id, count := range docs {
-		res = append(res, fts.DocRef{ID: id, Count: count})
+		if !last {
+			current = child
+			continue
+		}
+		if child.terminal {
+			return child
+		}
+		unlock(child, exclusive)
+		return nil
 	}
-	return res
 }
 
-func (t *Index) next(current *node, rest string) (*node, string, bool, bool) {
-	for _, child := range current.children {
-		p := lcp(rest, child.prefix)
-		if p == 0 {
+// DeleteKeys drops docID from the given keys only. Nodes left without
+// documents stop matching but stay in the tree until the next Delete.
+func (t *Index) DeleteKeys(docID fts.DocID, keys []string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, key := range keys {
+		n := t.find(key, true)
+		if n == nil {
 			continue
 		}
-		if p == len(rest) {
-			if child.terminal {
-				return child, "", true, true
+		delete(n.docs, docID)
+		delete(n.positions, docID)
+		if len(n.docs) == 0 {
+			n.terminal = false
+		}
+		n.mu.Unlock()
+	}
+	return nil
+}
+
+// SearchFuzzy walks the trie depth-first, extending one Levenshtein row per
+// rune and skipping subtrees whose row can no longer get within maxDist.
+func (t *Index) SearchFuzzy(ctx context.Context, word string, maxDist int) ([]fts.FuzzyMatch, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	m := fuzzy.NewMatcher(word, maxDist)
+	var matches []fts.FuzzyMatch
+
+	visited := 0
+	var walk func(edges []edge, path []byte, decoded int, row fuzzy.Row) error
+	walk = func(edges []edge, path []byte, decoded int, row fuzzy.Row) error {
+		for _, e := range edges {
+			if visited%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			visited++
+			key := append(path[:len(path):len(path)], e.prefix...)
+			rowAt, at := row, decoded
+			viable := true
+			for at < len(key) && utf8.FullRune(key[at:]) {
+				r, size := utf8.DecodeRune(key[at:])
+				rowAt = m.Step(rowAt, r)
+				at += size
+				if !m.Viable(rowAt) {
+					viable = false
+					break
+				}
+			}
+			if !viable {
+				continue
+			}
+
+			d, match := 0, false
+			if at == len(key) {
+				d, match = m.Match(rowAt)
+			}
+			child := e.node
+			child.mu.RLock()
+			if match && child.terminal {
+				matches = append(matches, fts.FuzzyMatch{Key: string(key), Distance: d, Docs: child.collectDocs()})
 			}
-			return nil, "", false, false
+			next := child.edges()
+			child.mu.RUnlock()
+
+			if err := walk(next, key, at, rowAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	t.root.mu.RLock()
+	edges := t.root.edges()
+	t.root.mu.RUnlock()
+	if err := walk(edges, nil, 0, m.Start()); err != nil {
+		return nil, err
+	}
+
+	return matches, nil
+}
+
+func (t *Index) Delete(docID fts.DocID) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.root.children = deleteDoc(t.root.children, docID)
+	return nil
+}
+
+// deleteDoc drops docID from every node below children, prunes branches left
+// without documents and merges non-terminal single-child nodes back into one
+// edge. The caller holds t.mu alone, so no node is locked.
+func deleteDoc(children []*node, docID fts.DocID) []*node {
+	kept := children[:0]
+	for _, child := range children {
+		delete(child.docs, docID)
+		delete(child.positions, docID)
+		if len(child.docs) == 0 {
+			child.terminal = false
 		}
-		if p == len(child.prefix) {
-			return child, rest[p:], true, false
+
+		child.children = deleteDoc(child.children, docID)
+
+		if !child.terminal {
+			switch len(child.children) {
+			case 0:
+				continue
+			case 1:
+				merged := child.children[0]
+				merged.prefix = child.prefix + merged.prefix
+				child = merged
+			}
 		}
-		return nil, "", false, false
+
+		kept = append(kept, child)
 	}
 
-	return nil, "", false, false
+	clear(children[len(kept):])
+	return kept
+}
+
+func (n *node) collectDocs() []fts.DocRef {
+	res := make([]fts.DocRef, 0, len(n.docs))
+	for id, count := range n.docs {
+		res = append(res, fts.DocRef{ID: id, Count: count, Positions: n.positions[id]})
+	}
+	return res
 }
 
 func (t *Index) Analyze() fts.Stats {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
 	var s fts.Stats
 	var totalDepth int
 
@@ -220,22 +551,27 @@ func (t *Index) Analyze() fts.Stats {
 
 	var dfs func(n *node, depth int)
 	dfs = func(n *node, depth int) {
+		n.mu.RLock()
+		terminal, docs := n.terminal, len(n.docs)
+		children := slices.Clone(n.children)
+		n.mu.RUnlock()
+
 		s.Nodes++
 		totalDepth += depth
-		if n.terminal {
+		if terminal {
 			s.Leaves++
 		}
 		if depth > s.MaxDepth {
 			s.MaxDepth = depth
 		}
-		s.TotalDocs += len(n.docs)
+		s.TotalDocs += docs
 
-		numChildren := len(n.children)
+		numChildren := len(children)
 		s.TotalChildren += numChildren
 		levelChildrenSum[depth] += numChildren
 		levelNodeCount[depth]++
 
-		for _, c := range n.children {
+		for _, c := range children {
 			if c != nil {
 				dfs(c, depth+1)
 			}
@@ -259,4 +595,24 @@ func (t *Index) Analyze() fts.Stats {
 	return s
 }
 
-var _ fts.Index = (*Index)(nil)
+// Reset drops every key, so the memory of the old structure can be reclaimed
+// once no caller holds postings read from it. It takes the write lock like
+// Insert, but a search running meanwhile sees either the full or the empty
+// index, so reset only an index that is no longer searched, such as the one a
+// rebuilt service replaced.
+func (t *Index) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.root = newNode("")
+}
+
+var (
+	_ fts.Index              = (*Index)(nil)
+	_ fts.PositionalIndex    = (*Index)(nil)
+	_ fts.FuzzySearcher      = (*Index)(nil)
+	_ fts.BatchSearcher      = (*Index)(nil)
+	_ fts.KeyDeleter         = (*Index)(nil)
+	_ fts.Resetter           = (*Index)(nil)
+	_ fts.DescendantSearcher = (*Index)(nil)
+)
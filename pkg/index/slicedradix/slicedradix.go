@@ -1,13 +1,21 @@
 package slicedradix
 
 import (
+	"context"
 	"encoding/gob"
 	"fmt"
 	"github.com/dariasmyr/fts-engine/pkg/fts"
+	"github.com/dariasmyr/fts-engine/pkg/fuzzy"
 	"io"
+	"slices"
 	"sync"
+	"unicode/utf8"
 )
 
+// ctxCheckInterval is how many nodes SearchFuzzy visits between cancellation
+// checks.
+const ctxCheckInterval = 256
+
 type node struct {
 	prefix   string
 	children []int
@@ -17,7 +25,9 @@ type node struct {
 type Index struct {
 	root  int
 	nodes []node
-	mu    sync.RWMutex
+	// mu guards nodes and their postings. Search returns copies, as inserts
+	// update postings in place.
+	mu sync.RWMutex
 }
 
 type snapshotNode struct {
@@ -99,6 +109,16 @@ func lcp(a, b string) int {
 }
 
 func (t *Index) Insert(word string, docID fts.DocID) error {
+	return t.insert(word, docID, nil)
+}
+
+// InsertAt inserts word like Insert and also records pos as one of its token
+// positions inside docID.
+func (t *Index) InsertAt(word string, docID fts.DocID, pos uint32) error {
+	return t.insert(word, docID, []uint32{pos})
+}
+
+func (t *Index) insert(word string, docID fts.DocID, at []uint32) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
@@ -117,7 +137,7 @@ func (t *Index) Insert(word string, docID fts.DocID) error {
 				current = child
 				rest = rest[p:]
 				if rest == "" {
-					t.addDoc(current, docID)
+					t.addDoc(current, docID, at)
 					return nil
 				}
 				advanced = true
@@ -135,12 +155,12 @@ func (t *Index) Insert(word string, docID fts.DocID) error {
 
 			if newSuffix != "" {
 				newIdx := t.newNode(newSuffix)
-				t.addDoc(newIdx, docID)
+				t.addDoc(newIdx, docID, at)
 				t.nodes[middle].children = append(t.nodes[middle].children, newIdx)
 				return nil
 			}
 
-			t.addDoc(middle, docID)
+			t.addDoc(middle, docID, at)
 			return nil
 		}
 
@@ -149,43 +169,200 @@ func (t *Index) Insert(word string, docID fts.DocID) error {
 		}
 
 		newIdx := t.newNode(rest)
-		t.addDoc(newIdx, docID)
+		t.addDoc(newIdx, docID, at)
 		t.nodes[current].children = append(t.nodes[current].children, newIdx)
 		return nil
 	}
 }
 
-func (t *Index) addDoc(nodeIdx int, docID fts.DocID) {
+func (t *Index) addDoc(nodeIdx int, docID fts.DocID, at []uint32) {
 	n := &t.nodes[nodeIdx]
 	for i := range n.docs {
 		if n.docs[i].ID == docID {
 			n.docs[i].Count++
+			n.docs[i].Positions = append(n.docs[i].Positions, at...)
 			return
 		}
 	}
-	n.docs = append(n.docs, fts.DocRef{ID: docID, Count: 1})
+	n.docs = append(n.docs, fts.DocRef{ID: docID, Count: 1, Positions: append([]uint32(nil), at...)})
+}
+
+// Delete drops docID from every node and compacts the node slice, pruning
+// branches left without documents. The root keeps index 0.
+func (t *Index) Delete(docID fts.DocID) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	nodes := make([]node, 1, len(t.nodes))
+
+	var compact func(old int) (int, bool)
+	compact = func(old int) (int, bool) {
+		n := t.nodes[old]
+		docs := removeDoc(n.docs, docID)
+
+		children := make([]int, 0, len(n.children))
+		for _, child := range n.children {
+			if idx, ok := compact(child); ok {
+				children = append(children, idx)
+			}
+		}
+
+		if len(docs) == 0 {
+			switch len(children) {
+			case 0:
+				return 0, false
+			case 1:
+				nodes[children[0]].prefix = n.prefix + nodes[children[0]].prefix
+				return children[0], true
+			}
+		}
+
+		nodes = append(nodes, node{prefix: n.prefix, children: children, docs: docs})
+		return len(nodes) - 1, true
+	}
+
+	root := t.nodes[t.root]
+	rootChildren := make([]int, 0, len(root.children))
+	for _, child := range root.children {
+		if idx, ok := compact(child); ok {
+			rootChildren = append(rootChildren, idx)
+		}
+	}
+	nodes[0] = node{prefix: root.prefix, children: rootChildren, docs: removeDoc(root.docs, docID)}
+
+	t.nodes = nodes
+	t.root = 0
+	return nil
+}
+
+// removeDoc returns docs without docID. A new slice is allocated on removal
+// because Search hands the stored slice to callers.
+func removeDoc(docs []fts.DocRef, docID fts.DocID) []fts.DocRef {
+	for i := range docs {
+		if docs[i].ID != docID {
+			continue
+		}
+		out := make([]fts.DocRef, 0, len(docs)-1)
+		out = append(out, docs[:i]...)
+		return append(out, docs[i+1:]...)
+	}
+	return docs
 }
 
 func (t *Index) Search(word string) ([]fts.DocRef, error) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
 
+	return t.search(word), nil
+}
+
+// SearchBatch looks all keys up under one read lock.
+func (t *Index) SearchBatch(keys []string) (map[string][]fts.DocRef, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	out := make(map[string][]fts.DocRef, len(keys))
+	for _, key := range keys {
+		if docs := t.search(key); len(docs) > 0 {
+			out[key] = docs
+		}
+	}
+	return out, nil
+}
+
+// search returns the postings of word. The caller holds t.mu.
+func (t *Index) search(word string) []fts.DocRef {
+	if i, ok := t.find(word); ok {
+		return slices.Clone(t.nodes[i].docs)
+	}
+	return nil
+}
+
+// find returns the node index of word. The caller holds t.mu.
+func (t *Index) find(word string) (int, bool) {
 	current := t.root
 	rest := word
 
 	for {
 		nextNode, nextRest, matched, exact := t.next(current, rest)
 		if nextNode == 0 || !matched {
-			return nil, nil
+			return 0, false
 		}
 		if exact {
-			return t.nodes[nextNode].docs, nil
+			return nextNode, true
 		}
 		current = nextNode
 		rest = nextRest
 	}
 }
 
+// DeleteKeys drops docID from the given keys only. Nodes left without
+// documents stop matching but stay in the slice until the next Delete.
+func (t *Index) DeleteKeys(docID fts.DocID, keys []string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, key := range keys {
+		if i, ok := t.find(key); ok {
+			t.nodes[i].docs = removeDoc(t.nodes[i].docs, docID)
+		}
+	}
+	return nil
+}
+
+// SearchFuzzy walks the trie depth-first, extending one Levenshtein row per
+// rune and skipping subtrees whose row can no longer get within maxDist.
+func (t *Index) SearchFuzzy(ctx context.Context, word string, maxDist int) ([]fts.FuzzyMatch, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	m := fuzzy.NewMatcher(word, maxDist)
+	var matches []fts.FuzzyMatch
+
+	visited := 0
+	var walk func(n int, path []byte, decoded int, row fuzzy.Row) error
+	walk = func(n int, path []byte, decoded int, row fuzzy.Row) error {
+		for _, child := range t.nodes[n].children {
+			if visited%ctxCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			visited++
+			key := append(path[:len(path):len(path)], t.nodes[child].prefix...)
+			rowAt, at := row, decoded
+			viable := true
+			for at < len(key) && utf8.FullRune(key[at:]) {
+				r, size := utf8.DecodeRune(key[at:])
+				rowAt = m.Step(rowAt, r)
+				at += size
+				if !m.Viable(rowAt) {
+					viable = false
+					break
+				}
+			}
+			if !viable {
+				continue
+			}
+
+			if t.nodes[child].isTerminal() && at == len(key) {
+				if d, ok := m.Match(rowAt); ok {
+					matches = append(matches, fts.FuzzyMatch{Key: string(key), Distance: d, Docs: slices.Clone(t.nodes[child].docs)})
+				}
+			}
+			if err := walk(child, key, at, rowAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(t.root, nil, 0, m.Start()); err != nil {
+		return nil, err
+	}
+
+	return matches, nil
+}
+
 func (t *Index) next(current int, rest string) (int, string, bool, bool) {
 	for _, child := range t.nodes[current].children {
 		p := lcp(rest, t.nodes[child].prefix)
@@ -211,6 +388,9 @@ func (n *node) isTerminal() bool {
 }
 
 func (t *Index) Analyze() fts.Stats {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
 	var s fts.Stats
 	var totalDepth int
 
@@ -256,4 +436,21 @@ func (t *Index) Analyze() fts.Stats {
 	return s
 }
 
-var _ fts.Index = (*Index)(nil)
+// Reset drops the node array and starts over from an empty root. It holds the
+// lock exclusively; see fts.Resetter for when it is safe to call.
+func (t *Index) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.nodes = nil
+	t.root = t.newNode("")
+}
+
+var (
+	_ fts.Index           = (*Index)(nil)
+	_ fts.PositionalIndex = (*Index)(nil)
+	_ fts.FuzzySearcher   = (*Index)(nil)
+	_ fts.BatchSearcher   = (*Index)(nil)
+	_ fts.KeyDeleter      = (*Index)(nil)
+	_ fts.Resetter        = (*Index)(nil)
+)
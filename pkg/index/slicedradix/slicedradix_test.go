@@ -1,6 +1,15 @@
 package slicedradix
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/dariasmyr/fts-engine/pkg/fts"
+)
 
 func TestIndexInsertAndSearch(t *testing.T) {
 	idx := New()
@@ -75,3 +84,199 @@ func TestIndexAnalyze(t *testing.T) {
 		t.Fatalf("stats.Nodes = %d, want > 0", stats.Nodes)
 	}
 }
+
+func TestIndexDelete(t *testing.T) {
+	idx := New()
+
+	docs := map[fts.DocID][]string{
+		"doc-1": {"hotel", "barge", "hotelier"},
+		"doc-2": {"hotel", "river"},
+		"doc-3": {"hotel", "barge", "harbour"},
+	}
+	for id, words := range docs {
+		for _, word := range words {
+			if err := idx.Insert(word, id); err != nil {
+				t.Fatalf("Insert(%q, %q) error = %v", word, id, err)
+			}
+		}
+	}
+
+	before := idx.Analyze().Nodes
+
+	if err := idx.Delete("doc-1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	for _, word := range []string{"hotel", "barge", "hotelier", "river", "harbour"} {
+		found, err := idx.Search(word)
+		if err != nil {
+			t.Fatalf("Search(%q) error = %v", word, err)
+		}
+		for _, doc := range found {
+			if doc.ID == "doc-1" {
+				t.Fatalf("Search(%q) still returns deleted doc-1", word)
+			}
+		}
+	}
+
+	if found, _ := idx.Search("hotelier"); len(found) != 0 {
+		t.Fatalf("Search(hotelier) = %+v, want empty", found)
+	}
+	if found, _ := idx.Search("hotel"); len(found) != 2 {
+		t.Fatalf("len(Search(hotel)) = %d, want 2", len(found))
+	}
+	if found, _ := idx.Search("barge"); len(found) != 1 || found[0].ID != "doc-3" {
+		t.Fatalf("Search(barge) = %+v, want doc-3", found)
+	}
+
+	if after := idx.Analyze().Nodes; after > before {
+		t.Fatalf("Analyze().Nodes = %d after delete, want <= %d", after, before)
+	}
+
+	if err := idx.Insert("hotelier", "doc-4"); err != nil {
+		t.Fatalf("Insert() after delete error = %v", err)
+	}
+	if found, _ := idx.Search("hotelier"); len(found) != 1 || found[0].ID != "doc-4" {
+		t.Fatalf("Search(hotelier) after reinsert = %+v, want doc-4", found)
+	}
+}
+
+func TestIndexDeleteMergesPrefix(t *testing.T) {
+	idx := New()
+
+	_ = idx.Insert("hot", "doc-1")
+	_ = idx.Insert("hotel", "doc-2")
+
+	if err := idx.Delete("doc-1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if docs, _ := idx.Search("hot"); len(docs) != 0 {
+		t.Fatalf("Search(hot) = %+v, want empty", docs)
+	}
+	if docs, _ := idx.Search("hotel"); len(docs) != 1 || docs[0].ID != "doc-2" {
+		t.Fatalf("Search(hotel) = %+v, want doc-2", docs)
+	}
+}
+
+func TestIndexInsertAtRecordsPositions(t *testing.T) {
+	idx := New()
+
+	_ = idx.InsertAt("hotel", "doc-1", 0)
+	_ = idx.InsertAt("hotel", "doc-1", 4)
+	_ = idx.Insert("hotel", "doc-2")
+
+	var buf bytes.Buffer
+	if err := idx.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize() error = %v", err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	for name, index := range map[string]fts.Index{"built": idx, "loaded": loaded} {
+		docs, err := index.Search("hotel")
+		if err != nil {
+			t.Fatalf("%s: Search() error = %v", name, err)
+		}
+		if len(docs) != 2 {
+			t.Fatalf("%s: len(docs) = %d, want 2", name, len(docs))
+		}
+		for _, doc := range docs {
+			switch doc.ID {
+			case "doc-1":
+				if doc.Count != 2 || !slices.Equal(doc.Positions, []uint32{0, 4}) {
+					t.Fatalf("%s: doc-1 = %+v, want Count 2 and Positions [0 4]", name, doc)
+				}
+			case "doc-2":
+				if doc.Count != 1 || len(doc.Positions) != 0 {
+					t.Fatalf("%s: doc-2 = %+v, want Count 1 and no positions", name, doc)
+				}
+			}
+		}
+	}
+}
+
+func TestIndexSearchFuzzy(t *testing.T) {
+	idx := New()
+
+	_ = idx.Insert("hotel", "doc-1")
+	_ = idx.Insert("hotels", "doc-2")
+	_ = idx.Insert("hostel", "doc-3")
+	_ = idx.Insert("straße", "doc-4")
+	_ = idx.Insert("barge", "doc-5")
+
+	matches, err := idx.SearchFuzzy(context.Background(), "hatel", 1)
+	if err != nil {
+		t.Fatalf("SearchFuzzy() error = %v", err)
+	}
+	if len(matches) != 1 || matches[0].Key != "hotel" || matches[0].Distance != 1 {
+		t.Fatalf("SearchFuzzy(hatel, 1) = %+v, want hotel at distance 1", matches)
+	}
+	if len(matches[0].Docs) != 1 || matches[0].Docs[0].ID != "doc-1" {
+		t.Fatalf("SearchFuzzy(hatel, 1) docs = %+v, want doc-1", matches[0].Docs)
+	}
+
+	matches, err = idx.SearchFuzzy(context.Background(), "hotel", 2)
+	if err != nil {
+		t.Fatalf("SearchFuzzy() error = %v", err)
+	}
+	keys := make([]string, 0, len(matches))
+	for _, m := range matches {
+		keys = append(keys, m.Key)
+	}
+	slices.Sort(keys)
+	if want := []string{"hostel", "hotel", "hotels"}; !slices.Equal(keys, want) {
+		t.Fatalf("SearchFuzzy(hotel, 2) keys = %v, want %v", keys, want)
+	}
+
+	matches, err = idx.SearchFuzzy(context.Background(), "strase", 1)
+	if err != nil {
+		t.Fatalf("SearchFuzzy() error = %v", err)
+	}
+	if len(matches) != 1 || matches[0].Key != "straße" {
+		t.Fatalf("SearchFuzzy(strase, 1) = %+v, want straße", matches)
+	}
+}
+
+func TestIndexSearchFuzzyCanceled(t *testing.T) {
+	idx := New()
+	for i := range 1000 {
+		_ = idx.Insert(fmt.Sprintf("hotel%d", i), fts.DocID(fmt.Sprintf("doc-%d", i)))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := idx.SearchFuzzy(ctx, "hotel", 1); !errors.Is(err, context.Canceled) {
+		t.Fatalf("SearchFuzzy() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestIndexReset(t *testing.T) {
+	idx := New()
+	_ = idx.InsertAt("hotel", "doc-1", 0)
+	_ = idx.Insert("hotels", "doc-2")
+
+	idx.Reset()
+
+	for _, key := range []string{"hotel", "hotels"} {
+		docs, err := idx.Search(key)
+		if err != nil {
+			t.Fatalf("Search(%q) error = %v", key, err)
+		}
+		if len(docs) != 0 {
+			t.Fatalf("Search(%q) after Reset = %+v, want no postings", key, docs)
+		}
+	}
+	if stats := idx.Analyze(); stats.TotalDocs != 0 {
+		t.Fatalf("Analyze() after Reset = %+v, want no postings", stats)
+	}
+
+	if err := idx.Insert("hotel", "doc-3"); err != nil {
+		t.Fatalf("Insert() after Reset error = %v", err)
+	}
+	if docs, _ := idx.Search("hotel"); len(docs) != 1 || docs[0].ID != "doc-3" {
+		t.Fatalf("Search() = %+v, want doc-3", docs)
+	}
+}
@@ -1,8 +1,13 @@
 package keygen
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"testing"
+
+	"github.com/dariasmyr/fts-engine/pkg/fts"
+	"github.com/dariasmyr/fts-engine/pkg/index/radix"
 )
 
 func TestWord(t *testing.T) {
@@ -16,3 +21,144 @@ func TestWord(t *testing.T) {
 		t.Fatalf("Word() = %v, want %v", got, want)
 	}
 }
+
+func TestTrigram(t *testing.T) {
+	got, err := Trigram("hotel")
+	if err != nil {
+		t.Fatalf("Trigram() error = %v", err)
+	}
+
+	want := []string{"hot", "ote", "tel"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Trigram() = %v, want %v", got, want)
+	}
+}
+
+func TestNGramLowercases(t *testing.T) {
+	for token, want := range map[string][]string{
+		"NASA":  {"nas", "asa"},
+		"HoTel": {"hot", "ote", "tel"},
+		"UN":    {"un"},
+		"ÉCOLE": {"éco", "col", "ole"},
+	} {
+		got, err := Trigram(token)
+		if err != nil {
+			t.Fatalf("Trigram(%q) error = %v", token, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("Trigram(%q) = %v, want %v", token, got, want)
+		}
+	}
+}
+
+func TestNGramSizes(t *testing.T) {
+	tests := []struct {
+		n     int
+		token string
+		want  []string
+	}{
+		{n: 2, token: "hotel", want: []string{"ho", "ot", "te", "el"}},
+		{n: 4, token: "hotel", want: []string{"hote", "otel"}},
+		{n: 4, token: "inn", want: []string{"inn"}},
+		{n: 1, token: "go", want: []string{"g", "o"}},
+		{n: 3, token: "", want: nil},
+	}
+
+	for _, tt := range tests {
+		got, err := NGram(tt.n)(tt.token)
+		if err != nil {
+			t.Fatalf("NGram(%d)(%q) error = %v", tt.n, tt.token, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("NGram(%d)(%q) = %v, want %v", tt.n, tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestNGramInvalidSize(t *testing.T) {
+	_, err := NGram(0)("hotel")
+	if !errors.Is(err, ErrInvalidNGramSize) {
+		t.Fatalf("NGram(0) error = %v, want ErrInvalidNGramSize", err)
+	}
+}
+
+func TestTrigramUnicodeAndDigits(t *testing.T) {
+	tests := []struct {
+		token string
+		want  []string
+	}{
+		{token: "mp3", want: []string{"mp3"}},
+		{token: "covid19", want: []string{"cov", "ovi", "vid", "id1", "d19"}},
+		{token: "café", want: []string{"caf", "afé"}},
+		{token: "москва", want: []string{"мос", "оск", "скв", "ква"}},
+	}
+
+	for _, tt := range tests {
+		got, err := Trigram(tt.token)
+		if err != nil {
+			t.Fatalf("Trigram(%q) error = %v", tt.token, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("Trigram(%q) = %v, want %v", tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestSynonyms(t *testing.T) {
+	keys := Synonyms(Trigram, map[string][]string{"NYC": {"newyork"}})
+
+	tests := map[string][]string{
+		"nyc":   {"nyc", "new", "ewy", "wyo", "yor", "ork"},
+		"Nyc":   {"nyc", "new", "ewy", "wyo", "yor", "ork"},
+		"hotel": {"hot", "ote", "tel"},
+	}
+	for token, want := range tests {
+		got, err := keys(token)
+		if err != nil {
+			t.Fatalf("Synonyms(%q) error = %v", token, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("Synonyms(%q) = %v, want %v", token, got, want)
+		}
+	}
+}
+
+func TestSynonymsDropsDuplicateKeys(t *testing.T) {
+	got, err := Synonyms(Trigram, map[string][]string{"hotel": {"hotels"}})("hotel")
+	if err != nil {
+		t.Fatalf("Synonyms() error = %v", err)
+	}
+	if want := []string{"hot", "ote", "tel", "els"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Synonyms() = %v, want %v", got, want)
+	}
+}
+
+func TestSynonymsPassesErrors(t *testing.T) {
+	if _, err := Synonyms(NGram(0), map[string][]string{"nyc": {"newyork"}})("nyc"); !errors.Is(err, ErrInvalidNGramSize) {
+		t.Fatalf("Synonyms() error = %v, want ErrInvalidNGramSize", err)
+	}
+}
+
+func TestSynonymsExpandQuery(t *testing.T) {
+	engine := fts.New(radix.New(), Synonyms(Word, map[string][]string{
+		"nyc":     {"newyork"},
+		"newyork": {"nyc"},
+	}))
+	ctx := context.Background()
+	if err := engine.IndexDocument(ctx, "doc-1", "a hotel in nyc"); err != nil {
+		t.Fatalf("IndexDocument() error = %v", err)
+	}
+	if err := engine.IndexDocument(ctx, "doc-2", "newyork harbour"); err != nil {
+		t.Fatalf("IndexDocument() error = %v", err)
+	}
+
+	for _, query := range []string{"nyc", "newyork"} {
+		result, err := engine.SearchDocuments(ctx, query, 10)
+		if err != nil {
+			t.Fatalf("SearchDocuments(%q) error = %v", query, err)
+		}
+		if len(result.Results) != 2 {
+			t.Fatalf("SearchDocuments(%q) = %+v, want both documents", query, result.Results)
+		}
+	}
+}
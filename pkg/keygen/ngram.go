@@ -0,0 +1,46 @@
+package keygen
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const TrigramSize = 3
+
+var ErrInvalidNGramSize = errors.New("keygen: invalid n-gram size")
+
+// NGram returns a key generator that splits a token into overlapping grams of
+// n runes. Tokens shorter than n are emitted whole so they stay searchable.
+// Grams are lowercased, so "NASA" and "nasa" share their keys whether or not
+// the pipeline lowercases.
+func NGram(n int) func(token string) ([]string, error) {
+	return func(token string) ([]string, error) {
+		return ngrams(token, n)
+	}
+}
+
+func Trigram(token string) ([]string, error) {
+	return ngrams(token, TrigramSize)
+}
+
+func ngrams(token string, n int) ([]string, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidNGramSize, n)
+	}
+	if token == "" {
+		return nil, nil
+	}
+
+	token = strings.ToLower(token)
+	runes := []rune(token)
+	if len(runes) <= n {
+		return []string{token}, nil
+	}
+
+	grams := make([]string, 0, len(runes)-n+1)
+	for i := 0; i+n <= len(runes); i++ {
+		grams = append(grams, string(runes[i:i+n]))
+	}
+	return grams, nil
+}
@@ -0,0 +1,49 @@
+package keygen
+
+import "strings"
+
+// Synonyms returns a key generator that emits the keys base generates for a
+// token and for each of its synonyms, without duplicates. Tokens are looked
+// up in synonyms case-insensitively, so the map should list them as the
+// pipeline emits them, apart from case. Used at both index and query time,
+// a document with "nyc" and a query for "newyork" then find each other given
+// "nyc": {"newyork"}; listing the reverse entry too makes the match
+// symmetric. Since the synonyms' keys belong to the same word, it should not
+// be combined with fts.SearchOptions.RequireAllKeys. The map is copied.
+func Synonyms(base func(token string) ([]string, error), synonyms map[string][]string) func(token string) ([]string, error) {
+	expansions := make(map[string][]string, len(synonyms))
+	for token, alternatives := range synonyms {
+		token = strings.ToLower(token)
+		expansions[token] = append(expansions[token], alternatives...)
+	}
+
+	return func(token string) ([]string, error) {
+		keys, err := base(token)
+		if err != nil {
+			return nil, err
+		}
+		alternatives := expansions[strings.ToLower(token)]
+		if len(alternatives) == 0 {
+			return keys, nil
+		}
+
+		seen := make(map[string]struct{}, len(keys))
+		for _, key := range keys {
+			seen[key] = struct{}{}
+		}
+		for _, alternative := range alternatives {
+			more, err := base(alternative)
+			if err != nil {
+				return nil, err
+			}
+			for _, key := range more {
+				if _, ok := seen[key]; ok {
+					continue
+				}
+				seen[key] = struct{}{}
+				keys = append(keys, key)
+			}
+		}
+		return keys, nil
+	}
+}
@@ -0,0 +1,335 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+var ErrSyntax = errors.New("query: syntax error")
+
+// Node is an element of a parsed query.
+type Node interface {
+	node()
+}
+
+// Term is a bare search word. Its text is raw, the caller runs it through
+// its text pipeline. Field names the document field the word must be found
+// in, written as field:word; it is empty for a word that may match any field.
+type Term struct {
+	Field string
+	Text  string
+}
+
+// Phrase is a double-quoted run of words that must occur next to each other,
+// in order. Its text is raw, like a Term's, and Field scopes it the same way,
+// written field:"some words".
+type Phrase struct {
+	Field string
+	Text  string
+}
+
+type And struct {
+	Left, Right Node
+}
+
+type Or struct {
+	Left, Right Node
+}
+
+type Not struct {
+	Operand Node
+}
+
+func (Term) node()   {}
+func (Phrase) node() {}
+func (And) node()    {}
+func (Or) node()     {}
+func (Not) node()    {}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenPhrase
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+// token is a lexed piece of the query. pos is the position of its first
+// character, counted in characters from 1, and text is its source; a phrase
+// keeps its quotes and field.
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// String describes tok for syntax errors.
+func (tok token) String() string {
+	return fmt.Sprintf("%q at position %d", tok.text, tok.pos)
+}
+
+// Parse parses a query made of words, double-quoted phrases, the uppercase
+// operators AND, OR and NOT, and parentheses. Words placed next to each other
+// are joined by OR, matching plain search, while NOT placed after a word binds
+// like AND NOT. Precedence from highest to lowest is NOT, AND, OR. A phrase
+// placed next to other operands without OR is required: `a b "c d"` is
+// (a OR b) AND "c d". A word written field:word, or a phrase written
+// field:"c d", with a field name of letters, digits and underscores, only
+// matches in that field. An unmatched quote is read as a space. Syntax errors
+// give the position of the offending token. A blank query yields a nil Node.
+func Parse(input string) (Node, error) {
+	p := parser{tokens: lex(input)}
+	if len(p.tokens) == 0 {
+		return nil, nil
+	}
+
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %s", ErrSyntax, p.tokens[p.pos])
+	}
+
+	return n, nil
+}
+
+func lex(input string) []token {
+	var tokens []token
+	var word []rune
+	runes := []rune(input)
+	wordPos := 0
+
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		text := string(word)
+		word = word[:0]
+
+		kind := tokenWord
+		switch text {
+		case "AND":
+			kind = tokenAnd
+		case "OR":
+			kind = tokenOr
+		case "NOT":
+			kind = tokenNot
+		}
+		tokens = append(tokens, token{kind: kind, text: text, pos: wordPos})
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '"':
+			end := slices.Index(runes[i+1:], '"')
+			if end < 0 {
+				flush()
+				continue
+			}
+			end += i + 1
+			// A word ending in a field name and a colon scopes the phrase.
+			pos := i + 1
+			if field, ok := strings.CutSuffix(string(word), ":"); ok && isFieldName(field) {
+				pos = wordPos
+			} else {
+				flush()
+			}
+			tokens = append(tokens, token{kind: tokenPhrase, text: string(runes[pos-1 : end+1]), pos: pos})
+			word = word[:0]
+			i = end
+		case r == '(':
+			flush()
+			tokens = append(tokens, token{kind: tokenOpen, text: "(", pos: i + 1})
+		case r == ')':
+			flush()
+			tokens = append(tokens, token{kind: tokenClose, text: ")", pos: i + 1})
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			if len(word) == 0 {
+				wordPos = i + 1
+			}
+			word = append(word, r)
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// parseOr joins its operands by OR, apart from phrases placed next to them
+// without OR, which are joined to the result by AND.
+func (p *parser) parseOr() (Node, error) {
+	var alternatives, required []Node
+	explicit := false
+
+	for {
+		operand, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		tok, ok := p.peek()
+		next := ok && tok.kind == tokenOr
+		if _, phrase := operand.(Phrase); phrase && !explicit && !next {
+			required = append(required, operand)
+		} else {
+			alternatives = append(alternatives, operand)
+		}
+
+		if !ok {
+			break
+		}
+		explicit = next
+		if explicit {
+			p.pos++
+		} else if tok.kind != tokenWord && tok.kind != tokenPhrase && tok.kind != tokenOpen {
+			break
+		}
+	}
+
+	var n Node
+	for _, alternative := range alternatives {
+		if n == nil {
+			n = alternative
+		} else {
+			n = Or{Left: n, Right: alternative}
+		}
+	}
+	for _, phrase := range required {
+		if n == nil {
+			n = phrase
+		} else {
+			n = And{Left: n, Right: phrase}
+		}
+	}
+	return n, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok, ok := p.peek()
+		if !ok {
+			return left, nil
+		}
+
+		switch tok.kind {
+		case tokenAnd:
+			p.pos++
+		case tokenNot:
+		default:
+			return left, nil
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	tok, ok := p.peek()
+	if ok && tok.kind == tokenNot {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Operand: operand}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	tok, ok := p.peek()
+	if !ok {
+		if p.pos == 0 {
+			return nil, fmt.Errorf("%w: empty query", ErrSyntax)
+		}
+		return nil, fmt.Errorf("%w: expected a term after %s", ErrSyntax, p.tokens[p.pos-1])
+	}
+
+	switch tok.kind {
+	case tokenWord:
+		p.pos++
+		return parseTerm(tok.text), nil
+	case tokenPhrase:
+		p.pos++
+		return parsePhrase(tok.text), nil
+	case tokenOpen:
+		p.pos++
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		closing, ok := p.peek()
+		if !ok || closing.kind != tokenClose {
+			return nil, fmt.Errorf("%w: missing closing parenthesis for %s", ErrSyntax, tok)
+		}
+		p.pos++
+		return n, nil
+	default:
+		return nil, fmt.Errorf("%w: unexpected %s", ErrSyntax, tok)
+	}
+}
+
+// parseTerm splits a field:word token. A token without a valid field name or
+// without a word after the colon is a plain word.
+func parseTerm(text string) Term {
+	field, word, ok := strings.Cut(text, ":")
+	if !ok || word == "" || !isFieldName(field) {
+		return Term{Text: text}
+	}
+	return Term{Field: field, Text: word}
+}
+
+// parsePhrase splits a phrase token, field:"words" or "words", as lex
+// produced it.
+func parsePhrase(text string) Phrase {
+	field, quoted, _ := strings.Cut(text, `"`)
+	return Phrase{
+		Field: strings.TrimSuffix(field, ":"),
+		Text:  strings.TrimSuffix(quoted, `"`),
+	}
+}
+
+func isFieldName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
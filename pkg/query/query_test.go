@@ -0,0 +1,105 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Node
+	}{
+		{input: "hotel", want: Term{Text: "hotel"}},
+		{input: "hotel barge", want: Or{Left: Term{Text: "hotel"}, Right: Term{Text: "barge"}}},
+		{input: "hotel AND NOT danish", want: And{Left: Term{Text: "hotel"}, Right: Not{Operand: Term{Text: "danish"}}}},
+		{input: "hotel NOT danish", want: And{Left: Term{Text: "hotel"}, Right: Not{Operand: Term{Text: "danish"}}}},
+		{
+			input: "hotel OR barge AND river",
+			want:  Or{Left: Term{Text: "hotel"}, Right: And{Left: Term{Text: "barge"}, Right: Term{Text: "river"}}},
+		},
+		{
+			input: "(hotel OR barge) AND river",
+			want:  And{Left: Or{Left: Term{Text: "hotel"}, Right: Term{Text: "barge"}}, Right: Term{Text: "river"}},
+		},
+		{input: "hotel and barge", want: Or{
+			Left:  Or{Left: Term{Text: "hotel"}, Right: Term{Text: "and"}},
+			Right: Term{Text: "barge"},
+		}},
+		{input: "   ", want: nil},
+		{input: "title:hotel", want: Term{Field: "title", Text: "hotel"}},
+		{input: "title:hotel AND NOT url:wikipedia", want: And{
+			Left:  Term{Field: "title", Text: "hotel"},
+			Right: Not{Operand: Term{Field: "url", Text: "wikipedia"}},
+		}},
+		{input: "title:", want: Term{Text: "title:"}},
+		{input: ":hotel", want: Term{Text: ":hotel"}},
+		{input: "a-b:hotel", want: Term{Text: "a-b:hotel"}},
+		{input: `"hotel barge"`, want: Phrase{Text: "hotel barge"}},
+		{input: `paris "hotel barge" river`, want: And{
+			Left:  Or{Left: Term{Text: "paris"}, Right: Term{Text: "river"}},
+			Right: Phrase{Text: "hotel barge"},
+		}},
+		{input: `"a b" "c d"`, want: And{Left: Phrase{Text: "a b"}, Right: Phrase{Text: "c d"}}},
+		{input: `hotel "barge`, want: Or{Left: Term{Text: "hotel"}, Right: Term{Text: "barge"}}},
+		{input: `title:"grand hotel"`, want: Phrase{Field: "title", Text: "grand hotel"}},
+		{input: `a-b:"grand hotel"`, want: And{Left: Term{Text: "a-b:"}, Right: Phrase{Text: "grand hotel"}}},
+		{input: `("hotel barge" OR "river cruise") AND france`, want: And{
+			Left:  Or{Left: Phrase{Text: "hotel barge"}, Right: Phrase{Text: "river cruise"}},
+			Right: Term{Text: "france"},
+		}},
+		{input: `"hotel barge" OR river cruise`, want: Or{
+			Left:  Or{Left: Phrase{Text: "hotel barge"}, Right: Term{Text: "river"}},
+			Right: Term{Text: "cruise"},
+		}},
+		{input: `(("a b" OR c) AND NOT (d OR "e f")) OR g`, want: Or{
+			Left: And{
+				Left:  Or{Left: Phrase{Text: "a b"}, Right: Term{Text: "c"}},
+				Right: Not{Operand: Or{Left: Term{Text: "d"}, Right: Phrase{Text: "e f"}}},
+			},
+			Right: Term{Text: "g"},
+		}},
+		{input: `"a (b) OR c"`, want: Phrase{Text: "a (b) OR c"}},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.input)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.input, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("Parse(%q) = %#v, want %#v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "hotel AND", want: `expected a term after "AND" at position 7`},
+		{input: "AND hotel", want: `unexpected "AND" at position 1`},
+		{input: "NOT", want: `expected a term after "NOT" at position 1`},
+		{input: "(hotel OR barge", want: `missing closing parenthesis for "(" at position 1`},
+		{input: "hotel)", want: `unexpected ")" at position 6`},
+		{input: "()", want: `unexpected ")" at position 2`},
+		{input: "hotel OR OR barge", want: `unexpected "OR" at position 10`},
+		{input: `("hotel barge" OR ("river cruise" AND france)`, want: `missing closing parenthesis for "(" at position 1`},
+		{input: `("hotel barge" OR "river cruise")) AND france`, want: `unexpected ")" at position 34`},
+		{input: `(("a b" OR c) AND (d) OR`, want: `expected a term after "OR" at position 23`},
+		{input: `"grand hôtel" AND )`, want: `unexpected ")" at position 19`},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.input)
+		if !errors.Is(err, ErrSyntax) {
+			t.Fatalf("Parse(%q) error = %v, want ErrSyntax", tt.input, err)
+		}
+		if !strings.HasSuffix(err.Error(), tt.want) {
+			t.Fatalf("Parse(%q) error = %q, want it to end in %q", tt.input, err, tt.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package textproc
 import (
 	"strconv"
 	"strings"
+	"sync"
 	"unicode"
 
 	snowballeng "github.com/kljensen/snowball/english"
@@ -13,23 +14,81 @@ type Filter interface {
 	Apply(tokens []string) []string
 }
 
+// TokenFilter is implemented by filters that handle every token on its own.
+// FilterToken returns what Apply would make of a one-token slice: the token
+// to keep, or false to drop it. Empty tokens are dropped.
+type TokenFilter interface {
+	Filter
+	FilterToken(token string) (string, bool)
+}
+
+// Stage is the kind of work a filter does, so that a pipeline can be run
+// without some kinds; see Pipeline.Without. Stages combine with |.
+type Stage uint8
+
+const (
+	StageLowercase Stage = 1 << iota
+	// StageStopWords drops words too common to search for, including
+	// MinLengthOrNumericFilter's short words.
+	StageStopWords
+	StageStem
+)
+
+// StagedFilter is implemented by filters that belong to a Stage. Other
+// filters run whatever stages are left out.
+type StagedFilter interface {
+	Filter
+	Stage() Stage
+}
+
 type Pipeline struct {
 	tokenizer Tokenizer
 	filters   []Filter
+
+	// streamer and tokenFilters are set when every stage supports the
+	// single-pass path; see NewPipeline.
+	streamer     TokenStreamer
+	tokenFilters []TokenFilter
 }
 
+// NewPipeline runs tokenizer and then filters in order. When the tokenizer is
+// a TokenStreamer and every filter a TokenFilter, as in the default
+// pipelines, Process takes each token through all filters before the next is
+// read, with no slice per filter. The tokens are the same either way.
 func NewPipeline(tokenizer Tokenizer, filters ...Filter) Pipeline {
 	if tokenizer == nil {
 		tokenizer = AlnumTokenizer{}
 	}
 
-	return Pipeline{
+	p := Pipeline{
 		tokenizer: tokenizer,
 		filters:   filters,
 	}
+
+	streamer, ok := tokenizer.(TokenStreamer)
+	if !ok {
+		return p
+	}
+	tokenFilters := make([]TokenFilter, 0, len(filters))
+	for _, filter := range filters {
+		if filter == nil {
+			continue
+		}
+		tokenFilter, ok := filter.(TokenFilter)
+		if !ok {
+			return p
+		}
+		tokenFilters = append(tokenFilters, tokenFilter)
+	}
+	p.streamer, p.tokenFilters = streamer, tokenFilters
+	return p
 }
 
 func (p Pipeline) Process(text string) []string {
+	if p.streamer != nil {
+		return p.processTokens(text)
+	}
+
 	tokens := p.tokenizer.Tokenize(text)
 	for _, filter := range p.filters {
 		if filter == nil {
@@ -40,6 +99,83 @@ func (p Pipeline) Process(text string) []string {
 	return tokens
 }
 
+// Without returns p with the filters of stages left out, as phrase searches
+// that must keep stop words need. Without(0) returns p as it is.
+func (p Pipeline) Without(stages Stage) Pipeline {
+	if stages == 0 {
+		return p
+	}
+
+	filters := make([]Filter, 0, len(p.filters))
+	for _, filter := range p.filters {
+		if staged, ok := filter.(StagedFilter); ok && staged.Stage()&stages != 0 {
+			continue
+		}
+		filters = append(filters, filter)
+	}
+	return NewPipeline(p.tokenizer, filters...)
+}
+
+// processTokens is Process in a single pass over the tokens.
+func (p Pipeline) processTokens(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	tokens := make([]string, 0, 16)
+next:
+	for token := range p.streamer.Tokens(text) {
+		for _, filter := range p.tokenFilters {
+			var ok bool
+			if token, ok = filter.FilterToken(token); !ok {
+				continue next
+			}
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// applyTokens implements Apply for a TokenFilter.
+func applyTokens(f TokenFilter, tokens []string) []string {
+	if len(tokens) == 0 {
+		return tokens
+	}
+
+	out := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if token, ok := f.FilterToken(token); ok {
+			out = append(out, token)
+		}
+	}
+	return out
+}
+
+var (
+	_ TokenStreamer = AlnumTokenizer{}
+	_ TokenFilter   = LowercaseFilter{}
+	_ TokenFilter   = MinLengthOrNumericFilter{}
+	_ TokenFilter   = EnglishStopwordFilter{}
+	_ TokenFilter   = (*StopwordFilter)(nil)
+	_ TokenFilter   = EnglishStemFilter{}
+	_ TokenFilter   = RussianStopwordFilter{}
+	_ TokenFilter   = RussianStemFilter{}
+	_ TokenFilter   = MultilingualStopwordFilter{}
+	_ TokenFilter   = MultilingualStemFilter{}
+	_ TokenFilter   = StemFilter{}
+
+	_ StagedFilter = LowercaseFilter{}
+	_ StagedFilter = MinLengthOrNumericFilter{}
+	_ StagedFilter = EnglishStopwordFilter{}
+	_ StagedFilter = (*StopwordFilter)(nil)
+	_ StagedFilter = EnglishStemFilter{}
+	_ StagedFilter = RussianStopwordFilter{}
+	_ StagedFilter = RussianStemFilter{}
+	_ StagedFilter = MultilingualStopwordFilter{}
+	_ StagedFilter = MultilingualStemFilter{}
+	_ StagedFilter = StemFilter{}
+)
+
 func DefaultEnglishPipeline() Pipeline {
 	return NewPipeline(
 		AlnumTokenizer{},
@@ -72,19 +208,21 @@ func DefaultMultilingualPipeline() Pipeline {
 
 type LowercaseFilter struct{}
 
-func (LowercaseFilter) Apply(tokens []string) []string {
-	if len(tokens) == 0 {
-		return tokens
-	}
+func (f LowercaseFilter) Apply(tokens []string) []string {
+	return applyTokens(f, tokens)
+}
 
-	out := make([]string, 0, len(tokens))
-	for _, token := range tokens {
-		if token == "" {
-			continue
-		}
-		out = append(out, strings.ToLower(token))
+// FilterToken lowercases token. Tokens without upper case letters are
+// returned as they are, without a copy.
+func (LowercaseFilter) FilterToken(token string) (string, bool) {
+	if token == "" {
+		return "", false
 	}
-	return out
+	return strings.ToLower(token), true
+}
+
+func (LowercaseFilter) Stage() Stage {
+	return StageLowercase
 }
 
 type MinLengthOrNumericFilter struct {
@@ -92,177 +230,218 @@ type MinLengthOrNumericFilter struct {
 }
 
 func (f MinLengthOrNumericFilter) Apply(tokens []string) []string {
-	if len(tokens) == 0 {
-		return tokens
+	return applyTokens(f, tokens)
+}
+
+func (f MinLengthOrNumericFilter) FilterToken(token string) (string, bool) {
+	if token == "" {
+		return "", false
 	}
+	return token, len(token) >= max(f.MinLength, 1) || isNumericToken(token)
+}
+
+func (MinLengthOrNumericFilter) Stage() Stage {
+	return StageStopWords
+}
+
+type EnglishStopwordFilter struct{}
+
+func (f EnglishStopwordFilter) Apply(tokens []string) []string {
+	return applyTokens(f, tokens)
+}
 
-	minLen := f.MinLength
-	if minLen <= 0 {
-		minLen = 1
+func (EnglishStopwordFilter) FilterToken(token string) (string, bool) {
+	if token == "" {
+		return "", false
 	}
+	return token, isNumericToken(token) || !snowballeng.IsStopWord(token)
+}
 
-	out := make([]string, 0, len(tokens))
-	for _, token := range tokens {
-		if token == "" {
-			continue
-		}
-		if isNumericToken(token) || len(token) >= minLen {
-			out = append(out, token)
+func (EnglishStopwordFilter) Stage() Stage {
+	return StageStopWords
+}
+
+// StopwordFilter drops tokens found in a configurable stop-word set.
+// A nil set falls back to the English snowball list; an empty one drops
+// nothing. The set can be swapped with SetStopWords between indexing runs.
+type StopwordFilter struct {
+	mu    sync.RWMutex
+	words map[string]struct{}
+}
+
+func NewStopwordFilter(words []string) *StopwordFilter {
+	f := &StopwordFilter{}
+	f.SetStopWords(words)
+	return f
+}
+
+// SetStopWords replaces the stop-word set. Words are lowercased, like the
+// tokens of a pipeline with LowercaseFilter; nil restores the English list.
+func (f *StopwordFilter) SetStopWords(words []string) {
+	var set map[string]struct{}
+	if words != nil {
+		set = make(map[string]struct{}, len(words))
+		for _, word := range words {
+			if word == "" {
+				continue
+			}
+			set[strings.ToLower(word)] = struct{}{}
 		}
 	}
-	return out
-}
 
-type EnglishStopwordFilter struct{}
+	f.mu.Lock()
+	f.words = set
+	f.mu.Unlock()
+}
 
-func (EnglishStopwordFilter) Apply(tokens []string) []string {
+func (f *StopwordFilter) Apply(tokens []string) []string {
 	if len(tokens) == 0 {
 		return tokens
 	}
 
+	f.mu.RLock()
+	words := f.words
+	f.mu.RUnlock()
+
 	out := make([]string, 0, len(tokens))
 	for _, token := range tokens {
-		if token == "" {
-			continue
-		}
-		if isNumericToken(token) {
+		if token != "" && !isStopWord(words, token) {
 			out = append(out, token)
-			continue
-		}
-		if snowballeng.IsStopWord(token) {
-			continue
 		}
-		out = append(out, token)
 	}
 	return out
 }
 
+// FilterToken drops token if it is a stop word. Apply reads the stop-word set
+// once per call rather than once per token.
+func (f *StopwordFilter) FilterToken(token string) (string, bool) {
+	if token == "" {
+		return "", false
+	}
+
+	f.mu.RLock()
+	words := f.words
+	f.mu.RUnlock()
+
+	return token, !isStopWord(words, token)
+}
+
+func (*StopwordFilter) Stage() Stage {
+	return StageStopWords
+}
+
+// isStopWord reports whether token is in words, or in the English snowball
+// list when words is nil. Numbers are never stop words.
+func isStopWord(words map[string]struct{}, token string) bool {
+	if isNumericToken(token) {
+		return false
+	}
+	if words == nil {
+		return snowballeng.IsStopWord(token)
+	}
+	_, ok := words[token]
+	return ok
+}
+
 type EnglishStemFilter struct{}
 
 func (EnglishStemFilter) Apply(tokens []string) []string {
-	if len(tokens) == 0 {
-		return tokens
-	}
+	return StemFilter{Stemmer: EnglishStemmer{}}.Apply(tokens)
+}
 
-	out := make([]string, 0, len(tokens))
-	for _, token := range tokens {
-		if token == "" {
-			continue
-		}
-		if isNumericToken(token) {
-			out = append(out, token)
-			continue
-		}
-		out = append(out, snowballeng.Stem(token, false))
-	}
-	return out
+func (EnglishStemFilter) FilterToken(token string) (string, bool) {
+	return StemFilter{Stemmer: EnglishStemmer{}}.FilterToken(token)
+}
+
+func (EnglishStemFilter) Stage() Stage {
+	return StageStem
 }
 
 type RussianStopwordFilter struct{}
 
-func (RussianStopwordFilter) Apply(tokens []string) []string {
-	if len(tokens) == 0 {
-		return tokens
-	}
+func (f RussianStopwordFilter) Apply(tokens []string) []string {
+	return applyTokens(f, tokens)
+}
 
-	out := make([]string, 0, len(tokens))
-	for _, token := range tokens {
-		if token == "" {
-			continue
-		}
-		if isNumericToken(token) {
-			out = append(out, token)
-			continue
-		}
-		if snowballrus.IsStopWord(token) {
-			continue
-		}
-		out = append(out, token)
+func (RussianStopwordFilter) FilterToken(token string) (string, bool) {
+	if token == "" {
+		return "", false
 	}
-	return out
+	return token, isNumericToken(token) || !snowballrus.IsStopWord(token)
+}
+
+func (RussianStopwordFilter) Stage() Stage {
+	return StageStopWords
 }
 
 type RussianStemFilter struct{}
 
 func (RussianStemFilter) Apply(tokens []string) []string {
-	if len(tokens) == 0 {
-		return tokens
-	}
+	return StemFilter{Stemmer: RussianStemmer{}}.Apply(tokens)
+}
 
-	out := make([]string, 0, len(tokens))
-	for _, token := range tokens {
-		if token == "" {
-			continue
-		}
-		if isNumericToken(token) {
-			out = append(out, token)
-			continue
-		}
-		out = append(out, snowballrus.Stem(token, false))
-	}
-	return out
+func (RussianStemFilter) FilterToken(token string) (string, bool) {
+	return StemFilter{Stemmer: RussianStemmer{}}.FilterToken(token)
 }
 
-type MultilingualStopwordFilter struct{}
+func (RussianStemFilter) Stage() Stage {
+	return StageStem
+}
 
-func (MultilingualStopwordFilter) Apply(tokens []string) []string {
-	if len(tokens) == 0 {
-		return tokens
-	}
+type MultilingualStopwordFilter struct{}
 
-	out := make([]string, 0, len(tokens))
-	for _, token := range tokens {
-		if token == "" {
-			continue
-		}
-		if isNumericToken(token) {
-			out = append(out, token)
-			continue
-		}
+func (f MultilingualStopwordFilter) Apply(tokens []string) []string {
+	return applyTokens(f, tokens)
+}
 
-		switch tokenScript(token) {
-		case scriptLatin:
-			if snowballeng.IsStopWord(token) {
-				continue
-			}
-		case scriptCyrillic:
-			if snowballrus.IsStopWord(token) {
-				continue
-			}
-		}
+func (MultilingualStopwordFilter) FilterToken(token string) (string, bool) {
+	if token == "" {
+		return "", false
+	}
+	if isNumericToken(token) {
+		return token, true
+	}
 
-		out = append(out, token)
+	switch tokenScript(token) {
+	case scriptLatin:
+		return token, !snowballeng.IsStopWord(token)
+	case scriptCyrillic:
+		return token, !snowballrus.IsStopWord(token)
+	default:
+		return token, true
 	}
-	return out
+}
+
+func (MultilingualStopwordFilter) Stage() Stage {
+	return StageStopWords
 }
 
 type MultilingualStemFilter struct{}
 
-func (MultilingualStemFilter) Apply(tokens []string) []string {
-	if len(tokens) == 0 {
-		return tokens
-	}
+func (f MultilingualStemFilter) Apply(tokens []string) []string {
+	return applyTokens(f, tokens)
+}
 
-	out := make([]string, 0, len(tokens))
-	for _, token := range tokens {
-		if token == "" {
-			continue
-		}
-		if isNumericToken(token) {
-			out = append(out, token)
-			continue
-		}
+func (MultilingualStemFilter) FilterToken(token string) (string, bool) {
+	if token == "" {
+		return "", false
+	}
+	if isNumericToken(token) {
+		return token, true
+	}
 
-		switch tokenScript(token) {
-		case scriptLatin:
-			out = append(out, snowballeng.Stem(token, false))
-		case scriptCyrillic:
-			out = append(out, snowballrus.Stem(token, false))
-		default:
-			out = append(out, token)
-		}
+	switch tokenScript(token) {
+	case scriptLatin:
+		return snowballeng.Stem(token, false), true
+	case scriptCyrillic:
+		return snowballrus.Stem(token, false), true
+	default:
+		return token, true
 	}
-	return out
+}
+
+func (MultilingualStemFilter) Stage() Stage {
+	return StageStem
 }
 
 type scriptKind uint8
@@ -2,6 +2,7 @@ package textproc
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -16,6 +17,76 @@ func TestAlnumTokenizer(t *testing.T) {
 	}
 }
 
+func TestAlnumTokenizer_Delimiters(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "empty", text: "", want: nil},
+		{name: "only delimiters", text: " ,.!? ", want: []string{}},
+		{name: "leading", text: "...grand hotel", want: []string{"grand", "hotel"}},
+		{name: "leading single", text: "-hotel", want: []string{"hotel"}},
+		{name: "trailing", text: "grand hotel!!", want: []string{"grand", "hotel"}},
+		{name: "trailing content", text: "grand hotel", want: []string{"grand", "hotel"}},
+		{name: "single rune", text: "a", want: []string{"a"}},
+		{name: "consecutive", text: "grand,,  ;hotel", want: []string{"grand", "hotel"}},
+		{name: "both ends", text: "(grand)", want: []string{"grand"}},
+	}
+
+	for _, tt := range tests {
+		for _, tok := range []AlnumTokenizer{{}, {Inner: "-'"}} {
+			got := tok.Tokenize(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("%s: Tokenize(%q) with inner %q = %q, want %q", tt.name, tt.text, tok.Inner, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestAlnumTokenizer_Unicode(t *testing.T) {
+	tok := AlnumTokenizer{}
+
+	got := tok.Tokenize("Café naïve cafe\u0301 Москва, Zürich-2024 x²")
+	want := []string{"Café", "naïve", "cafe\u0301", "Москва", "Zürich", "2024", "x²"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Tokenize() = %q, want %q", got, want)
+	}
+}
+
+func TestAlnumTokenizer_TypographicPunctuation(t *testing.T) {
+	tok := AlnumTokenizer{}
+
+	curly := tok.Tokenize("don’t “stop” — ever… -- ...")
+	ascii := tok.Tokenize(`don't "stop" - ever... -- ...`)
+	want := []string{"don", "t", "stop", "ever"}
+
+	if !reflect.DeepEqual(curly, want) || !reflect.DeepEqual(ascii, want) {
+		t.Fatalf("Tokenize() = %q and %q, want %q for both", curly, ascii, want)
+	}
+}
+
+func TestAlnumTokenizer_Inner(t *testing.T) {
+	tests := []struct {
+		inner string
+		text  string
+		want  []string
+	}{
+		{inner: "", text: "e-mail O'Brien", want: []string{"e", "mail", "O", "Brien"}},
+		{inner: "-'", text: "e-mail, mother-in-law and O'Brien", want: []string{"e-mail", "mother-in-law", "and", "O'Brien"}},
+		{inner: "-'", text: "well-- -known 'quoted' rock'n'roll 19-20 x-2", want: []string{"well", "known", "quoted", "rock'n'roll", "19", "20", "x", "2"}},
+		{inner: "-", text: "e\xff-mail tail-", want: []string{"e", "mail", "tail"}},
+	}
+
+	for _, tt := range tests {
+		got := AlnumTokenizer{Inner: tt.inner}.Tokenize(tt.text)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("Tokenize(%q) with inner %q = %q, want %q", tt.text, tt.inner, got, tt.want)
+		}
+	}
+}
+
 func TestDefaultEnglishPipeline_Golden(t *testing.T) {
 	p := DefaultEnglishPipeline()
 
@@ -92,3 +163,230 @@ func TestRussianStemFilter(t *testing.T) {
 		t.Fatalf("Apply() = %v, want %v", got, want)
 	}
 }
+
+func TestStopwordFilter_DefaultsToEnglish(t *testing.T) {
+	f := NewStopwordFilter(nil)
+
+	got := f.Apply([]string{"the", "hotel", "was", "not", "2024"})
+	want := []string{"hotel", "2024"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Apply() = %v, want %v", got, want)
+	}
+}
+
+func TestStopwordFilter_CustomSet(t *testing.T) {
+	f := NewStopwordFilter([]string{"shall", "the"})
+
+	got := f.Apply([]string{"the", "tenant", "shall", "not", "sublet"})
+	want := []string{"tenant", "not", "sublet"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Apply() = %v, want %v", got, want)
+	}
+}
+
+func TestStopwordFilter_SetStopWords(t *testing.T) {
+	f := NewStopwordFilter([]string{"shall"})
+	p := NewPipeline(AlnumTokenizer{}, LowercaseFilter{}, f)
+
+	if got := p.Process("Tenant shall pay"); !reflect.DeepEqual(got, []string{"tenant", "pay"}) {
+		t.Fatalf("Process() before swap = %v", got)
+	}
+
+	f.SetStopWords([]string{"pay"})
+
+	got := p.Process("Tenant shall pay")
+	want := []string{"tenant", "shall"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Process() after swap = %v, want %v", got, want)
+	}
+}
+
+func TestStopwordFilter_LowercasesWords(t *testing.T) {
+	p := NewPipeline(AlnumTokenizer{}, LowercaseFilter{}, NewStopwordFilter([]string{"Shall", "HEREBY"}))
+
+	got := p.Process("Tenant shall hereby pay")
+	want := []string{"tenant", "pay"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Process() = %v, want %v", got, want)
+	}
+}
+
+func TestStopwordFilter_EmptyListKeepsEverything(t *testing.T) {
+	f := NewStopwordFilter([]string{})
+
+	got := f.Apply([]string{"the", "tenant", "shall", "pay"})
+	want := []string{"the", "tenant", "shall", "pay"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Apply() = %v, want %v", got, want)
+	}
+
+	f.SetStopWords(nil)
+	if got := f.Apply([]string{"the", "tenant"}); !reflect.DeepEqual(got, []string{"tenant"}) {
+		t.Fatalf("Apply() after SetStopWords(nil) = %v, want the English list back", got)
+	}
+}
+
+func TestStemFilter_DefaultsToEnglish(t *testing.T) {
+	f := StemFilter{}
+
+	got := f.Apply([]string{"hotels", "running", "2024"})
+	want := []string{"hotel", "run", "2024"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Apply() = %v, want %v", got, want)
+	}
+}
+
+func TestStemFilter_NoopStemmer(t *testing.T) {
+	f := StemFilter{Stemmer: NoopStemmer{}}
+
+	got := f.Apply([]string{"häuser", "straßen"})
+	want := []string{"häuser", "straßen"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Apply() = %v, want %v", got, want)
+	}
+}
+
+type suffixStemmer struct{}
+
+func (suffixStemmer) Stem(token string) string {
+	return strings.TrimSuffix(token, "en")
+}
+
+func TestStemFilter_CustomStemmer(t *testing.T) {
+	p := NewPipeline(AlnumTokenizer{}, LowercaseFilter{}, StemFilter{Stemmer: suffixStemmer{}})
+
+	got := p.Process("Gärten Straßen")
+	want := []string{"gärt", "straß"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Process() = %v, want %v", got, want)
+	}
+}
+
+// sliceFilter hides the FilterToken method of a filter, so a pipeline using
+// it runs every filter over the whole token slice.
+type sliceFilter struct{ Filter }
+
+// sliceTokenizer hides the Tokens method of a tokenizer.
+type sliceTokenizer struct{ Tokenizer }
+
+// unfused rebuilds the stages of p so that Process runs them one after the
+// other.
+func unfused(p Pipeline) Pipeline {
+	filters := make([]Filter, 0, len(p.filters))
+	for _, filter := range p.filters {
+		if filter != nil {
+			filter = sliceFilter{filter}
+		}
+		filters = append(filters, filter)
+	}
+	return NewPipeline(sliceTokenizer{p.tokenizer}, filters...)
+}
+
+func TestPipelineSinglePassMatchesFilters(t *testing.T) {
+	texts := []string{
+		"",
+		"!!!",
+		"The Hotels of Москва, and the 2024 e-mail O'Brien sent.",
+		"  Grand   HOTEL  ",
+		"Kjøbenhavn ved Øresund and cafe\u0301s",
+	}
+	pipelines := map[string]Pipeline{
+		"English":      DefaultEnglishPipeline(),
+		"Russian":      DefaultRussianPipeline(),
+		"Multilingual": DefaultMultilingualPipeline(),
+		"Custom": NewPipeline(AlnumTokenizer{Inner: "-'"}, LowercaseFilter{}, nil,
+			NewStopwordFilter([]string{"and"}), StemFilter{Stemmer: suffixStemmer{}}),
+	}
+
+	for name, p := range pipelines {
+		if p.streamer == nil {
+			t.Fatalf("%s pipeline does not take the single pass", name)
+		}
+		for _, text := range texts {
+			got, want := p.Process(text), unfused(p).Process(text)
+			if len(got) != len(want) || (len(got) > 0 && !reflect.DeepEqual(got, want)) {
+				t.Fatalf("%s Process(%q) = %q, want %q", name, text, got, want)
+			}
+		}
+	}
+}
+
+func TestPipelineSliceFilter(t *testing.T) {
+	p := NewPipeline(AlnumTokenizer{}, LowercaseFilter{}, sliceFilter{EnglishStemFilter{}})
+	if p.streamer != nil {
+		t.Fatal("pipeline with a slice-only filter takes the single pass")
+	}
+
+	got := p.Process("Grand Hotels")
+	if want := []string{"grand", "hotel"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Process() = %v, want %v", got, want)
+	}
+}
+
+func TestPipelineWithout(t *testing.T) {
+	p := DefaultEnglishPipeline()
+	text := "To be or not to be, Hotels"
+
+	tests := []struct {
+		stages Stage
+		want   []string
+	}{
+		{stages: 0, want: []string{"hotel"}},
+		{stages: StageStopWords, want: []string{"to", "be", "or", "not", "to", "be", "hotel"}},
+		{stages: StageStopWords | StageStem, want: []string{"to", "be", "or", "not", "to", "be", "hotels"}},
+		{stages: StageLowercase | StageStopWords | StageStem, want: []string{"To", "be", "or", "not", "to", "be", "Hotels"}},
+	}
+
+	for _, tt := range tests {
+		if got := p.Without(tt.stages).Process(text); !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("Without(%b).Process(%q) = %q, want %q", tt.stages, text, got, tt.want)
+		}
+	}
+	if got := p.Process(text); !reflect.DeepEqual(got, []string{"hotel"}) {
+		t.Fatalf("Process() after Without = %q, want the pipeline unchanged", got)
+	}
+}
+
+func TestPipelineWithoutKeepsUnstagedFilters(t *testing.T) {
+	p := NewPipeline(AlnumTokenizer{}, sliceFilter{LowercaseFilter{}}, NewStopwordFilter([]string{"the"}))
+
+	got := p.Without(StageLowercase | StageStopWords).Process("The Hotel")
+	if want := []string{"the", "hotel"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Process() = %q, want %q", got, want)
+	}
+}
+
+// benchText is an abstract-sized English text with mixed case, numbers,
+// stop words and punctuation.
+const benchText = "The Grand Hotel is a luxury hotel on the waterfront of Stockholm, Sweden. " +
+	"It opened in 1874 and has hosted the Nobel laureates every December since 1901. " +
+	"The hotel has 278 rooms and suites, two Michelin-starred restaurants, and a spa " +
+	"overlooking the Royal Palace and the old town of Gamla Stan."
+
+func BenchmarkPipeline(b *testing.B) {
+	pipelines := map[string]Pipeline{
+		"English":      DefaultEnglishPipeline(),
+		"Russian":      DefaultRussianPipeline(),
+		"Multilingual": DefaultMultilingualPipeline(),
+		"Tokenize":     NewPipeline(AlnumTokenizer{}),
+	}
+	for name, p := range pipelines {
+		for mode, p := range map[string]Pipeline{"SinglePass": p, "PerFilter": unfused(p)} {
+			b.Run(name+"/"+mode, func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(len(benchText)))
+				for b.Loop() {
+					p.Process(benchText)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package textproc
+
+import (
+	snowballeng "github.com/kljensen/snowball/english"
+	snowballrus "github.com/kljensen/snowball/russian"
+)
+
+// Stemmer reduces a single lowercase token to its stem.
+type Stemmer interface {
+	Stem(token string) string
+}
+
+type EnglishStemmer struct{}
+
+func (EnglishStemmer) Stem(token string) string {
+	return snowballeng.Stem(token, false)
+}
+
+type RussianStemmer struct{}
+
+func (RussianStemmer) Stem(token string) string {
+	return snowballrus.Stem(token, false)
+}
+
+// NoopStemmer keeps tokens unchanged, for languages without stemming rules.
+type NoopStemmer struct{}
+
+func (NoopStemmer) Stem(token string) string {
+	return token
+}
+
+// StemFilter applies Stemmer to every non-numeric token.
+// A nil Stemmer defaults to EnglishStemmer.
+type StemFilter struct {
+	Stemmer Stemmer
+}
+
+func (f StemFilter) Apply(tokens []string) []string {
+	return applyTokens(f, tokens)
+}
+
+func (f StemFilter) FilterToken(token string) (string, bool) {
+	if token == "" {
+		return "", false
+	}
+	if isNumericToken(token) {
+		return token, true
+	}
+
+	stemmer := f.Stemmer
+	if stemmer == nil {
+		stemmer = EnglishStemmer{}
+	}
+	return stemmer.Stem(token), true
+}
+
+func (StemFilter) Stage() Stage {
+	return StageStem
+}
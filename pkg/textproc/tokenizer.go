@@ -1,40 +1,90 @@
 package textproc
 
 import (
+	"iter"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 type Tokenizer interface {
 	Tokenize(text string) []string
 }
 
-type AlnumTokenizer struct{}
+// TokenStreamer is implemented by tokenizers that can yield their tokens one
+// at a time. With such a tokenizer and only TokenFilters, a Pipeline
+// processes each token through all filters in a single pass.
+type TokenStreamer interface {
+	Tokenizer
+	Tokens(text string) iter.Seq[string]
+}
+
+// AlnumTokenizer splits text into runs of letters, numbers and combining
+// marks. Runes in Inner do not split a word when a letter is on both sides of
+// them, so with Inner "-'" "e-mail" and "O'Brien" stay whole while "--" and a
+// trailing "'" still split.
+type AlnumTokenizer struct {
+	Inner string
+}
 
-func (AlnumTokenizer) Tokenize(text string) []string {
+func (t AlnumTokenizer) Tokenize(text string) []string {
 	if text == "" {
 		return nil
 	}
 
 	tokens := make([]string, 0, 16)
-	var b strings.Builder
+	for token := range t.Tokens(text) {
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
 
-	flush := func() {
-		if b.Len() == 0 {
-			return
+// Tokens yields the tokens Tokenize returns, one at a time. Tokens are
+// substrings of text, so none is copied.
+func (t AlnumTokenizer) Tokens(text string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		start := -1
+		var last rune
+		for i, r := range text {
+			if IsWordRune(r) || t.joins(last, text[i:]) {
+				if start < 0 {
+					start = i
+				}
+				last = r
+				continue
+			}
+			if start >= 0 {
+				if !yield(text[start:i]) {
+					return
+				}
+				start = -1
+			}
+			last = 0
+		}
+		if start >= 0 {
+			yield(text[start:])
 		}
-		tokens = append(tokens, b.String())
-		b.Reset()
 	}
+}
 
-	for _, r := range text {
-		if unicode.IsLetter(r) || unicode.IsDigit(r) {
-			b.WriteRune(r)
-			continue
-		}
-		flush()
+// joins reports whether the rune text starts with is an inner rune between
+// last and another letter.
+func (t AlnumTokenizer) joins(last rune, text string) bool {
+	if t.Inner == "" || !unicode.IsLetter(last) {
+		return false
 	}
-	flush()
+	r, size := utf8.DecodeRuneInString(text)
+	if !strings.ContainsRune(t.Inner, r) {
+		return false
+	}
+	next, _ := utf8.DecodeRuneInString(text[size:])
+	return unicode.IsLetter(next)
+}
 
-	return tokens
+// IsWordRune reports whether r belongs to a token. Combining marks are kept
+// so decomposed letters (e.g. "e\u0301") are not split mid-word. Every
+// tokenizer of the module, and the word boundaries of fts snippets, use it,
+// so indexing, queries and highlighting agree on what a word is.
+func IsWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r)
 }